@@ -2,23 +2,59 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/k8s"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
 
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/controller"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/handlers"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/sdk"
 	"github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
 	_ "github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/resources"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/metrics"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/webhook"
 	appsv1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	routev1 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
 	"github.com/operator-framework/operator-sdk/pkg/k8sclient"
 	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/record"
+)
+
+var (
+	webAppImage    = flag.String("webapp-image", os.Getenv("WEBAPP_IMAGE"), "default image of the web app, a WebApp can override it with spec.image")
+	backupImage    = flag.String("backup-image", os.Getenv("BACKUP_IMAGE"), "default image of the backups and restores, a WebApp can override it with spec.backup.image")
+	webhookPort    = flag.Int("webhook-port", 8443, "port of the admission webhook server")
+	webhookCertDir = flag.String("webhook-cert-dir", "", "directory with the tls.crt and tls.key of the admission webhook server, the webhooks are disabled when not set")
+	workers        = flag.Int("workers", 2, "number of WebApps reconciled in parallel")
+	resyncPeriod   = flag.Duration("resync-period", 10*time.Minute, "interval in which every WebApp is reconciled without a change")
+
+	leaderElect            = flag.Bool("leader-elect", false, "elect a leader among the replicas of the operator, only the leader reconciles WebApps")
+	leaderElectionNS       = flag.String("leader-election-namespace", os.Getenv("POD_NAMESPACE"), "namespace of the leader election lock, the watched namespace when not set")
+	leaderElectionID       = flag.String("leader-election-id", os.Getenv("POD_NAME"), "identity of this replica in the leader election, the host name when not set")
+	leaderElectionLease    = flag.Duration("leader-election-lease-duration", 15*time.Second, "how long the other replicas wait before they replace a leader that stopped renewing its lease")
+	leaderElectionDeadline = flag.Duration("leader-election-renew-deadline", 10*time.Second, "how long the leader retries renewing its lease before it gives up leadership")
+	leaderElectionRetry    = flag.Duration("leader-election-retry-period", 2*time.Second, "interval in which the lease is acquired or renewed")
+)
+
+const (
+	leaderElectionLock = "tutorial-web-app-operator-lock"
+	// eventSource is the component of the events recorded on WebApps
+	eventSource = "tutorial-web-app-operator"
 )
 
 func printVersion() {
@@ -28,6 +64,7 @@ func printVersion() {
 }
 
 func main() {
+	flag.Parse()
 	printVersion()
 	sdk.ExposeMetricsPort()
 
@@ -36,10 +73,18 @@ func main() {
 		logrus.Errorf("failed to register operator specific metrics: %v", err)
 	}
 
-	namespace, err := k8sutil.GetWatchNamespace()
+	// WATCH_NAMESPACE is a comma separated list of namespaces, all namespaces are watched when it is empty
+	watchNamespace, err := k8sutil.GetWatchNamespace()
 	if err != nil {
 		logrus.Fatalf("failed to get watch namespace: %v", err)
 	}
+	namespaces := controller.ParseNamespaces(watchNamespace)
+
+	platform, err := openshift.DetectPlatform(k8sclient.GetKubeClient().Discovery())
+	if err != nil {
+		logrus.Fatalf("failed to detect platform: %v", err)
+	}
+	logrus.Infof("Running on %s", platform)
 
 	routeClient, err := routev1.NewForConfig(k8sclient.GetKubeConfig())
 	if err != nil {
@@ -51,7 +96,8 @@ func main() {
 		panic(err)
 	}
 
-	tmpl, err := openshift.NewTemplate(namespace, k8sclient.GetKubeConfig(), openshift.TemplateDefaultOpts)
+	// templates are processed in the namespace of their WebApp
+	tmpl, err := openshift.NewTemplate("", k8sclient.GetKubeConfig(), openshift.TemplateDefaultOpts)
 	if err != nil {
 		panic(err)
 	}
@@ -61,16 +107,111 @@ func main() {
 		logrus.Fatalf("failed to initialize openshift client: %v", err)
 	}
 
+	// SIGTERM and SIGINT stop the operator, the leader releases its lease so another replica takes over right away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		sig := <-signals
+		logrus.Infof("Received %s, shutting down", sig)
+		cancel()
+	}()
+
 	cruder := k8s.Cruder{}
-	webAppHandler := handlers.NewWebHandler(metrics, osClient, k8sclient.GetResourceClient, cruder)
-	handlers := handlers.NewHandler(&webAppHandler)
-	resource := "integreatly.org/v1alpha1"
-	kind := "WebApp"
-	resyncPeriod := time.Duration(5) * time.Second
+	recorder, err := newRecorder()
+	if err != nil {
+		logrus.Fatalf("failed to set up the event recorder: %v", err)
+	}
+	webAppHandler := handlers.NewWebHandler(metrics, recorder, osClient, k8sclient.GetResourceClient, cruder, platform, *webAppImage, *backupImage)
+
+	if *webhookCertDir != "" {
+		logrus.Infof("Serving admission webhooks on port %d", *webhookPort)
+		webhook.NewServer(*webhookPort, *webhookCertDir, &webAppHandler, &webAppHandler).Start(ctx)
+	}
+
+	c, err := controller.New(&webAppHandler, k8sclient.GetKubeConfig(), k8sclient.GetResourceClient, controller.Options{
+		Namespaces:   namespaces,
+		Workers:      *workers,
+		ResyncPeriod: *resyncPeriod,
+		Owned:        handlers.OwnedKinds(platform),
+	})
+	if err != nil {
+		logrus.Fatalf("failed to create the controller: %v", err)
+	}
+	run := func(ctx context.Context) {
+		// the controller stops with the context, an error while it stops is not a failure
+		if err := c.Run(ctx); err != nil && ctx.Err() == nil {
+			logrus.Fatalf("failed to run the controller: %v", err)
+		}
+	}
+	if !*leaderElect {
+		run(ctx)
+		return
+	}
+
+	elector, err := newElector(ctx, namespaces, metrics, recorder, run)
+	if err != nil {
+		logrus.Fatalf("failed to set up the leader election: %v", err)
+	}
+	elector.Run(ctx)
+}
+
+// newRecorder returns the recorder of the events on the WebApps and on the leader election lock, the events are
+// aggregated and written in the background by the event broadcaster of client-go
+func newRecorder() (record.EventRecorder, error) {
+	s := k8sruntime.NewScheme()
+	scheme.AddToScheme(s)
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(logrus.Debugf)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sclient.GetKubeClient().CoreV1().Events("")})
+	return broadcaster.NewRecorder(s, v1.EventSource{Component: eventSource}), nil
+}
 
-	logrus.Infof("Watching %s, %s, %s, %d", resource, kind, namespace, resyncPeriod)
+func newElector(ctx context.Context, namespaces []string, m *metrics.Metrics, recorder record.EventRecorder, run func(ctx context.Context)) (*leader.Elector, error) {
+	ns := *leaderElectionNS
+	if ns == "" && len(namespaces) == 1 {
+		ns = namespaces[0]
+	}
+	if ns == "" {
+		return nil, fmt.Errorf("--leader-election-namespace must be set when more than one namespace is watched")
+	}
+	id := *leaderElectionID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		id = hostname
+	}
 
-	sdk.Watch(resource, kind, namespace, resyncPeriod)
-	sdk.Handle(handlers.WebAppHandler)
-	sdk.Run(context.TODO())
+	return leader.New(k8sclient.GetKubeClient(), leader.Config{
+		Namespace:     ns,
+		Name:          leaderElectionLock,
+		Identity:      id,
+		Recorder:      recorder,
+		LeaseDuration: *leaderElectionLease,
+		RenewDeadline: *leaderElectionDeadline,
+		RetryPeriod:   *leaderElectionRetry,
+		OnStartedLeading: func(ctx context.Context) {
+			m.SetLeader(true)
+			run(ctx)
+		},
+		OnStoppedLeading: func() {
+			m.SetLeader(false)
+			// the lease is released when the operator shuts down, that is not a failure
+			if ctx.Err() != nil {
+				logrus.Infof("%s released the leadership", id)
+				return
+			}
+			// the informers and workers of the controller can not be restarted, a new replica takes over
+			logrus.Fatalf("%s lost the leadership", id)
+		},
+		OnNewLeader: func(string) {
+			m.ObserveLeader()
+		},
+	})
 }
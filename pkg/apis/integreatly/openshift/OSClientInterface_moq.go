@@ -6,17 +6,20 @@ package openshift
 import (
 	appsv1 "github.com/openshift/api/apps/v1"
 	tmplv1 "github.com/openshift/api/template/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sync"
 )
 
 var (
-	lockOSClientInterfaceMockDelete          sync.RWMutex
-	lockOSClientInterfaceMockGetDC           sync.RWMutex
-	lockOSClientInterfaceMockGetPod          sync.RWMutex
-	lockOSClientInterfaceMockProcessTemplate sync.RWMutex
-	lockOSClientInterfaceMockUpdateDC        sync.RWMutex
+	lockOSClientInterfaceMockGetConfigMap     sync.RWMutex
+	lockOSClientInterfaceMockGetDC            sync.RWMutex
+	lockOSClientInterfaceMockGetDeployment    sync.RWMutex
+	lockOSClientInterfaceMockGetEndpoints     sync.RWMutex
+	lockOSClientInterfaceMockGetPods          sync.RWMutex
+	lockOSClientInterfaceMockGetSecret        sync.RWMutex
+	lockOSClientInterfaceMockProcessTemplate  sync.RWMutex
 )
 
 // Ensure, that OSClientInterfaceMock does implement OSClientInterface.
@@ -29,20 +32,26 @@ var _ OSClientInterface = &OSClientInterfaceMock{}
 //
 //         // make and configure a mocked OSClientInterface
 //         mockedOSClientInterface := &OSClientInterfaceMock{
-//             DeleteFunc: func(ns string, label string) error {
-// 	               panic("mock out the Delete method")
+//             GetConfigMapFunc: func(ns string, name string) (v1.ConfigMap, error) {
+// 	               panic("mock out the GetConfigMap method")
 //             },
-//             GetDCFunc: func(ns string, dcName string) (v1.DeploymentConfig, error) {
+//             GetDCFunc: func(ns string, dcName string) (appsv1.DeploymentConfig, error) {
 // 	               panic("mock out the GetDC method")
 //             },
-//             GetPodFunc: func(ns string, dc string) (v1.Pod, error) {
-// 	               panic("mock out the GetPod method")
+//             GetDeploymentFunc: func(ns string, name string) (k8sappsv1.Deployment, error) {
+// 	               panic("mock out the GetDeployment method")
 //             },
-//             ProcessTemplateFunc: func(in1 *v1.Template, in2 map[string]string, in3 TemplateOpt) ([]runtime.RawExtension, error) {
-// 	               panic("mock out the ProcessTemplate method")
+//             GetEndpointsFunc: func(ns string, name string) (v1.Endpoints, error) {
+// 	               panic("mock out the GetEndpoints method")
+//             },
+//             GetPodsFunc: func(ns string, selector string) ([]v1.Pod, error) {
+// 	               panic("mock out the GetPods method")
 //             },
-//             UpdateDCFunc: func(ns string, dc *v1.DeploymentConfig) error {
-// 	               panic("mock out the UpdateDC method")
+//             GetSecretFunc: func(ns string, name string) (v1.Secret, error) {
+// 	               panic("mock out the GetSecret method")
+//             },
+//             ProcessTemplateFunc: func(in1 *tmplv1.Template, in2 map[string]string, in3 TemplateOpt) ([]runtime.RawExtension, error) {
+// 	               panic("mock out the ProcessTemplate method")
 //             },
 //         }
 //
@@ -51,29 +60,35 @@ var _ OSClientInterface = &OSClientInterfaceMock{}
 //
 //     }
 type OSClientInterfaceMock struct {
-	// DeleteFunc mocks the Delete method.
-	DeleteFunc func(ns string, label string) error
+	// GetConfigMapFunc mocks the GetConfigMap method.
+	GetConfigMapFunc func(ns string, name string) (v1.ConfigMap, error)
 
 	// GetDCFunc mocks the GetDC method.
 	GetDCFunc func(ns string, dcName string) (appsv1.DeploymentConfig, error)
 
-	// GetPodFunc mocks the GetPod method.
-	GetPodFunc func(ns string, dc string) (v1.Pod, error)
+	// GetDeploymentFunc mocks the GetDeployment method.
+	GetDeploymentFunc func(ns string, name string) (k8sappsv1.Deployment, error)
+
+	// GetEndpointsFunc mocks the GetEndpoints method.
+	GetEndpointsFunc func(ns string, name string) (v1.Endpoints, error)
+
+	// GetPodsFunc mocks the GetPods method.
+	GetPodsFunc func(ns string, selector string) ([]v1.Pod, error)
+
+	// GetSecretFunc mocks the GetSecret method.
+	GetSecretFunc func(ns string, name string) (v1.Secret, error)
 
 	// ProcessTemplateFunc mocks the ProcessTemplate method.
 	ProcessTemplateFunc func(in1 *tmplv1.Template, in2 map[string]string, in3 TemplateOpt) ([]runtime.RawExtension, error)
 
-	// UpdateDCFunc mocks the UpdateDC method.
-	UpdateDCFunc func(ns string, dc *appsv1.DeploymentConfig) error
-
 	// calls tracks calls to the methods.
 	calls struct {
-		// Delete holds details about calls to the Delete method.
-		Delete []struct {
+		// GetConfigMap holds details about calls to the GetConfigMap method.
+		GetConfigMap []struct {
 			// Ns is the ns argument value.
 			Ns string
-			// Label is the label argument value.
-			Label string
+			// Name is the name argument value.
+			Name string
 		}
 		// GetDC holds details about calls to the GetDC method.
 		GetDC []struct {
@@ -82,12 +97,33 @@ type OSClientInterfaceMock struct {
 			// DcName is the dcName argument value.
 			DcName string
 		}
-		// GetPod holds details about calls to the GetPod method.
-		GetPod []struct {
+		// GetDeployment holds details about calls to the GetDeployment method.
+		GetDeployment []struct {
+			// Ns is the ns argument value.
+			Ns string
+			// Name is the name argument value.
+			Name string
+		}
+		// GetEndpoints holds details about calls to the GetEndpoints method.
+		GetEndpoints []struct {
+			// Ns is the ns argument value.
+			Ns string
+			// Name is the name argument value.
+			Name string
+		}
+		// GetPods holds details about calls to the GetPods method.
+		GetPods []struct {
 			// Ns is the ns argument value.
 			Ns string
-			// Dc is the dc argument value.
-			Dc string
+			// Selector is the selector argument value.
+			Selector string
+		}
+		// GetSecret holds details about calls to the GetSecret method.
+		GetSecret []struct {
+			// Ns is the ns argument value.
+			Ns string
+			// Name is the name argument value.
+			Name string
 		}
 		// ProcessTemplate holds details about calls to the ProcessTemplate method.
 		ProcessTemplate []struct {
@@ -98,48 +134,41 @@ type OSClientInterfaceMock struct {
 			// In3 is the in3 argument value.
 			In3 TemplateOpt
 		}
-		// UpdateDC holds details about calls to the UpdateDC method.
-		UpdateDC []struct {
-			// Ns is the ns argument value.
-			Ns string
-			// Dc is the dc argument value.
-			Dc *appsv1.DeploymentConfig
-		}
 	}
 }
 
-// Delete calls DeleteFunc.
-func (mock *OSClientInterfaceMock) Delete(ns string, label string) error {
-	if mock.DeleteFunc == nil {
-		panic("OSClientInterfaceMock.DeleteFunc: method is nil but OSClientInterface.Delete was just called")
+// GetConfigMap calls GetConfigMapFunc.
+func (mock *OSClientInterfaceMock) GetConfigMap(ns string, name string) (v1.ConfigMap, error) {
+	if mock.GetConfigMapFunc == nil {
+		panic("OSClientInterfaceMock.GetConfigMapFunc: method is nil but OSClientInterface.GetConfigMap was just called")
 	}
 	callInfo := struct {
-		Ns    string
-		Label string
+		Ns   string
+		Name string
 	}{
-		Ns:    ns,
-		Label: label,
+		Ns:   ns,
+		Name: name,
 	}
-	lockOSClientInterfaceMockDelete.Lock()
-	mock.calls.Delete = append(mock.calls.Delete, callInfo)
-	lockOSClientInterfaceMockDelete.Unlock()
-	return mock.DeleteFunc(ns, label)
+	lockOSClientInterfaceMockGetConfigMap.Lock()
+	mock.calls.GetConfigMap = append(mock.calls.GetConfigMap, callInfo)
+	lockOSClientInterfaceMockGetConfigMap.Unlock()
+	return mock.GetConfigMapFunc(ns, name)
 }
 
-// DeleteCalls gets all the calls that were made to Delete.
+// GetConfigMapCalls gets all the calls that were made to GetConfigMap.
 // Check the length with:
-//     len(mockedOSClientInterface.DeleteCalls())
-func (mock *OSClientInterfaceMock) DeleteCalls() []struct {
-	Ns    string
-	Label string
+//     len(mockedOSClientInterface.GetConfigMapCalls())
+func (mock *OSClientInterfaceMock) GetConfigMapCalls() []struct {
+	Ns   string
+	Name string
 } {
 	var calls []struct {
-		Ns    string
-		Label string
+		Ns   string
+		Name string
 	}
-	lockOSClientInterfaceMockDelete.RLock()
-	calls = mock.calls.Delete
-	lockOSClientInterfaceMockDelete.RUnlock()
+	lockOSClientInterfaceMockGetConfigMap.RLock()
+	calls = mock.calls.GetConfigMap
+	lockOSClientInterfaceMockGetConfigMap.RUnlock()
 	return calls
 }
 
@@ -178,38 +207,143 @@ func (mock *OSClientInterfaceMock) GetDCCalls() []struct {
 	return calls
 }
 
-// GetPod calls GetPodFunc.
-func (mock *OSClientInterfaceMock) GetPod(ns string, dc string) (v1.Pod, error) {
-	if mock.GetPodFunc == nil {
-		panic("OSClientInterfaceMock.GetPodFunc: method is nil but OSClientInterface.GetPod was just called")
+// GetDeployment calls GetDeploymentFunc.
+func (mock *OSClientInterfaceMock) GetDeployment(ns string, name string) (k8sappsv1.Deployment, error) {
+	if mock.GetDeploymentFunc == nil {
+		panic("OSClientInterfaceMock.GetDeploymentFunc: method is nil but OSClientInterface.GetDeployment was just called")
+	}
+	callInfo := struct {
+		Ns   string
+		Name string
+	}{
+		Ns:   ns,
+		Name: name,
+	}
+	lockOSClientInterfaceMockGetDeployment.Lock()
+	mock.calls.GetDeployment = append(mock.calls.GetDeployment, callInfo)
+	lockOSClientInterfaceMockGetDeployment.Unlock()
+	return mock.GetDeploymentFunc(ns, name)
+}
+
+// GetDeploymentCalls gets all the calls that were made to GetDeployment.
+// Check the length with:
+//     len(mockedOSClientInterface.GetDeploymentCalls())
+func (mock *OSClientInterfaceMock) GetDeploymentCalls() []struct {
+	Ns   string
+	Name string
+} {
+	var calls []struct {
+		Ns   string
+		Name string
+	}
+	lockOSClientInterfaceMockGetDeployment.RLock()
+	calls = mock.calls.GetDeployment
+	lockOSClientInterfaceMockGetDeployment.RUnlock()
+	return calls
+}
+
+// GetEndpoints calls GetEndpointsFunc.
+func (mock *OSClientInterfaceMock) GetEndpoints(ns string, name string) (v1.Endpoints, error) {
+	if mock.GetEndpointsFunc == nil {
+		panic("OSClientInterfaceMock.GetEndpointsFunc: method is nil but OSClientInterface.GetEndpoints was just called")
+	}
+	callInfo := struct {
+		Ns   string
+		Name string
+	}{
+		Ns:   ns,
+		Name: name,
+	}
+	lockOSClientInterfaceMockGetEndpoints.Lock()
+	mock.calls.GetEndpoints = append(mock.calls.GetEndpoints, callInfo)
+	lockOSClientInterfaceMockGetEndpoints.Unlock()
+	return mock.GetEndpointsFunc(ns, name)
+}
+
+// GetEndpointsCalls gets all the calls that were made to GetEndpoints.
+// Check the length with:
+//     len(mockedOSClientInterface.GetEndpointsCalls())
+func (mock *OSClientInterfaceMock) GetEndpointsCalls() []struct {
+	Ns   string
+	Name string
+} {
+	var calls []struct {
+		Ns   string
+		Name string
+	}
+	lockOSClientInterfaceMockGetEndpoints.RLock()
+	calls = mock.calls.GetEndpoints
+	lockOSClientInterfaceMockGetEndpoints.RUnlock()
+	return calls
+}
+
+// GetPods calls GetPodsFunc.
+func (mock *OSClientInterfaceMock) GetPods(ns string, selector string) ([]v1.Pod, error) {
+	if mock.GetPodsFunc == nil {
+		panic("OSClientInterfaceMock.GetPodsFunc: method is nil but OSClientInterface.GetPods was just called")
+	}
+	callInfo := struct {
+		Ns       string
+		Selector string
+	}{
+		Ns:       ns,
+		Selector: selector,
+	}
+	lockOSClientInterfaceMockGetPods.Lock()
+	mock.calls.GetPods = append(mock.calls.GetPods, callInfo)
+	lockOSClientInterfaceMockGetPods.Unlock()
+	return mock.GetPodsFunc(ns, selector)
+}
+
+// GetPodsCalls gets all the calls that were made to GetPods.
+// Check the length with:
+//     len(mockedOSClientInterface.GetPodsCalls())
+func (mock *OSClientInterfaceMock) GetPodsCalls() []struct {
+	Ns       string
+	Selector string
+} {
+	var calls []struct {
+		Ns       string
+		Selector string
+	}
+	lockOSClientInterfaceMockGetPods.RLock()
+	calls = mock.calls.GetPods
+	lockOSClientInterfaceMockGetPods.RUnlock()
+	return calls
+}
+
+// GetSecret calls GetSecretFunc.
+func (mock *OSClientInterfaceMock) GetSecret(ns string, name string) (v1.Secret, error) {
+	if mock.GetSecretFunc == nil {
+		panic("OSClientInterfaceMock.GetSecretFunc: method is nil but OSClientInterface.GetSecret was just called")
 	}
 	callInfo := struct {
-		Ns string
-		Dc string
+		Ns   string
+		Name string
 	}{
-		Ns: ns,
-		Dc: dc,
+		Ns:   ns,
+		Name: name,
 	}
-	lockOSClientInterfaceMockGetPod.Lock()
-	mock.calls.GetPod = append(mock.calls.GetPod, callInfo)
-	lockOSClientInterfaceMockGetPod.Unlock()
-	return mock.GetPodFunc(ns, dc)
+	lockOSClientInterfaceMockGetSecret.Lock()
+	mock.calls.GetSecret = append(mock.calls.GetSecret, callInfo)
+	lockOSClientInterfaceMockGetSecret.Unlock()
+	return mock.GetSecretFunc(ns, name)
 }
 
-// GetPodCalls gets all the calls that were made to GetPod.
+// GetSecretCalls gets all the calls that were made to GetSecret.
 // Check the length with:
-//     len(mockedOSClientInterface.GetPodCalls())
-func (mock *OSClientInterfaceMock) GetPodCalls() []struct {
-	Ns string
-	Dc string
+//     len(mockedOSClientInterface.GetSecretCalls())
+func (mock *OSClientInterfaceMock) GetSecretCalls() []struct {
+	Ns   string
+	Name string
 } {
 	var calls []struct {
-		Ns string
-		Dc string
+		Ns   string
+		Name string
 	}
-	lockOSClientInterfaceMockGetPod.RLock()
-	calls = mock.calls.GetPod
-	lockOSClientInterfaceMockGetPod.RUnlock()
+	lockOSClientInterfaceMockGetSecret.RLock()
+	calls = mock.calls.GetSecret
+	lockOSClientInterfaceMockGetSecret.RUnlock()
 	return calls
 }
 
@@ -251,38 +385,3 @@ func (mock *OSClientInterfaceMock) ProcessTemplateCalls() []struct {
 	lockOSClientInterfaceMockProcessTemplate.RUnlock()
 	return calls
 }
-
-// UpdateDC calls UpdateDCFunc.
-func (mock *OSClientInterfaceMock) UpdateDC(ns string, dc *appsv1.DeploymentConfig) error {
-	if mock.UpdateDCFunc == nil {
-		panic("OSClientInterfaceMock.UpdateDCFunc: method is nil but OSClientInterface.UpdateDC was just called")
-	}
-	callInfo := struct {
-		Ns string
-		Dc *appsv1.DeploymentConfig
-	}{
-		Ns: ns,
-		Dc: dc,
-	}
-	lockOSClientInterfaceMockUpdateDC.Lock()
-	mock.calls.UpdateDC = append(mock.calls.UpdateDC, callInfo)
-	lockOSClientInterfaceMockUpdateDC.Unlock()
-	return mock.UpdateDCFunc(ns, dc)
-}
-
-// UpdateDCCalls gets all the calls that were made to UpdateDC.
-// Check the length with:
-//     len(mockedOSClientInterface.UpdateDCCalls())
-func (mock *OSClientInterfaceMock) UpdateDCCalls() []struct {
-	Ns string
-	Dc *appsv1.DeploymentConfig
-} {
-	var calls []struct {
-		Ns string
-		Dc *appsv1.DeploymentConfig
-	}
-	lockOSClientInterfaceMockUpdateDC.RLock()
-	calls = mock.calls.UpdateDC
-	lockOSClientInterfaceMockUpdateDC.RUnlock()
-	return calls
-}
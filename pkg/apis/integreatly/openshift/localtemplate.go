@@ -0,0 +1,231 @@
+package openshift
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	v1template "github.com/openshift/api/template/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	generatorExpression = "expression"
+
+	alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numerals = "0123456789"
+	symbols  = "~!@#$%^&*()-_+={}[]\\|<,>.?/\"';:`"
+
+	maxGeneratedLength = 255
+)
+
+var (
+	stringParameterExp    = regexp.MustCompile(`\$\{([a-zA-Z0-9\_]+?)\}`)
+	nonStringParameterExp = regexp.MustCompile(`^\$\{\{([a-zA-Z0-9\_]+)\}\}$`)
+	generatorExp          = regexp.MustCompile(`\[([a-zA-Z0-9\-\\]+)\](\{(\w+)\})`)
+	rangeExp              = regexp.MustCompile(`([\\]?[a-zA-Z0-9]\-?[a-zA-Z0-9]?)`)
+)
+
+// LocalTemplate processes OpenShift templates in the operator, following the same rules as the
+// template.openshift.io processedtemplates API without the round-trip to the cluster.
+type LocalTemplate struct {
+	namespace string
+}
+
+func NewLocalTemplate(namespace string) *LocalTemplate {
+	return &LocalTemplate{namespace: namespace}
+}
+
+func (template *LocalTemplate) getNS() string {
+	return template.namespace
+}
+
+func (template *LocalTemplate) Process(tmpl *v1template.Template, params map[string]string, opts TemplateOpt) ([]runtime.RawExtension, error) {
+	template.FillParams(tmpl, params)
+
+	values := make(map[string]string, len(tmpl.Parameters))
+	for i, param := range tmpl.Parameters {
+		if param.Value == "" && param.Generate != "" {
+			value, err := GenerateValue(param)
+			if err != nil {
+				return nil, fmt.Errorf("template.parameters[%d]: %v", i, err)
+			}
+			tmpl.Parameters[i].Value = value
+		}
+		if param.Required && tmpl.Parameters[i].Value == "" {
+			return nil, fmt.Errorf("template.parameters[%d]: parameter %s is required and must be specified", i, param.Name)
+		}
+		values[param.Name] = tmpl.Parameters[i].Value
+	}
+
+	objects := make([]runtime.RawExtension, 0, len(tmpl.Objects))
+	for i, obj := range tmpl.Objects {
+		data := obj.Raw
+		if len(data) == 0 && obj.Object != nil {
+			var err error
+			data, err = json.Marshal(obj.Object)
+			if err != nil {
+				return nil, fmt.Errorf("template.objects[%d]: %v", i, err)
+			}
+		}
+
+		var content interface{}
+		if err := json.Unmarshal(data, &content); err != nil {
+			return nil, fmt.Errorf("template.objects[%d]: %v", i, err)
+		}
+		content, err := substitute(content, values)
+		if err != nil {
+			return nil, fmt.Errorf("template.objects[%d]: %v", i, err)
+		}
+		data, err = json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("template.objects[%d]: %v", i, err)
+		}
+		objects = append(objects, runtime.RawExtension{Raw: data})
+	}
+
+	return objects, nil
+}
+
+func (template *LocalTemplate) FillParams(tmpl *v1template.Template, params map[string]string) {
+	for i, param := range tmpl.Parameters {
+		if value, ok := params[param.Name]; ok {
+			tmpl.Parameters[i].Value = value
+		}
+	}
+}
+
+// substitute walks a decoded JSON document and replaces parameter references in every string,
+// map values and map keys alike.
+func substitute(in interface{}, values map[string]string) (interface{}, error) {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			newVal, err := substitute(val, values)
+			if err != nil {
+				return nil, err
+			}
+			out[substituteString(key, values)] = newVal
+		}
+		return out, nil
+	case []interface{}:
+		for i, val := range v {
+			newVal, err := substitute(val, values)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = newVal
+		}
+		return v, nil
+	case string:
+		// ${{PARAM}} must be the whole value, its content is used as a non-string value
+		if match := nonStringParameterExp.FindStringSubmatch(v); match != nil {
+			if value, ok := values[match[1]]; ok {
+				var out interface{}
+				if err := json.Unmarshal([]byte(value), &out); err != nil {
+					return nil, fmt.Errorf("value of parameter %s can not be used as a non-string value: %v", match[1], err)
+				}
+				return out, nil
+			}
+			return v, nil
+		}
+		return substituteString(v, values), nil
+	}
+	return in, nil
+}
+
+// substituteString replaces ${PARAM} references, unknown parameters are left untouched
+func substituteString(in string, values map[string]string) string {
+	return stringParameterExp.ReplaceAllStringFunc(in, func(ref string) string {
+		name := stringParameterExp.FindStringSubmatch(ref)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// GenerateValue generates the value of a template parameter with a generator, only the "expression" generator
+// is supported
+func GenerateValue(param v1template.Parameter) (string, error) {
+	if param.Generate != generatorExpression {
+		return "", fmt.Errorf("unsupported generator %q for parameter %s", param.Generate, param.Name)
+	}
+	value, err := generateExpressionValue(param.From)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate value for parameter %s: %v", param.Name, err)
+	}
+	return value, nil
+}
+
+// generateExpressionValue implements the "expression" generator, e.g. "[a-zA-Z0-9]{8}" or "test[\d]{4}"
+func generateExpressionValue(from string) (string, error) {
+	var genErr error
+	out := generatorExp.ReplaceAllStringFunc(from, func(expr string) string {
+		if genErr != nil {
+			return expr
+		}
+		match := generatorExp.FindStringSubmatch(expr)
+		length, err := strconv.Atoi(match[3])
+		if err != nil || length <= 0 || length > maxGeneratedLength {
+			genErr = fmt.Errorf("invalid length %q in expression %q, must be between 1 and %d", match[3], expr, maxGeneratedLength)
+			return expr
+		}
+		charset, err := expressionCharset(match[1])
+		if err != nil {
+			genErr = err
+			return expr
+		}
+		value := make([]byte, length)
+		max := big.NewInt(int64(len(charset)))
+		for i := range value {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				genErr = err
+				return expr
+			}
+			value[i] = charset[n.Int64()]
+		}
+		return string(value)
+	})
+	if genErr != nil {
+		return "", genErr
+	}
+	return out, nil
+}
+
+func expressionCharset(ranges string) (string, error) {
+	charset := ""
+	for _, r := range rangeExp.FindAllString(ranges, -1) {
+		switch {
+		case r == `\w`:
+			charset += alphabet + numerals + "_"
+		case r == `\d`:
+			charset += numerals
+		case r == `\a`:
+			charset += alphabet + numerals
+		case r == `\A`:
+			charset += symbols
+		case strings.HasPrefix(r, `\`):
+			return "", fmt.Errorf("unsupported character class %q", r)
+		case len(r) == 3 && r[1] == '-':
+			if r[0] > r[2] {
+				return "", fmt.Errorf("invalid range %q", r)
+			}
+			for c := r[0]; c <= r[2]; c++ {
+				charset += string(c)
+			}
+		default:
+			charset += r
+		}
+	}
+	if charset == "" {
+		return "", fmt.Errorf("empty character range %q", ranges)
+	}
+	return charset, nil
+}
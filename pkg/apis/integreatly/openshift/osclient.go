@@ -6,6 +6,7 @@ import (
 	v12 "github.com/openshift/api/template/v1"
 	appsv1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	routev1 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -14,10 +15,11 @@ import (
 
 func NewOSClient(kubeClient kubernetes.Interface, routeClient routev1.RouteV1Interface, dcClient appsv1.AppsV1Interface, tmpl TemplateHandler) (*OSClient, error) {
 	return &OSClient{
-		kubeClient:    kubeClient,
-		ocRouteClient: routeClient,
-		ocDCClient:    dcClient,
-		TmplHandler:   tmpl,
+		kubeClient:       kubeClient,
+		ocRouteClient:    routeClient,
+		ocDCClient:       dcClient,
+		TmplHandler:      tmpl,
+		LocalTmplHandler: NewLocalTemplate(tmpl.getNS()),
 	}, nil
 }
 
@@ -31,48 +33,58 @@ func (osClient *OSClient) GetDC(ns string, dcName string) (osappsv1.DeploymentCo
 
 }
 
-func (osClient *OSClient) ProcessTemplate(tmpl *v12.Template, params map[string]string, TemplateDefaultOpts TemplateOpt) ([]runtime.RawExtension, error) {
-	return osClient.TmplHandler.Process(tmpl, params, TemplateDefaultOpts)
-}
-
-func (osClient *OSClient) UpdateDC(ns string, dc *osappsv1.DeploymentConfig) error {
-	_, err := osClient.ocDCClient.DeploymentConfigs(ns).Update(dc)
-	return err
+func (osClient *OSClient) ProcessTemplate(tmpl *v12.Template, params map[string]string, opts TemplateOpt) ([]runtime.RawExtension, error) {
+	if opts.Local {
+		if osClient.LocalTmplHandler == nil {
+			return nil, errors.New("local template processing is not configured")
+		}
+		return osClient.LocalTmplHandler.Process(tmpl, params, opts)
+	}
+	return osClient.TmplHandler.Process(tmpl, params, opts)
 }
 
-func (osClient *OSClient) GetPod(ns string, dc string) (v1.Pod, error) {
-	pods := osClient.kubeClient.CoreV1().Pods(ns)
-
-	poList, err := pods.List(meta_v1.ListOptions{LabelSelector: "deploymentconfig=" + dc})
+func (osClient *OSClient) GetDeployment(ns string, name string) (k8sappsv1.Deployment, error) {
+	deployment, err := osClient.kubeClient.AppsV1().Deployments(ns).Get(name, meta_v1.GetOptions{})
 	if err != nil {
-		return v1.Pod{}, err
+		return k8sappsv1.Deployment{}, err
 	}
 
-	if len(poList.Items) == 0 {
-		return v1.Pod{}, errors.New("Pod not found")
+	return *deployment, nil
+}
+
+// GetPods returns the pods matching the label selector
+func (osClient *OSClient) GetPods(ns string, selector string) ([]v1.Pod, error) {
+	pods, err := osClient.kubeClient.CoreV1().Pods(ns).List(meta_v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
 	}
 
-	return poList.Items[0], nil
+	return pods.Items, nil
 }
 
-func (osClient *OSClient) Delete(ns string, label string) error {
-	deleteOpts := meta_v1.NewDeleteOptions(0)
-	listOpts := meta_v1.ListOptions{LabelSelector: "app=" + label}
-
-	err := osClient.ocDCClient.DeploymentConfigs(ns).DeleteCollection(deleteOpts, listOpts)
+func (osClient *OSClient) GetEndpoints(ns string, name string) (v1.Endpoints, error) {
+	endpoints, err := osClient.kubeClient.CoreV1().Endpoints(ns).Get(name, meta_v1.GetOptions{})
 	if err != nil {
-		return err
+		return v1.Endpoints{}, err
 	}
 
-	err = osClient.kubeClient.CoreV1().Services(ns).Delete(label, deleteOpts)
+	return *endpoints, nil
+}
+
+func (osClient *OSClient) GetConfigMap(ns string, name string) (v1.ConfigMap, error) {
+	cm, err := osClient.kubeClient.CoreV1().ConfigMaps(ns).Get(name, meta_v1.GetOptions{})
 	if err != nil {
-		return err
+		return v1.ConfigMap{}, err
 	}
 
-	err = osClient.ocRouteClient.Routes(ns).DeleteCollection(deleteOpts, listOpts)
+	return *cm, nil
+}
+
+func (osClient *OSClient) GetSecret(ns string, name string) (v1.Secret, error) {
+	secret, err := osClient.kubeClient.CoreV1().Secrets(ns).Get(name, meta_v1.GetOptions{})
 	if err != nil {
-		return err
+		return v1.Secret{}, err
 	}
 
-	return nil
+	return *secret, nil
 }
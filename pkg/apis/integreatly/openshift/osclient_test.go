@@ -10,6 +10,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -42,106 +44,87 @@ func TestNewOSClient(t *testing.T) {
 	}
 }
 
-func TestOSClient_GetPod(t *testing.T) {
+func TestOSClient_GetPods(t *testing.T) {
+	pod := func(name string, labels map[string]string) *v1.Pod {
+		return &v1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels:    labels,
+			},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+			},
+		}
+	}
 	cases := []struct {
-		Name        string
-		Client      func() *fake.Clientset
-		Label       string
-		ExpectError bool
-		Validate    func(pod *v1.Pod, t *testing.T)
+		Name     string
+		Selector string
+		Expected []string
 	}{
 		{
-			Name:  "should find pod",
-			Label: "tutorial-web-app",
-			Client: func() *fake.Clientset {
-				fakeKube := fake.NewSimpleClientset(&v1.Pod{
-					TypeMeta: metav1.TypeMeta{
-						APIVersion: "v1",
-						Kind:       "Pod",
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "my-tutorial-pod",
-						Namespace: "test",
-						Labels: map[string]string{
-							"deploymentconfig": "tutorial-web-app",
-						},
-					},
-					Status: v1.PodStatus{
-						Phase: v1.PodRunning,
-						PodIP: "172.1.0.3",
-					},
-				})
-
-				return fakeKube
-			},
-			Validate: func(pod *v1.Pod, t *testing.T) {
-				val, ok := pod.Labels["deploymentconfig"]
-
-				if pod.Name != "my-tutorial-pod" {
-					t.Fatalf("Pod name didn't match: %v", pod)
-				}
-
-				if !ok {
-					t.Fatalf("Pod deploymentconfig label not found: %v", pod)
-				}
-
-				if val != "tutorial-web-app" {
-					t.Fatalf("Pod deploymentconfig  label did not match: %v", pod)
-				}
-
-			},
-			ExpectError: false,
+			Name:     "should find the pods of the deployment config",
+			Selector: "deploymentconfig=tutorial-web-app",
+			Expected: []string{"tutorial-web-app-1-abcde", "tutorial-web-app-2-fghij"},
 		},
 		{
-			Name:  "should not find pod",
-			Label: "tutorial-web-ap",
-			Client: func() *fake.Clientset {
-				fakeKube := fake.NewSimpleClientset(&v1.Pod{
-					TypeMeta: metav1.TypeMeta{
-						APIVersion: "v1",
-						Kind:       "Pod",
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "test",
-					},
-					Status: v1.PodStatus{
-						Phase: v1.PodRunning,
-						PodIP: "172.1.0.3",
-					},
-				})
-
-				return fakeKube
-			},
-			Validate: func(pod *v1.Pod, t *testing.T) {
-				_, ok := pod.Labels["deploymentconfig"]
-
-				if ok {
-					t.Fatalf("Pod should not have a deploymentconfig label: %v", pod)
-				}
-
-			},
-			ExpectError: true,
+			Name:     "should find the pods of the latest version",
+			Selector: "deploymentconfig=tutorial-web-app,deployment=tutorial-web-app-2",
+			Expected: []string{"tutorial-web-app-2-fghij"},
+		},
+		{
+			Name:     "should not find pods",
+			Selector: "deploymentconfig=tutorial-web-ap",
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			client := OSClient{kubeClient: tc.Client()}
-			pod, err := client.GetPod("test", tc.Label)
-
-			if tc.ExpectError && err == nil {
-				t.Fatalf("expected an error but got none")
-			}
-
-			if !tc.ExpectError && err != nil {
+			client := OSClient{kubeClient: fake.NewSimpleClientset(
+				pod("tutorial-web-app-1-abcde", map[string]string{"deploymentconfig": "tutorial-web-app", "deployment": "tutorial-web-app-1"}),
+				pod("tutorial-web-app-2-fghij", map[string]string{"deploymentconfig": "tutorial-web-app", "deployment": "tutorial-web-app-2"}),
+				pod("other", nil),
+			)}
+			pods, err := client.GetPods("test", tc.Selector)
+			if err != nil {
 				t.Fatalf("did not expect error but got %s ", err)
 			}
 
-			tc.Validate(&pod, t)
+			names := []string{}
+			for _, p := range pods {
+				names = append(names, p.Name)
+			}
+			sort.Strings(names)
+			if len(names) != len(tc.Expected) || (len(names) > 0 && !reflect.DeepEqual(names, tc.Expected)) {
+				t.Fatalf("expected pods %v, got %v", tc.Expected, names)
+			}
 		})
 	}
 }
 
+func TestOSClient_GetEndpoints(t *testing.T) {
+	client := OSClient{kubeClient: fake.NewSimpleClientset(&v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "test"},
+		Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "172.1.0.3"}}}},
+	})}
+
+	endpoints, err := client.GetEndpoints("test", "tutorial-web-app")
+	if err != nil {
+		t.Fatalf("did not expect error but got %s ", err)
+	}
+	if len(endpoints.Subsets) != 1 || endpoints.Subsets[0].Addresses[0].IP != "172.1.0.3" {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+
+	if _, err := client.GetEndpoints("test", "missing"); err == nil {
+		t.Fatalf("expected an error but got none")
+	}
+}
+
 func TestOSClient_GetDc(t *testing.T) {
 	cases := []struct {
 		Name        string
@@ -216,81 +199,3 @@ func TestOSClient_GetDc(t *testing.T) {
 		})
 	}
 }
-
-func TestOSClient_Delete(t *testing.T) {
-	cases := []struct {
-		Name        string
-		Client      func() (*fake.Clientset, *routeclientfake.Clientset, *appsclientfake.Clientset)
-		Label       string
-		ExpectError bool
-	}{
-		{
-			Name: "Should delete resources",
-			Client: func() (*fake.Clientset, *routeclientfake.Clientset, *appsclientfake.Clientset) {
-				fakeKube := fake.NewSimpleClientset(&v1.Service{
-					TypeMeta: metav1.TypeMeta{
-						APIVersion: "v1",
-						Kind:       "Service",
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "tutorial-web-app",
-						Namespace: "test",
-						Labels: map[string]string{
-							"app": "tutorial-web-app",
-						},
-					},
-				})
-				fakeRoute := routeclientfake.NewSimpleClientset()
-				fakeApp := appsclientfake.NewSimpleClientset()
-
-				return fakeKube, fakeRoute, fakeApp
-			},
-			Label:       "tutorial-web-app",
-			ExpectError: false,
-		},
-		{
-			Name: "Should not delete resources",
-			Client: func() (*fake.Clientset, *routeclientfake.Clientset, *appsclientfake.Clientset) {
-				fakeKube := fake.NewSimpleClientset(&v1.Service{
-					TypeMeta: metav1.TypeMeta{
-						APIVersion: "v1",
-						Kind:       "Service",
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "tutorial-web-app",
-						Namespace: "test",
-						Labels: map[string]string{
-							"app": "tutorial-web-app",
-						},
-					},
-				})
-				fakeRoute := routeclientfake.NewSimpleClientset()
-				fakeApp := appsclientfake.NewSimpleClientset()
-
-				return fakeKube, fakeRoute, fakeApp
-			},
-			Label:       "tutorial-web-ap",
-			ExpectError: true,
-		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.Name, func(t *testing.T) {
-			kubeClient, routeClient, appClient := tc.Client()
-			client := OSClient{
-				kubeClient:    kubeClient,
-				ocDCClient:    appClient.AppsV1(),
-				ocRouteClient: routeClient.RouteV1(),
-			}
-
-			err := client.Delete("test", tc.Label)
-			if tc.ExpectError && err == nil {
-				t.Fatalf("expected an error but got none")
-			}
-
-			if !tc.ExpectError && err != nil {
-				t.Fatalf("did not expect error but got %s ", err)
-			}
-		})
-	}
-}
@@ -0,0 +1,37 @@
+package openshift
+
+import (
+	"k8s.io/client-go/discovery"
+)
+
+type Platform string
+
+const (
+	// PlatformOpenShift serves the web app with a DeploymentConfig and a Route
+	PlatformOpenShift Platform = "openshift"
+	// PlatformKubernetes serves the web app with an apps/v1 Deployment and an Ingress
+	PlatformKubernetes Platform = "kubernetes"
+)
+
+// openShiftGroups are the API groups the operator relies on when running on OpenShift
+var openShiftGroups = []string{"apps.openshift.io", "route.openshift.io"}
+
+// DetectPlatform discovers whether the cluster serves the OpenShift API groups
+func DetectPlatform(client discovery.ServerGroupsInterface) (Platform, error) {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return "", err
+	}
+
+	found := make(map[string]bool)
+	for _, group := range groups.Groups {
+		found[group.Name] = true
+	}
+	for _, group := range openShiftGroups {
+		if !found[group] {
+			return PlatformKubernetes, nil
+		}
+	}
+
+	return PlatformOpenShift, nil
+}
@@ -0,0 +1,56 @@
+package openshift
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Resources []*metav1.APIResourceList
+		Expected  Platform
+	}{
+		{
+			Name: "Should detect OpenShift",
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1"},
+				{GroupVersion: "apps.openshift.io/v1"},
+				{GroupVersion: "route.openshift.io/v1"},
+			},
+			Expected: PlatformOpenShift,
+		},
+		{
+			Name: "Should detect Kubernetes",
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1"},
+				{GroupVersion: "apps/v1"},
+				{GroupVersion: "extensions/v1beta1"},
+			},
+			Expected: PlatformKubernetes,
+		},
+		{
+			Name: "Should require routes",
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1"},
+				{GroupVersion: "apps.openshift.io/v1"},
+			},
+			Expected: PlatformKubernetes,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := &fake.FakeDiscovery{Fake: &k8stesting.Fake{Resources: tc.Resources}}
+			platform, err := DetectPlatform(client)
+			if err != nil {
+				t.Fatalf("did not expect error but got %s ", err)
+			}
+			if platform != tc.Expected {
+				t.Fatalf("expected platform %s, got %s", tc.Expected, platform)
+			}
+		})
+	}
+}
@@ -43,9 +43,13 @@ func (template *Template) Process(tmpl *v1template.Template, params map[string]s
 		return nil, err
 	}
 
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = template.namespace
+	}
 	result := template.RestClient.
 		Post().
-		Namespace(template.namespace).
+		Namespace(namespace).
 		Body(resource).
 		Resource(opts.ApiResource).
 		Do()
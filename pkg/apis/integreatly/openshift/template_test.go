@@ -8,11 +8,14 @@ import (
 	"io"
 	"io/ioutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
 	"net/http"
 	"path"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -137,6 +140,51 @@ func TestTemplate_Process(t *testing.T) {
 	}
 }
 
+func TestTemplate_ProcessNamespace(t *testing.T) {
+	cases := []struct {
+		Name         string
+		OptNamespace string
+		ExpectedPath string
+	}{
+		{
+			Name:         "Should process in the template namespace",
+			ExpectedPath: "/namespaces/test/processedtemplates",
+		},
+		{
+			Name:         "Should process in the namespace of the options",
+			OptNamespace: "team-a",
+			ExpectedPath: "/namespaces/team-a/processedtemplates",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var requestPath string
+			tmpl := Template{
+				namespace: "test",
+				RestClient: &fake.RESTClient{
+					NegotiatedSerializer: scheme.Codecs,
+					Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+						requestPath = req.URL.Path
+						header := http.Header{}
+						header.Set("Content-Type", "application/json")
+						return &http.Response{StatusCode: 201, Header: header, Body: objBody(&v1template.Template{TypeMeta: metav1.TypeMeta{APIVersion: "template.openshift.io/v1", Kind: "Template"}})}, nil
+					}),
+				},
+			}
+
+			opts := TemplateDefaultOpts
+			opts.Namespace = tc.OptNamespace
+			if _, err := tmpl.Process(&v1template.Template{}, nil, opts); err != nil {
+				t.Fatalf("did not expect error but got %s ", err)
+			}
+			if !strings.HasSuffix(requestPath, tc.ExpectedPath) {
+				t.Fatalf("expected request to %s, got %s", tc.ExpectedPath, requestPath)
+			}
+		})
+	}
+}
+
 func TestTemplate_FillParams(t *testing.T) {
 	cases := []struct {
 		Name        string
@@ -203,3 +251,172 @@ func TestTemplate_FillParams(t *testing.T) {
 		tc.Validate(&tmpl, tc.Params, t)
 	}
 }
+
+func TestLocalTemplate_Process(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Template    func() *v1template.Template
+		Params      map[string]string
+		ExpectError bool
+		Validate    func(objects []runtime.RawExtension, t *testing.T)
+	}{
+		{
+			Name: "Should substitute params from the test template",
+			Template: func() *v1template.Template {
+				res, err := LoadKubernetesResourceFromFile(path.Join("_testdata", "test-template.yaml"))
+				if err != nil {
+					t.Fatalf("Could not load template file %v", err)
+				}
+				return res.(*v1template.Template)
+			},
+			Params: map[string]string{
+				"OPENSHIFT_HOST":   "127.0.0.1:8443",
+				"WEBAPP_IMAGE_TAG": "2.0.0",
+			},
+			Validate: func(objects []runtime.RawExtension, t *testing.T) {
+				if len(objects) != 3 {
+					t.Fatalf("expected 3 objects, got %d", len(objects))
+				}
+				raw := string(objects[0].Raw)
+				if !strings.Contains(raw, `"image":"quay.io/integreatly/tutorial-web-app:2.0.0"`) {
+					t.Fatalf("expected image to be substituted, got %s", raw)
+				}
+				if !strings.Contains(raw, `"value":"127.0.0.1:8443"`) {
+					t.Fatalf("expected OPENSHIFT_HOST to be substituted, got %s", raw)
+				}
+				if strings.Contains(raw, "${") {
+					t.Fatalf("expected all params to be substituted, got %s", raw)
+				}
+			},
+		},
+		{
+			Name: "Should substitute non-string params",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "REPLICAS", Value: "1"}, {Name: "NAME", Value: "web"}},
+					Objects: []runtime.RawExtension{
+						{Raw: []byte(`{"kind":"DeploymentConfig","metadata":{"name":"${NAME}-app"},"spec":{"replicas":"${{REPLICAS}}","label":"${UNKNOWN}"}}`)},
+					},
+				}
+			},
+			Params: map[string]string{"REPLICAS": "3"},
+			Validate: func(objects []runtime.RawExtension, t *testing.T) {
+				expected := `{"kind":"DeploymentConfig","metadata":{"name":"web-app"},"spec":{"label":"${UNKNOWN}","replicas":3}}`
+				if string(objects[0].Raw) != expected {
+					t.Fatalf("expected %s, got %s", expected, objects[0].Raw)
+				}
+			},
+		},
+		{
+			Name: "Should fail on invalid non-string value",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "REPLICAS"}},
+					Objects:    []runtime.RawExtension{{Raw: []byte(`{"spec":{"replicas":"${{REPLICAS}}"}}`)}},
+				}
+			},
+			Params:      map[string]string{"REPLICAS": "three"},
+			ExpectError: true,
+		},
+		{
+			Name: "Should generate values",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "SECRET", Generate: "expression", From: "pre-[a-f\\d]{16}", Required: true}},
+					Objects:    []runtime.RawExtension{{Raw: []byte(`{"data":"${SECRET}"}`)}},
+				}
+			},
+			Validate: func(objects []runtime.RawExtension, t *testing.T) {
+				if !regexp.MustCompile(`^\{"data":"pre-[a-f0-9]{16}"\}$`).Match(objects[0].Raw) {
+					t.Fatalf("expected generated value, got %s", objects[0].Raw)
+				}
+			},
+		},
+		{
+			Name: "Should not generate a value when one is provided",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "SECRET", Generate: "expression", From: "[a-z]{8}"}},
+					Objects:    []runtime.RawExtension{{Raw: []byte(`{"data":"${SECRET}"}`)}},
+				}
+			},
+			Params: map[string]string{"SECRET": "provided"},
+			Validate: func(objects []runtime.RawExtension, t *testing.T) {
+				if string(objects[0].Raw) != `{"data":"provided"}` {
+					t.Fatalf("expected provided value, got %s", objects[0].Raw)
+				}
+			},
+		},
+		{
+			Name: "Should fail on missing required params",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "REQUIRED", Required: true}},
+				}
+			},
+			ExpectError: true,
+		},
+		{
+			Name: "Should fail on unsupported generator",
+			Template: func() *v1template.Template {
+				return &v1template.Template{
+					Parameters: []v1template.Parameter{{Name: "SECRET", Generate: "uuid"}},
+				}
+			},
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tmpl := NewLocalTemplate("test")
+			objects, err := tmpl.Process(tc.Template(), tc.Params, TemplateLocalOpts)
+
+			if tc.ExpectError && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+
+			if !tc.ExpectError && err != nil {
+				t.Fatalf("did not expect error but got %s ", err)
+			}
+
+			if tc.Validate != nil {
+				tc.Validate(objects, t)
+			}
+		})
+	}
+}
+
+func TestGenerateExpressionValue(t *testing.T) {
+	cases := []struct {
+		From        string
+		Expected    string
+		ExpectError bool
+	}{
+		{From: "test[0-9]{1}x", Expected: `^test[0-9]x$`},
+		{From: "[0-1]{8}", Expected: `^[01]{8}$`},
+		{From: "0x[A-F0-9]{4}", Expected: `^0x[A-F0-9]{4}$`},
+		{From: "[\\w]{12}", Expected: `^[a-zA-Z0-9_]{12}$`},
+		{From: "no-expression", Expected: `^no-expression$`},
+		{From: "[a-z]{0}", ExpectError: true},
+		{From: "[z-a]{4}", ExpectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.From, func(t *testing.T) {
+			value, err := generateExpressionValue(tc.From)
+
+			if tc.ExpectError && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+
+			if !tc.ExpectError && err != nil {
+				t.Fatalf("did not expect error but got %s ", err)
+			}
+
+			if !tc.ExpectError && !regexp.MustCompile(tc.Expected).MatchString(value) {
+				t.Fatalf("expected value matching %s, got %s", tc.Expected, value)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	v1template "github.com/openshift/api/template/v1"
 	v12 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	v13 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -16,17 +17,20 @@ import (
 
 type OSClientInterface interface {
 	GetDC(ns string, dcName string) (v14.DeploymentConfig, error)
-	UpdateDC(ns string, dc *v14.DeploymentConfig) error
-	GetPod(ns string, dc string) (v1.Pod, error)
-	Delete(ns string, label string) error
+	GetDeployment(ns string, name string) (k8sappsv1.Deployment, error)
+	GetPods(ns string, selector string) ([]v1.Pod, error)
+	GetEndpoints(ns string, name string) (v1.Endpoints, error)
+	GetConfigMap(ns string, name string) (v1.ConfigMap, error)
+	GetSecret(ns string, name string) (v1.Secret, error)
 	ProcessTemplate(*v1template.Template, map[string]string, TemplateOpt) ([]runtime.RawExtension, error)
 }
 
 type OSClient struct {
-	kubeClient    kubernetes.Interface
-	TmplHandler   TemplateHandler
-	ocDCClient    v12.AppsV1Interface
-	ocRouteClient v13.RouteV1Interface
+	kubeClient       kubernetes.Interface
+	TmplHandler      TemplateHandler
+	LocalTmplHandler TemplateHandler
+	ocDCClient       v12.AppsV1Interface
+	ocRouteClient    v13.RouteV1Interface
 }
 
 type Template struct {
@@ -41,6 +45,10 @@ type TemplateOpt struct {
 	ApiGroup    string
 	ApiMimetype string
 	ApiResource string
+	// Local processes the template in the operator instead of the processedtemplates API
+	Local bool
+	// Namespace the template is processed in, the namespace of the template handler when empty
+	Namespace string
 }
 
 var (
@@ -51,6 +59,10 @@ var (
 		ApiGroup:    "template.openshift.io",
 		ApiResource: "processedtemplates",
 	}
+
+	TemplateLocalOpts = TemplateOpt{
+		Local: true,
+	}
 )
 
 type TemplateHandler interface {
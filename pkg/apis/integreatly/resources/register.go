@@ -35,12 +35,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	err = doAdd(authorization.Install, scheme, err)
 
 	// Legacy api resources
-	err = doAdd(apps.AddToScheme, scheme, err)
-	err = doAdd(template.AddToScheme, scheme, err)
-	err = doAdd(image.AddToScheme, scheme, err)
-	err = doAdd(route.AddToScheme, scheme, err)
-	err = doAdd(build.AddToScheme, scheme, err)
-	err = doAdd(authorization.AddToScheme, scheme, err)
+	err = doAdd(apps.DeprecatedInstallWithoutGroup, scheme, err)
+	err = doAdd(template.DeprecatedInstallWithoutGroup, scheme, err)
+	err = doAdd(image.DeprecatedInstallWithoutGroup, scheme, err)
+	err = doAdd(route.DeprecatedInstallWithoutGroup, scheme, err)
+	err = doAdd(build.DeprecatedInstallWithoutGroup, scheme, err)
+	err = doAdd(authorization.DeprecatedInstallWithoutGroup, scheme, err)
 
 	return err
 }
@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition reasons, these are part of the API and must not change once released
+const (
+	ReasonTemplateProcessFailed  = "TemplateProcessFailed"
+	ReasonParameterResolveFailed = "ParameterResolveFailed"
+	ReasonTemplateProcessed      = "TemplateProcessed"
+	ReasonInvalidObjects         = "InvalidObjects"
+	ReasonProvisionFailed        = "ProvisionFailed"
+	ReasonProvisioned            = "Provisioned"
+	ReasonReconcileFailed        = "ReconcileFailed"
+	ReasonRolloutComplete        = "RolloutComplete"
+	ReasonRolloutInProgress      = "RolloutInProgress"
+	ReasonRolloutFailed          = "RolloutFailed"
+	ReasonPodsNotReady           = "PodsNotReady"
+	ReasonRouteCreated           = "RouteCreated"
+	ReasonRouteAdmitted          = "RouteAdmitted"
+	ReasonRouteRejected          = "RouteRejected"
+	ReasonReady                  = "Ready"
+	ReasonDeploymentUnavailable  = "DeploymentUnavailable"
+	ReasonNoEndpoints            = "NoEndpoints"
+	ReasonRouteNotAdmitted       = "RouteNotAdmitted"
+	ReasonOAuthClientSynced      = "OAuthClientSynced"
+	ReasonOAuthClientFailed      = "OAuthClientFailed"
+	ReasonRouteHostPending       = "RouteHostPending"
+	ReasonMigrationFailed        = "MigrationFailed"
+	ReasonPaused                 = "Paused"
+	ReasonResumed                = "Resumed"
+	ReasonScaledDown             = "ScaledDown"
+	ReasonRestoreInProgress      = "RestoreInProgress"
+	ReasonRestoreFailed          = "RestoreFailed"
+)
+
+// GetCondition returns the condition of the given type or nil if it has not been set
+func (s *WebAppStatus) GetCondition(t WebAppConditionType) *WebAppCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns true when the condition of the given type is set and has status True
+func (s *WebAppStatus) IsConditionTrue(t WebAppConditionType) bool {
+	c := s.GetCondition(t)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or updates the condition of the given type. The transition time is
+// only moved forward when the status of the condition changes.
+func (s *WebAppStatus) SetCondition(t WebAppConditionType, status corev1.ConditionStatus, reason, msg string, generation int64) {
+	c := s.GetCondition(t)
+	if c == nil {
+		s.Conditions = append(s.Conditions, WebAppCondition{Type: t})
+		c = &s.Conditions[len(s.Conditions)-1]
+	}
+	if c.Status != status {
+		c.LastTransitionTime = metav1.Now()
+	}
+	c.Status = status
+	c.Reason = reason
+	c.Message = msg
+	c.ObservedGeneration = generation
+}
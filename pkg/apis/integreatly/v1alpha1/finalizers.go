@@ -0,0 +1,41 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebAppFinalizer blocks the deletion of a WebApp until the operator released the resources that are
+// not garbage collected through owner references, e.g. cluster scoped objects
+const WebAppFinalizer = "finalizer.webapp.integreatly.org"
+
+func (w *WebApp) HasFinalizer(finalizer string) bool {
+	for _, f := range w.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WebApp) AddFinalizer(finalizer string) {
+	if w.HasFinalizer(finalizer) {
+		return
+	}
+	w.SetFinalizers(append(w.GetFinalizers(), finalizer))
+}
+
+func (w *WebApp) RemoveFinalizer(finalizer string) {
+	finalizers := make([]string, 0, len(w.GetFinalizers()))
+	for _, f := range w.GetFinalizers() {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	w.SetFinalizers(finalizers)
+}
+
+// OwnerReference returns the controller reference set on the objects created for the WebApp, so they
+// are garbage collected with it
+func (w *WebApp) OwnerReference() metav1.OwnerReference {
+	return *metav1.NewControllerRef(w, SchemeGroupVersion.WithKind("WebApp"))
+}
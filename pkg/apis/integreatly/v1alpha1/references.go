@@ -0,0 +1,31 @@
+package v1alpha1
+
+// ReferencedSecrets returns the names of the Secrets the operator reads for the WebApp, changes to them
+// are reconciled like changes to the WebApp
+func (w *WebApp) ReferencedSecrets() []string {
+	names := []string{}
+	for _, p := range w.Spec.Template.ParametersFrom {
+		if p.ValueFrom.SecretKeyRef != nil {
+			names = append(names, p.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	if route := w.Spec.Route; route != nil && route.TLS != nil && route.TLS.CertificateSecret != "" {
+		names = append(names, route.TLS.CertificateSecret)
+	}
+	return names
+}
+
+// ReferencedConfigMaps returns the names of the ConfigMaps the operator reads for the WebApp, the ConfigMap
+// of the template and the ConfigMaps of parameters
+func (w *WebApp) ReferencedConfigMaps() []string {
+	names := []string{}
+	if w.Spec.Template.ConfigMap != nil {
+		names = append(names, w.Spec.Template.ConfigMap.Name)
+	}
+	for _, p := range w.Spec.Template.ParametersFrom {
+		if p.ValueFrom.ConfigMapKeyRef != nil {
+			names = append(names, p.ValueFrom.ConfigMapKeyRef.Name)
+		}
+	}
+	return names
+}
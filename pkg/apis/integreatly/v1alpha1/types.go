@@ -1,7 +1,12 @@
 package v1alpha1
 
 import (
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -24,14 +29,342 @@ type WebApp struct {
 type WebAppSpec struct {
 	AppLabel string         `json:"app_label"`
 	Template WebAppTemplate `json:"template"`
+	// Image of the web app, by tag or digest. Defaults to the image the operator is configured with
+	Image string `json:"image,omitempty"`
+	// ImagePolicy controls how the image of an existing web app is reconciled, defaults to ImagePolicyEnforce
+	ImagePolicy ImagePolicy `json:"imagePolicy,omitempty"`
+	// Replicas of the web app, defaults to the replicas of the template. More than one replica requires
+	// ReadWriteMany storage.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Strategy replaces the old pods of the web app with new ones, defaults to the strategy of the template
+	Strategy *WebAppStrategy `json:"strategy,omitempty"`
+	// Storage configures the volume claim the web app keeps its data on
+	Storage *WebAppStorage `json:"storage,omitempty"`
+	// PodTemplate overrides the resources, probes and scheduling constraints of the web app pod
+	PodTemplate *WebAppPodTemplate `json:"podTemplate,omitempty"`
+	// Backup schedules backups of the walkthrough progress of the users
+	Backup *WebAppBackup `json:"backup,omitempty"`
+	// Restore seeds the storage of a new web app from a backup
+	Restore *WebAppRestore `json:"restore,omitempty"`
+	// Route configures the route, or the Ingress on Kubernetes, the web app is served through
+	Route *WebAppRoute `json:"route,omitempty"`
+	// Paused stops the operator from changing the objects of the web app, the status is still reported
+	Paused bool `json:"paused,omitempty"`
 }
 
+type StrategyType string
+
+const (
+	// StrategyRecreate stops the old pods before the new ones are started
+	StrategyRecreate StrategyType = "Recreate"
+	// StrategyRolling replaces the old pods gradually, old and new pods run side by side
+	StrategyRolling StrategyType = "Rolling"
+)
+
+type WebAppStrategy struct {
+	Type StrategyType `json:"type"`
+	// MaxSurge is the number or percentage of pods started above the replicas during a Rolling update
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// MaxUnavailable is the number or percentage of replicas that may be unavailable during a Rolling update
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// WebAppPodTemplate holds the settings of the web app pod that replace the ones of the template, unset
+// fields keep the values of the template. NodeSelector is merged into the node selector of the template.
+type WebAppPodTemplate struct {
+	// Resources of the web app container
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// LivenessProbe and ReadinessProbe of the web app container
+	LivenessProbe  *corev1.Probe `json:"livenessProbe,omitempty"`
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	NodeSelector      map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations       []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity          *corev1.Affinity    `json:"affinity,omitempty"`
+	PriorityClassName string              `json:"priorityClassName,omitempty"`
+}
+
+type WebAppStorage struct {
+	// AccessMode of the volume claim, ReadWriteOnce or ReadWriteMany. Defaults to the access mode of the
+	// template. It can not be changed once the claim was created.
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+	// Size of the volume claim, defaults to the size of the template. A larger size expands the claim when its
+	// storage class allows volume expansion, claims are never shrunk.
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName of the volume claim, defaults to the default storage class of the cluster. It can not be
+	// changed once the claim was created.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// EmptyDir keeps the data of the web app in an emptyDir volume instead of a volume claim, the data is lost
+	// when the pod is replaced. Size limits the emptyDir.
+	EmptyDir bool `json:"emptyDir,omitempty"`
+}
+
+type WebAppBackup struct {
+	// Schedule of the backups in cron format
+	Schedule string `json:"schedule"`
+	// Suspend stops scheduling backups, running backups are not stopped
+	Suspend bool `json:"suspend,omitempty"`
+	// Target the archives are written to
+	Target BackupLocation `json:"target"`
+	// Image of the backup and restore containers, it needs sh, tar and the MinIO client mc. Defaults to
+	// the MinIO client image.
+	Image string `json:"image,omitempty"`
+}
+
+type WebAppRestore struct {
+	// Source the archive is read from
+	Source BackupLocation `json:"source"`
+	// Archive to restore, defaults to the latest archive of the source
+	Archive string `json:"archive,omitempty"`
+	// Image of the restore container, defaults to the image of the backups
+	Image string `json:"image,omitempty"`
+}
+
+// BackupLocation is where backup archives are kept, exactly one of S3 and PersistentVolumeClaim is set
+type BackupLocation struct {
+	S3                    *S3Location    `json:"s3,omitempty"`
+	PersistentVolumeClaim *ClaimLocation `json:"persistentVolumeClaim,omitempty"`
+}
+
+// S3Location is a prefix in a bucket of an S3 compatible object store
+type S3Location struct {
+	// Endpoint of the object store, e.g. https://s3.amazonaws.com or http://minio:9000
+	Endpoint string `json:"endpoint"`
+	Bucket   string `json:"bucket"`
+	// Prefix of the archives in the bucket, defaults to the namespace and the name of the WebApp
+	Prefix string `json:"prefix,omitempty"`
+	// CredentialsSecret is the name of the secret holding the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	// of the object store
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// ClaimLocation is a directory on a volume claim in the namespace of the WebApp
+type ClaimLocation struct {
+	ClaimName string `json:"claimName"`
+	// Path of the archives on the volume, defaults to the name of the WebApp
+	Path string `json:"path,omitempty"`
+}
+
+type WebAppRoute struct {
+	// Host of the route, defaults to the host derived from ROUTING_SUBDOMAIN or to the host generated by the router
+	Host string `json:"host,omitempty"`
+	// Path the web app is served under, defaults to all paths of the host
+	Path string `json:"path,omitempty"`
+	// TLS configures the termination of the route, defaults to edge termination with the certificate of the router
+	TLS *WebAppRouteTLS `json:"tls,omitempty"`
+	// Annotations are added to the route, e.g. haproxy.router.openshift.io/timeout or
+	// haproxy.router.openshift.io/ip_whitelist
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels are added to the labels of the WebApp on the route, e.g. to select a router shard
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type WebAppRouteTLS struct {
+	// Termination is edge, reencrypt or passthrough, defaults to edge
+	Termination routev1.TLSTerminationType `json:"termination,omitempty"`
+	// CertificateSecret is the name of a kubernetes.io/tls Secret holding the tls.crt and tls.key the route
+	// serves, and optionally the ca.crt of their chain. Defaults to the certificate of the router.
+	CertificateSecret string `json:"certificateSecret,omitempty"`
+	// DestinationCACertificate is the PEM encoded CA the router verifies the web app with on reencrypt routes
+	DestinationCACertificate string `json:"destinationCACertificate,omitempty"`
+	// InsecureEdgeTerminationPolicy is None, Allow or Redirect for plain HTTP requests, defaults to None
+	InsecureEdgeTerminationPolicy routev1.InsecureEdgeTerminationPolicyType `json:"insecureEdgeTerminationPolicy,omitempty"`
+}
+
+type ImagePolicy string
+
+const (
+	// ImagePolicyEnforce migrates the web app to the desired image on every reconcile
+	ImagePolicyEnforce ImagePolicy = "Enforce"
+	// ImagePolicyIfNotSet uses the desired image when the web app is created and keeps the image it runs afterwards
+	ImagePolicyIfNotSet ImagePolicy = "IfNotSet"
+	// ImagePolicyIgnore leaves the image to the template and keeps the image the web app runs afterwards
+	ImagePolicyIgnore ImagePolicy = "Ignore"
+)
+
 type WebAppStatus struct {
+	// Message is a human readable summary of the last handled event, automation
+	// should rely on Phase and Conditions instead
 	Message string `json:"message"`
 	Version string `json:"version"`
+	// URL is the address the web app is served at, set once its route was admitted
+	URL string `json:"url,omitempty"`
+	// Storage is the observed state of the volume the web app keeps its data on
+	Storage *WebAppStorageStatus `json:"storage,omitempty"`
+	// Backup is the observed state of the scheduled backups
+	Backup *WebAppBackupStatus `json:"backup,omitempty"`
+	// Restore is the observed state of the restore of spec.restore, it is set once and kept afterwards
+	Restore *WebAppRestoreStatus `json:"restore,omitempty"`
+	// OAuthClient is the name of the OAuthClient managed for the web app, it is deleted with the WebApp
+	OAuthClient string `json:"oauthClient,omitempty"`
+	// Migrations records the last run of each migration that moved objects of the web app to the current layout,
+	// a recorded migration is not run again for the same target
+	Migrations []WebAppMigration `json:"migrations,omitempty"`
+	// Plan lists the changes a reconcile would make, it is computed instead of a reconcile while the WebApp
+	// has the integreatly.org/dry-run annotation
+	Plan               *WebAppPlan       `json:"plan,omitempty"`
+	Phase              WebAppPhase       `json:"phase,omitempty"`
+	ObservedGeneration int64             `json:"observedGeneration,omitempty"`
+	Conditions         []WebAppCondition `json:"conditions,omitempty"`
+}
+
+type WebAppPlan struct {
+	// ObservedGeneration is the generation of the WebApp the plan was computed for
+	ObservedGeneration int64           `json:"observedGeneration"`
+	Time               metav1.Time     `json:"time"`
+	Changes            []PlannedChange `json:"changes,omitempty"`
+	// Error is the error a reconcile would fail with, the changes after it are not planned
+	Error string `json:"error,omitempty"`
+}
+
+type PlanAction string
+
+const (
+	PlanActionCreate PlanAction = "Create"
+	PlanActionPatch  PlanAction = "Patch"
+	PlanActionUpdate PlanAction = "Update"
+	PlanActionDelete PlanAction = "Delete"
+)
+
+type PlannedChange struct {
+	Action     PlanAction `json:"action"`
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Name       string     `json:"name"`
+	// Diff is the JSON patch that would be applied to the live object, or the object that would be created.
+	// Secrets and the last applied configuration are left out.
+	Diff string `json:"diff,omitempty"`
+}
+
+type WebAppMigration struct {
+	// Version orders the migrations, they run from the lowest to the highest version
+	Version int32  `json:"version"`
+	Name    string `json:"name"`
+	// Message describes what was migrated, empty when the migration found nothing to migrate
+	Message string `json:"message,omitempty"`
+	// Target is the state the migration moved the web app to, e.g. the image version. A migration only runs
+	// again once its target changed.
+	Target string      `json:"target,omitempty"`
+	Time   metav1.Time `json:"time"`
+}
+
+type WebAppStorageStatus struct {
+	// ClaimName of the volume claim, empty when the data is kept in an emptyDir
+	ClaimName string `json:"claimName,omitempty"`
+	EmptyDir  bool   `json:"emptyDir,omitempty"`
+	// Phase of the volume claim
+	Phase corev1.PersistentVolumeClaimPhase `json:"phase,omitempty"`
+	// Requested is the size requested by the claim, Capacity the size of the bound volume
+	Requested *resource.Quantity `json:"requested,omitempty"`
+	Capacity  *resource.Quantity `json:"capacity,omitempty"`
+	// Message explains why the claim does not have the desired size
+	Message string `json:"message,omitempty"`
+}
+
+type WebAppBackupStatus struct {
+	// LastScheduleTime is when the last backup was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is when the last successful backup completed
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Active is the number of running backups
+	Active int `json:"active,omitempty"`
+	// Message explains why the last backup failed
+	Message string `json:"message,omitempty"`
+}
+
+type WebAppRestoreStatus struct {
+	// Job restoring the volume claim of the web app, empty when the data is restored by an init container
+	Job string `json:"job,omitempty"`
+	// CompletionTime is when the restore completed or was skipped, the restore does not run again afterwards
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message explains why the restore failed or was skipped
+	Message string `json:"message,omitempty"`
+}
+
+type WebAppPhase string
+
+const (
+	PhaseProvisioning WebAppPhase = "Provisioning"
+	PhaseReconciling  WebAppPhase = "Reconciling"
+	PhaseReady        WebAppPhase = "Ready"
+	PhaseDegraded     WebAppPhase = "Degraded"
+	PhaseDeleting     WebAppPhase = "Deleting"
+)
+
+type WebAppConditionType string
+
+const (
+	TemplateProcessed   WebAppConditionType = "TemplateProcessed"
+	ObjectsProvisioned  WebAppConditionType = "ObjectsProvisioned"
+	DeploymentAvailable WebAppConditionType = "DeploymentAvailable"
+	RouteAdmitted       WebAppConditionType = "RouteAdmitted"
+	// Ready is true when the latest rollout is available, the service has endpoints and the route was admitted
+	Ready WebAppConditionType = "Ready"
+	// OAuthClientReady is true when the OAuthClient of the web app redirects to the host of its route
+	OAuthClientReady WebAppConditionType = "OAuthClientReady"
+	// Paused is true while the objects of the web app are left as they are because of spec.paused
+	Paused WebAppConditionType = "Paused"
+)
+
+type WebAppCondition struct {
+	Type               WebAppConditionType    `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	ObservedGeneration int64                  `json:"observedGeneration,omitempty"`
 }
 
 type WebAppTemplate struct {
-	Path       string            `json:"path"`
-	Parameters map[string]string `json:"parameters"`
+	// Path of a template file in the operator image, used when none of the other sources is set
+	Path string `json:"path,omitempty"`
+	// ConfigMap references a template stored in a ConfigMap in the namespace of the WebApp
+	ConfigMap *TemplateConfigMapSource `json:"configMap,omitempty"`
+	// Inline is a template object embedded in the WebApp
+	Inline *runtime.RawExtension `json:"inline,omitempty"`
+	// Remote is a template fetched over HTTP(S) and verified against its checksum
+	Remote     *TemplateRemoteSource `json:"remote,omitempty"`
+	Parameters map[string]string     `json:"parameters"`
+	// ParametersFrom sets template parameters from keys of Secrets and ConfigMaps in the namespace of the WebApp
+	ParametersFrom []TemplateParameterSource `json:"parametersFrom,omitempty"`
+	// Processor selects where the template is processed, defaults to TemplateProcessorServer
+	Processor TemplateProcessor `json:"processor,omitempty"`
+}
+
+// DefaultTemplateConfigMapKey is the ConfigMap key holding the template when no key is set
+const DefaultTemplateConfigMapKey = "template.yml"
+
+type TemplateConfigMapSource struct {
+	Name string `json:"name"`
+	// Key holding the template in YAML or JSON, defaults to DefaultTemplateConfigMapKey
+	Key string `json:"key,omitempty"`
+}
+
+type TemplateRemoteSource struct {
+	URL string `json:"url"`
+	// Checksum of the template in the form sha256:<hex>
+	Checksum string `json:"checksum"`
 }
+
+type TemplateParameterSource struct {
+	// Name of the template parameter
+	Name      string               `json:"name"`
+	ValueFrom ParameterValueSource `json:"valueFrom"`
+}
+
+// ParameterValueSource selects the value of a parameter, exactly one of the references must be set.
+// Environment variables of the web app named after a parameter from a Secret reference the Secret
+// instead of holding its value.
+type ParameterValueSource struct {
+	SecretKeyRef    *corev1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+type TemplateProcessor string
+
+const (
+	// TemplateProcessorServer uses the template.openshift.io processedtemplates API
+	TemplateProcessorServer TemplateProcessor = "server"
+	// TemplateProcessorLocal processes the template in the operator
+	TemplateProcessorLocal TemplateProcessor = "local"
+)
@@ -0,0 +1,327 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciler brings the cluster to the state described by a WebApp. The WebApp is a copy owned by the
+// reconciler, a WebApp is never reconciled by two workers at the same time. A failed reconcile is always
+// retried with a per WebApp exponential backoff, the result is only used on success.
+type Reconciler interface {
+	Reconcile(ctx context.Context, cr *v1alpha1.WebApp) (reconcile.Result, error)
+}
+
+type ClientFactory func(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error)
+
+// Options configure the controller
+type Options struct {
+	// Namespaces are the namespaces the WebApps are watched in, metav1.NamespaceAll watches all namespaces
+	Namespaces []string
+	// Workers is the number of WebApps reconciled in parallel
+	Workers int
+	// ResyncPeriod is the interval in which every WebApp is reconciled without an event
+	ResyncPeriod time.Duration
+	// Owned are the kinds of the objects created for a WebApp, changes to them reconcile their WebApp
+	Owned []schema.GroupVersionKind
+}
+
+// referenced are the kinds of the objects a WebApp reads its settings from, changes to them reconcile the
+// WebApps referencing them
+var referenced = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "Secret"},
+	{Version: "v1", Kind: "ConfigMap"},
+}
+
+// referencesIndex indexes the WebApps by the objects they reference, as kind/name
+const referencesIndex = "references"
+
+// Controller reconciles the WebApps of a set of namespaces with a controller-runtime controller. The WebApps,
+// the objects they own and the objects they reference are watched by informers per namespace, which feed the
+// work queue of the controller, so all namespaces share the queue and the workers.
+type Controller struct {
+	reconciler Reconciler
+	opts       Options
+	manager    manager.Manager
+	// webApps are the WebApp informers by watched namespace
+	webApps map[string]cache.SharedIndexInformer
+	// watched are the informers of the owned and referenced objects
+	watched []cache.SharedIndexInformer
+	// ctx is the context of Run, it is handed to the reconciler
+	ctx context.Context
+}
+
+var _ reconcile.Reconciler = &Controller{}
+
+func New(reconciler Reconciler, cfg *rest.Config, factory ClientFactory, opts Options) (*Controller, error) {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if len(opts.Namespaces) == 0 {
+		return nil, fmt.Errorf("no namespace to watch")
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	mgr, err := manager.New(cfg, manager.Options{
+		Scheme: scheme,
+		// objects are read from the informers of the controller and written by the reconciler, the client of the
+		// manager is not used and needs no discovery of the cluster
+		MapperProvider: func(*rest.Config) (meta.RESTMapper, error) {
+			return meta.NewDefaultRESTMapper(nil), nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the manager: %v", err)
+	}
+	c := &Controller{
+		reconciler: reconciler,
+		opts:       opts,
+		manager:    mgr,
+		webApps:    map[string]cache.SharedIndexInformer{},
+		ctx:        context.TODO(),
+	}
+	ctrl, err := controller.New("webapp-controller", mgr, controller.Options{MaxConcurrentReconciles: opts.Workers, Reconciler: c})
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range opts.Namespaces {
+		if err := c.watch(ctrl, factory, ns); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// watch adds the informers of a namespace
+func (c *Controller) watch(ctrl controller.Controller, factory ClientFactory, ns string) error {
+	client, _, err := factory(v1alpha1.SchemeGroupVersion.String(), "WebApp", ns)
+	if err != nil {
+		return fmt.Errorf("failed to get the WebApp client for namespace %q: %v", ns, err)
+	}
+	webApps := cache.NewSharedIndexInformer(listWatch(client), &unstructured.Unstructured{}, c.opts.ResyncPeriod, cache.Indexers{referencesIndex: references})
+	err = ctrl.Watch(informerSource(webApps), &handler.EnqueueRequestForObject{}, predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// status updates written by the reconciler itself are not a reason to reconcile again,
+			// periodic resyncs deliver identical objects and are always enqueued
+			oldObj, newObj := e.ObjectOld.(*unstructured.Unstructured), e.ObjectNew.(*unstructured.Unstructured)
+			return oldObj.GetResourceVersion() == newObj.GetResourceVersion() || specChanged(oldObj, newObj)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	c.webApps[ns] = webApps
+
+	for _, gvk := range c.opts.Owned {
+		// owned objects are only watched, a resync of them would duplicate the resync of the WebApps
+		informer, err := c.informer(factory, gvk, ns)
+		if err != nil {
+			return err
+		}
+		if err := ctrl.Watch(informerSource(informer), &handler.EnqueueRequestForOwner{OwnerType: &v1alpha1.WebApp{}, IsController: true}); err != nil {
+			return err
+		}
+	}
+	for _, gvk := range referenced {
+		informer, err := c.informer(factory, gvk, ns)
+		if err != nil {
+			return err
+		}
+		if err := ctrl.Watch(informerSource(informer), &handler.EnqueueRequestsFromMapFunc{ToRequests: c.referencing(gvk.Kind)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) informer(factory ClientFactory, gvk schema.GroupVersionKind, ns string) (cache.SharedIndexInformer, error) {
+	client, _, err := factory(gvk.GroupVersion().String(), gvk.Kind, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s client for namespace %q: %v", gvk.Kind, ns, err)
+	}
+	informer := cache.NewSharedIndexInformer(listWatch(client), &unstructured.Unstructured{}, 0, cache.Indexers{})
+	c.watched = append(c.watched, informer)
+	return informer, nil
+}
+
+func listWatch(client dynamic.ResourceInterface) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.Watch(options)
+		},
+	}
+}
+
+// references is the index function of the objects a WebApp references
+func references(obj interface{}) ([]string, error) {
+	cr, err := decode(obj.(*unstructured.Unstructured))
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, name := range cr.ReferencedSecrets() {
+		keys = append(keys, "Secret/"+name)
+	}
+	for _, name := range cr.ReferencedConfigMaps() {
+		keys = append(keys, "ConfigMap/"+name)
+	}
+	return keys, nil
+}
+
+// referencing maps a referenced object to the WebApps of its namespace referencing it
+func (c *Controller) referencing(kind string) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		informer, ok := c.webAppInformer(o.Meta.GetNamespace())
+		if !ok {
+			return nil
+		}
+		objs, err := informer.GetIndexer().ByIndex(referencesIndex, kind+"/"+o.Meta.GetName())
+		if err != nil {
+			logrus.Errorf("failed to look up the WebApps referencing %s %s/%s: %v", kind, o.Meta.GetNamespace(), o.Meta.GetName(), err)
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, obj := range objs {
+			u := obj.(*unstructured.Unstructured)
+			// the informer of all namespaces indexes the WebApps of every namespace
+			if u.GetNamespace() != o.Meta.GetNamespace() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: u.GetNamespace(), Name: u.GetName()}})
+		}
+		return requests
+	}
+}
+
+func (c *Controller) webAppInformer(ns string) (cache.SharedIndexInformer, bool) {
+	informer, ok := c.webApps[ns]
+	if !ok {
+		informer, ok = c.webApps[metav1.NamespaceAll]
+	}
+	return informer, ok
+}
+
+// Run reconciles WebApps until the context is done
+func (c *Controller) Run(ctx context.Context) error {
+	c.ctx = ctx
+	synced := []cache.InformerSynced{}
+	for _, informer := range c.webApps {
+		go informer.Run(ctx.Done())
+		synced = append(synced, informer.HasSynced)
+	}
+	for _, informer := range c.watched {
+		go informer.Run(ctx.Done())
+		synced = append(synced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("timed out waiting for the caches to sync")
+	}
+
+	logrus.Infof("Reconciling WebApps in %s with %d workers", describeNamespaces(c.opts.Namespaces), c.opts.Workers)
+	return c.manager.Start(ctx.Done())
+}
+
+// Reconcile implements reconcile.Reconciler, it reconciles the WebApp of the request as it is in the informer
+// of its namespace
+func (c *Controller) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	informer, ok := c.webAppInformer(req.Namespace)
+	if !ok {
+		// owned objects are only watched in the namespaces of the WebApps, so this is never retried
+		logrus.Warnf("ignoring WebApp %s of a namespace that is not watched", req)
+		return reconcile.Result{}, nil
+	}
+	obj, exists, err := informer.GetIndexer().GetByKey(req.String())
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !exists {
+		// the objects of a deleted WebApp are garbage collected, the finalizer took care of the rest
+		return reconcile.Result{}, nil
+	}
+
+	cr, err := decode(obj.(*unstructured.Unstructured))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	result, err := c.reconciler.Reconcile(c.ctx, cr)
+	if err != nil {
+		logrus.Errorf("failed to reconcile WebApp %s: %v", req, err)
+	}
+	return result, err
+}
+
+func decode(u *unstructured.Unstructured) (*v1alpha1.WebApp, error) {
+	cr := &v1alpha1.WebApp{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), cr); err != nil {
+		return nil, fmt.Errorf("failed to decode WebApp %s/%s: %v", u.GetNamespace(), u.GetName(), err)
+	}
+	return cr, nil
+}
+
+// specChanged reports whether anything but the status and the bookkeeping metadata of a WebApp changed
+func specChanged(oldObj, newObj *unstructured.Unstructured) bool {
+	return !reflect.DeepEqual(withoutStatus(oldObj), withoutStatus(newObj))
+}
+
+func withoutStatus(u *unstructured.Unstructured) map[string]interface{} {
+	u = u.DeepCopy()
+	unstructured.RemoveNestedField(u.Object, "status")
+	unstructured.RemoveNestedField(u.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(u.Object, "metadata", "generation")
+	return u.Object
+}
+
+func describeNamespaces(namespaces []string) string {
+	for _, ns := range namespaces {
+		if ns == metav1.NamespaceAll {
+			return "all namespaces"
+		}
+	}
+	return "namespaces " + strings.Join(namespaces, ", ")
+}
+
+// ParseNamespaces parses a comma separated list of namespaces, an empty list means all namespaces
+func ParseNamespaces(value string) []string {
+	namespaces := []string{}
+	seen := map[string]bool{}
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	if len(namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return namespaces
+}
@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// config is the config of the manager, the controller never connects to it
+var config = &rest.Config{Host: "http://127.0.0.1:1"}
+
+// fakeWatchers are the watchers of a namespace by kind
+type fakeWatchers map[string]*watch.RaceFreeFakeWatcher
+
+func (w fakeWatchers) factory(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error) {
+	watcher := watch.NewRaceFreeFake()
+	w[kind] = watcher
+	return &fakeWatchClient{watcher: watcher}, strings.ToLower(kind) + "s", nil
+}
+
+// fakeWatchClient serves an empty list and the events sent to its watcher
+type fakeWatchClient struct {
+	dynamic.ResourceInterface
+	watcher *watch.RaceFreeFakeWatcher
+}
+
+func (f *fakeWatchClient) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return &unstructured.UnstructuredList{Object: map[string]interface{}{}}, nil
+}
+
+func (f *fakeWatchClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return f.watcher, nil
+}
+
+type fakeReconciler struct {
+	mu         sync.Mutex
+	reconciled []*v1alpha1.WebApp
+	results    []error
+	requeue    time.Duration
+}
+
+func (r *fakeReconciler) Reconcile(ctx context.Context, cr *v1alpha1.WebApp) (reconcile.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reconciled = append(r.reconciled, cr)
+	if len(r.results) > 0 {
+		err := r.results[0]
+		r.results = r.results[1:]
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: r.requeue}, nil
+}
+
+func (r *fakeReconciler) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.reconciled)
+}
+
+func webApp(resourceVersion, appLabel, phase string) *unstructured.Unstructured {
+	return webAppIn("webapp", resourceVersion, appLabel, phase)
+}
+
+func webAppIn(namespace, resourceVersion, appLabel, phase string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "integreatly.org/v1alpha1",
+		"kind":       "WebApp",
+		"metadata": map[string]interface{}{
+			"name":            "tutorial-web-app",
+			"namespace":       namespace,
+			"resourceVersion": resourceVersion,
+		},
+		"spec":   map[string]interface{}{"app_label": appLabel},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	return u
+}
+
+func ownedService(owner string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":      "tutorial-web-app",
+			"namespace": "webapp",
+		},
+	}}
+	controller := true
+	u.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "integreatly.org/v1alpha1",
+		Kind:       "WebApp",
+		Name:       owner,
+		Controller: &controller,
+	}})
+	return u
+}
+
+func referencedSecret(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "webapp",
+		},
+	}}
+}
+
+func waitFor(t *testing.T, r *fakeReconciler, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for r.count() < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d reconciles, got %d", count, r.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestController(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Reconciler *fakeReconciler
+		// Events sends watch events, reconciled waits until the WebApp was reconciled count times
+		Events   func(w fakeWatchers, reconciled func(count int))
+		Expected int
+	}{
+		{
+			Name:       "Reconcile an added WebApp",
+			Reconciler: &fakeReconciler{},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+			},
+			Expected: 1,
+		},
+		{
+			Name:       "Ignore status updates",
+			Reconciler: &fakeReconciler{},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+				w["WebApp"].Modify(webApp("2", "tutorial-web-app", "Ready"))
+			},
+			Expected: 1,
+		},
+		{
+			Name:       "Reconcile spec updates",
+			Reconciler: &fakeReconciler{},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+				reconciled(1)
+				w["WebApp"].Modify(webApp("2", "other-label", ""))
+			},
+			Expected: 2,
+		},
+		{
+			Name:       "Reconcile the owner of a changed object",
+			Reconciler: &fakeReconciler{},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+				reconciled(1)
+				w["Service"].Add(ownedService("tutorial-web-app"))
+				w["Service"].Add(ownedService("not-a-webapp"))
+			},
+			Expected: 2,
+		},
+		{
+			Name:       "Reconcile the WebApps referencing a changed object",
+			Reconciler: &fakeReconciler{},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				cr := webApp("1", "tutorial-web-app", "")
+				unstructured.SetNestedField(cr.Object, "tutorial-web-app-tls", "spec", "route", "tls", "certificateSecret")
+				w["WebApp"].Add(cr)
+				reconciled(1)
+				w["Secret"].Add(referencedSecret("tutorial-web-app-tls"))
+				w["Secret"].Add(referencedSecret("not-referenced"))
+			},
+			Expected: 2,
+		},
+		{
+			Name:       "Retry failed reconciles",
+			Reconciler: &fakeReconciler{results: []error{errors.New("failed"), errors.New("failed")}},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+			},
+			Expected: 3,
+		},
+		{
+			Name:       "Requeue after the requested duration",
+			Reconciler: &fakeReconciler{requeue: 50 * time.Millisecond},
+			Events: func(w fakeWatchers, reconciled func(count int)) {
+				w["WebApp"].Add(webApp("1", "tutorial-web-app", ""))
+			},
+			Expected: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			watchers := fakeWatchers{}
+			c, err := New(tc.Reconciler, config, watchers.factory, Options{
+				Namespaces: []string{"webapp"},
+				Owned:      []schema.GroupVersionKind{{Version: "v1", Kind: "Service"}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go c.Run(ctx)
+
+			tc.Events(watchers, func(count int) { waitFor(t, tc.Reconciler, count) })
+			waitFor(t, tc.Reconciler, tc.Expected)
+			if tc.Reconciler.requeue == 0 {
+				// no further reconcile is expected once the events were handled
+				time.Sleep(200 * time.Millisecond)
+				if count := tc.Reconciler.count(); count != tc.Expected {
+					t.Fatalf("expected %d reconciles, got %d", tc.Expected, count)
+				}
+			}
+			tc.Reconciler.mu.Lock()
+			defer tc.Reconciler.mu.Unlock()
+			if cr := tc.Reconciler.reconciled[0]; cr.Name != "tutorial-web-app" || cr.Namespace != "webapp" {
+				t.Fatalf("expected WebApp webapp/tutorial-web-app, got %s/%s", cr.Namespace, cr.Name)
+			}
+		})
+	}
+}
+
+func TestController_Namespaces(t *testing.T) {
+	watchers := map[string]*watch.RaceFreeFakeWatcher{}
+	factory := func(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error) {
+		w := watch.NewRaceFreeFake()
+		watchers[kind+"/"+namespace] = w
+		return &fakeWatchClient{watcher: w}, "webapps", nil
+	}
+	reconciler := &fakeReconciler{}
+	c, err := New(reconciler, config, factory, Options{Namespaces: []string{"team-a", "team-b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(watchers) != 2*(1+len(referenced)) {
+		t.Fatalf("expected a watch per namespace, got %v", watchers)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	watchers["WebApp/team-a"].Add(webAppIn("team-a", "1", "tutorial-web-app", ""))
+	watchers["WebApp/team-b"].Add(webAppIn("team-b", "2", "tutorial-web-app", ""))
+	waitFor(t, reconciler, 2)
+
+	reconciler.mu.Lock()
+	defer reconciler.mu.Unlock()
+	namespaces := map[string]bool{}
+	for _, cr := range reconciler.reconciled {
+		namespaces[cr.Namespace] = true
+	}
+	if !namespaces["team-a"] || !namespaces["team-b"] {
+		t.Fatalf("expected the WebApps of both namespaces to be reconciled, got %v", namespaces)
+	}
+}
+
+func TestParseNamespaces(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected []string
+	}{
+		{Value: "", Expected: []string{metav1.NamespaceAll}},
+		{Value: " , ", Expected: []string{metav1.NamespaceAll}},
+		{Value: "webapp", Expected: []string{"webapp"}},
+		{Value: "team-a, team-b,team-a", Expected: []string{"team-a", "team-b"}},
+	}
+
+	for _, tc := range cases {
+		if namespaces := ParseNamespaces(tc.Value); !reflect.DeepEqual(namespaces, tc.Expected) {
+			t.Fatalf("expected %q to be parsed to %v, got %v", tc.Value, tc.Expected, namespaces)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// informerSource turns the events of an informer of unstructured objects into controller-runtime events. The
+// informers are per namespace, so a single controller can watch a list of namespaces.
+func informerSource(informer cache.SharedIndexInformer) source.Source {
+	return source.Func(func(h handler.EventHandler, queue workqueue.RateLimitingInterface, predicates ...predicate.Predicate) error {
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				u, ok := obj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				e := event.CreateEvent{Meta: u, Object: u}
+				for _, p := range predicates {
+					if !p.Create(e) {
+						return
+					}
+				}
+				h.Create(e, queue)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldU, ok := oldObj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				newU, ok := newObj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				e := event.UpdateEvent{MetaOld: oldU, ObjectOld: oldU, MetaNew: newU, ObjectNew: newU}
+				for _, p := range predicates {
+					if !p.Update(e) {
+						return
+					}
+				}
+				h.Update(e, queue)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				u, ok := obj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				e := event.DeleteEvent{Meta: u, Object: u}
+				for _, p := range predicates {
+					if !p.Delete(e) {
+						return
+					}
+				}
+				h.Delete(e, queue)
+			},
+		})
+		return nil
+	})
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// lastAppliedAnnotation holds the desired state of an object as it was last applied by the operator,
+// it is the original side of the three-way merge so fields removed from the template are removed too
+const lastAppliedAnnotation = "integreatly.org/last-applied-configuration"
+
+// OwnedKinds are the kinds of the objects provisioned for a WebApp on the platform, changes to them
+// are watched so drift is corrected without waiting for the next resync
+func OwnedKinds(platform openshift.Platform) []schema.GroupVersionKind {
+	kinds := []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Service"},
+		{Version: "v1", Kind: "PersistentVolumeClaim"},
+		{Group: "batch", Version: "v1beta1", Kind: "CronJob"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+	}
+	if platform == openshift.PlatformKubernetes {
+		return append(kinds,
+			schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+		)
+	}
+	return append(kinds,
+		schema.GroupVersionKind{Group: "apps.openshift.io", Version: "v1", Kind: "DeploymentConfig"},
+		schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"},
+	)
+}
+
+// applied describes what applyObject did to an object
+type applied struct {
+	name    string
+	created bool
+	patched bool
+	// env holds the names of the container environment variables changed by the patch
+	env []string
+}
+
+func (a applied) changed() bool {
+	return a.created || a.patched
+}
+
+// applyObject creates the object when it is missing, otherwise it patches the live object with a
+// three-way strategic merge of the last applied, the desired and the live state. Objects are owned
+// by the WebApp so they are garbage collected with it. The patch also removes the legacyEnv environment
+// variables of the first container, which are not in the last applied state.
+func (h *AppHandler) applyObject(o runtime.Object, cr *v1alpha1.WebApp, legacyEnv []string) (applied, error) {
+	gvk := o.GetObjectKind().GroupVersionKind()
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	gvkStr := gvk.String()
+
+	resourceClient, _, err := h.dynamicResourceClientFactory(apiVersion, kind, cr.Namespace)
+	if err != nil {
+		return applied{}, fmt.Errorf("failed to get resource client: %v", err)
+	}
+
+	desired, err := desiredState(o)
+	if err != nil {
+		return applied{}, fmt.Errorf("%v failed to turn runtime object %s into unstructured object during provision", err, gvkStr)
+	}
+	desired.SetNamespace(cr.Namespace)
+	desired.SetOwnerReferences([]metav1.OwnerReference{cr.OwnerReference()})
+	lastApplied, err := desired.MarshalJSON()
+	if err != nil {
+		return applied{}, err
+	}
+	annotations := desired.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastAppliedAnnotation] = string(lastApplied)
+	desired.SetAnnotations(annotations)
+
+	result := applied{name: desired.GetName()}
+	live, err := resourceClient.Get(desired.GetName(), metav1.GetOptions{})
+	if errors2.IsNotFound(err) {
+		if _, err := resourceClient.Create(desired); err != nil {
+			return applied{}, fmt.Errorf("%v failed to create object during provision with kind %s", err, gvkStr)
+		}
+		result.created = true
+		return result, nil
+	}
+	if err != nil {
+		return applied{}, fmt.Errorf("%v failed to get %s %s", err, gvkStr, desired.GetName())
+	}
+
+	patch, err := threeWayPatch(o, desired, live, legacyEnv)
+	if err != nil {
+		return applied{}, fmt.Errorf("%v failed to compute patch for %s %s", err, gvkStr, desired.GetName())
+	}
+	if patch == nil {
+		return result, nil
+	}
+
+	if _, err := resourceClient.Patch(desired.GetName(), types.StrategicMergePatchType, patch); err != nil {
+		return applied{}, fmt.Errorf("%v failed to patch %s %s", err, gvkStr, desired.GetName())
+	}
+	result.patched = true
+	result.env = envChanges(live.GetAnnotations()[lastAppliedAnnotation], desired)
+	return result, nil
+}
+
+// threeWayPatch returns the strategic merge patch that moves the live object to the desired state,
+// or nil when the live object is already in sync
+func threeWayPatch(o runtime.Object, desired, live *unstructured.Unstructured, legacyEnv []string) ([]byte, error) {
+	modified, err := desired.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	current, err := live.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	original, err := withLegacyEnv([]byte(live.GetAnnotations()[lastAppliedAnnotation]), live, legacyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(o)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+	if err != nil {
+		return nil, err
+	}
+	if string(patch) == "{}" {
+		return nil, nil
+	}
+	return patch, nil
+}
+
+// withLegacyEnv adds the legacyEnv environment variables of the first live container to the last applied
+// state, the three-way merge removes the variables of the last applied state the desired state does not set
+func withLegacyEnv(original []byte, live *unstructured.Unstructured, legacyEnv []string) ([]byte, error) {
+	if len(legacyEnv) == 0 {
+		return original, nil
+	}
+	liveContainers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+	if len(liveContainers) == 0 {
+		return original, nil
+	}
+	container, _, _ := unstructured.NestedString(liveContainers[0].(map[string]interface{}), "name")
+	remove := map[string]bool{}
+	for _, name := range legacyEnv {
+		remove[name] = true
+	}
+	var env []interface{}
+	liveEnv, _, _ := unstructured.NestedSlice(liveContainers[0].(map[string]interface{}), "env")
+	for _, e := range liveEnv {
+		if name, _, _ := unstructured.NestedString(e.(map[string]interface{}), "name"); remove[name] {
+			env = append(env, e)
+		}
+	}
+
+	state := map[string]interface{}{}
+	if len(original) > 0 {
+		if err := json.Unmarshal(original, &state); err != nil {
+			return nil, err
+		}
+	}
+	containers, _, _ := unstructured.NestedSlice(state, "spec", "template", "spec", "containers")
+	found := false
+	for _, c := range containers {
+		if name, _, _ := unstructured.NestedString(c.(map[string]interface{}), "name"); name == container {
+			applied, _, _ := unstructured.NestedSlice(c.(map[string]interface{}), "env")
+			c.(map[string]interface{})["env"] = append(applied, env...)
+			found = true
+		}
+	}
+	if !found {
+		containers = append(containers, map[string]interface{}{"name": container, "env": env})
+	}
+	if err := unstructured.SetNestedSlice(state, containers, "spec", "template", "spec", "containers"); err != nil {
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+// desiredState turns a typed object into its unstructured form without status and without unset
+// fields, typed objects serialize some of them as null or empty strings which would otherwise
+// reset values defaulted by the cluster on every reconcile
+func desiredState(o runtime.Object) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	delete(content, "status")
+	pruneUnset(reflect.ValueOf(o), content)
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// pruneUnset removes the struct fields of a typed object that hold their zero value and are serialized
+// anyway, because their json tag has no omitempty or they serialize themselves as null. Entries of maps
+// like labels or the data of a ConfigMap are set by the template and kept even when they are empty.
+func pruneUnset(v reflect.Value, content interface{}) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			pruneUnset(v.Elem(), content)
+		}
+	case reflect.Struct:
+		// structs with their own json form, like metav1.Time, are not serialized as an object
+		if fields, ok := content.(map[string]interface{}); ok {
+			pruneFields(v, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := content.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			pruneUnset(v.Index(i), items[i])
+		}
+	case reflect.Map:
+		entries, ok := content.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			if entry, ok := entries[key.String()]; ok {
+				pruneUnset(v.MapIndex(key), entry)
+			}
+		}
+	}
+}
+
+func pruneFields(v reflect.Value, fields map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name, omitEmpty := tag[0], false
+		for _, opt := range tag[1:] {
+			omitEmpty = omitEmpty || opt == "omitempty"
+		}
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			// inlined like the TypeMeta of an object
+			pruneUnset(v.Field(i), fields)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if value == nil || (!omitEmpty && reflect.DeepEqual(fv.Interface(), reflect.Zero(fv.Type()).Interface())) {
+			delete(fields, name)
+			continue
+		}
+		pruneUnset(fv, value)
+	}
+}
+
+// envChanges returns the names of the container environment variables that were added, changed or
+// removed between the last applied and the desired state of a workload. Objects that were created
+// before the last applied state was recorded have nothing to compare against.
+func envChanges(lastApplied string, desired *unstructured.Unstructured) []string {
+	if lastApplied == "" {
+		return nil
+	}
+	original := &unstructured.Unstructured{}
+	if err := original.UnmarshalJSON([]byte(lastApplied)); err != nil {
+		return nil
+	}
+	before, after := envVars(original), envVars(desired)
+
+	changes := []string{}
+	for name, value := range after {
+		if previous, ok := before[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes = append(changes, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, name)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// envVars indexes the environment variables of the pod template containers by name
+func envVars(o *unstructured.Unstructured) map[string]interface{} {
+	env := map[string]interface{}{}
+	containers, _, _ := unstructured.NestedSlice(o.Object, "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		vars, _, _ := unstructured.NestedSlice(container, "env")
+		for _, v := range vars {
+			if envVar, ok := v.(map[string]interface{}); ok {
+				env[fmt.Sprintf("%v", envVar["name"])] = v
+			}
+		}
+	}
+	return env
+}
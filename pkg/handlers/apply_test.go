@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+)
+
+// fakeCluster is an in-memory store of the objects handled through the dynamic client
+type fakeCluster struct {
+	objects   map[string]*unstructured.Unstructured
+	creates   int
+	patches   int
+	createErr error
+	patchErr  error
+	// unavailable keeps created workloads from being rolled out and routes from being admitted
+	unavailable bool
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{objects: map[string]*unstructured.Unstructured{}}
+}
+
+func (c *fakeCluster) clientFactory(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error) {
+	return &fakeResourceClient{cluster: c, kind: kind}, "", nil
+}
+
+func (c *fakeCluster) get(kind, name string) *unstructured.Unstructured {
+	return c.objects[kind+"/"+name]
+}
+
+type fakeResourceClient struct {
+	cluster *fakeCluster
+	kind    string
+}
+
+var _ dynamic.ResourceInterface = &fakeResourceClient{}
+
+func (f *fakeResourceClient) key(name string) string {
+	return f.kind + "/" + name
+}
+
+func (f *fakeResourceClient) notFound(name string) error {
+	return errors2.NewNotFound(schema.GroupResource{Resource: f.kind}, name)
+}
+
+func (f *fakeResourceClient) Create(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	if f.cluster.createErr != nil {
+		return nil, f.cluster.createErr
+	}
+	if _, ok := f.cluster.objects[f.key(obj.GetName())]; ok {
+		return nil, errors2.NewAlreadyExists(schema.GroupResource{Resource: f.kind}, obj.GetName())
+	}
+	f.cluster.creates++
+	f.cluster.objects[f.key(obj.GetName())] = obj.DeepCopy()
+	if !f.cluster.unavailable {
+		f.cluster.rollOut(f.cluster.objects[f.key(obj.GetName())])
+	}
+	return obj, nil
+}
+
+// rollOut sets the status the cluster reports once a workload was rolled out or a route was admitted
+func (c *fakeCluster) rollOut(obj *unstructured.Unstructured) {
+	// desired objects are decoded from JSON, their numbers are float64
+	replicas := int64(1)
+	if value, ok, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas"); ok {
+		replicas = int64(value.(float64))
+	}
+	switch obj.GetKind() {
+	case "DeploymentConfig":
+		obj.Object["status"] = map[string]interface{}{
+			"latestVersion":     int64(1),
+			"replicas":          replicas,
+			"updatedReplicas":   replicas,
+			"availableReplicas": replicas,
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Progressing", "status": "True", "reason": dcRolloutComplete},
+			},
+		}
+	case "Deployment":
+		obj.Object["status"] = map[string]interface{}{
+			"replicas":          replicas,
+			"updatedReplicas":   replicas,
+			"availableReplicas": replicas,
+		}
+	case "Route":
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		if host == "" {
+			host = obj.GetName() + "-" + obj.GetNamespace() + ".apps.example.com"
+		}
+		obj.Object["status"] = map[string]interface{}{
+			"ingress": []interface{}{
+				map[string]interface{}{
+					"host":       host,
+					"routerName": "default",
+					"conditions": []interface{}{map[string]interface{}{"type": "Admitted", "status": "True"}},
+				},
+			},
+		}
+	case "Ingress":
+		obj.Object["status"] = map[string]interface{}{
+			"loadBalancer": map[string]interface{}{
+				"ingress": []interface{}{map[string]interface{}{"ip": "10.0.0.1"}},
+			},
+		}
+	}
+}
+
+func (f *fakeResourceClient) Update(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	f.cluster.objects[f.key(obj.GetName())] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (f *fakeResourceClient) UpdateStatus(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return f.Update(obj)
+}
+
+func (f *fakeResourceClient) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	if _, ok := f.cluster.objects[f.key(name)]; !ok {
+		return f.notFound(name)
+	}
+	delete(f.cluster.objects, f.key(name))
+	return nil
+}
+
+func (f *fakeResourceClient) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeResourceClient) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	obj, ok := f.cluster.objects[f.key(name)]
+	if !ok {
+		return nil, f.notFound(name)
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (f *fakeResourceClient) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	for key, obj := range f.cluster.objects {
+		if strings.HasPrefix(key, f.kind+"/") && selector.Matches(labels.Set(obj.GetLabels())) {
+			list.Items = append(list.Items, *obj.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeResourceClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeResourceClient) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*unstructured.Unstructured, error) {
+	if pt != types.StrategicMergePatchType {
+		return nil, fmt.Errorf("unexpected patch type %s", pt)
+	}
+	if f.cluster.patchErr != nil {
+		return nil, f.cluster.patchErr
+	}
+	live, ok := f.cluster.objects[f.key(name)]
+	if !ok {
+		return nil, f.notFound(name)
+	}
+	typed, err := k8sutil.RuntimeObjectFromUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	current, err := live.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(current, data, typed)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(patched); err != nil {
+		return nil, err
+	}
+	f.cluster.patches++
+	f.cluster.objects[f.key(name)] = obj
+	return obj, nil
+}
+
+func testService(port int32, selector map[string]string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "tutorial-web-app",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports:    []corev1.ServicePort{{Name: "http", Port: port}},
+			Selector: selector,
+		},
+	}
+}
+
+func TestApplyObject(t *testing.T) {
+	cr := &v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Namespace: "test"}}
+
+	cases := []struct {
+		Name    string
+		Initial *corev1.Service
+		// Mutate changes the live object between the initial and the second apply
+		Mutate          func(*unstructured.Unstructured)
+		Desired         *corev1.Service
+		ExpectedChanged bool
+		Verify          func(*unstructured.Unstructured, *testing.T)
+	}{
+		{
+			Name:            "Object in sync is not patched",
+			Initial:         testService(80, map[string]string{"app": "tutorial-web-app"}),
+			Desired:         testService(80, map[string]string{"app": "tutorial-web-app"}),
+			ExpectedChanged: false,
+		},
+		{
+			Name:    "Fields defaulted by the cluster are kept",
+			Initial: testService(80, map[string]string{"app": "tutorial-web-app"}),
+			Mutate: func(live *unstructured.Unstructured) {
+				unstructured.SetNestedField(live.Object, "172.30.0.1", "spec", "clusterIP")
+			},
+			Desired:         testService(80, map[string]string{"app": "tutorial-web-app"}),
+			ExpectedChanged: false,
+			Verify: func(live *unstructured.Unstructured, t *testing.T) {
+				if ip, _, _ := unstructured.NestedString(live.Object, "spec", "clusterIP"); ip != "172.30.0.1" {
+					t.Fatalf("expected clusterIP to be kept, got %q", ip)
+				}
+			},
+		},
+		{
+			Name:    "Drifted field is restored",
+			Initial: testService(80, map[string]string{"app": "tutorial-web-app"}),
+			Mutate: func(live *unstructured.Unstructured) {
+				unstructured.SetNestedStringMap(live.Object, map[string]string{"app": "other"}, "spec", "selector")
+			},
+			Desired:         testService(80, map[string]string{"app": "tutorial-web-app"}),
+			ExpectedChanged: true,
+			Verify: func(live *unstructured.Unstructured, t *testing.T) {
+				if sel, _, _ := unstructured.NestedStringMap(live.Object, "spec", "selector"); sel["app"] != "tutorial-web-app" {
+					t.Fatalf("expected selector to be restored, got %v", sel)
+				}
+			},
+		},
+		{
+			Name:            "Field removed from the template is removed",
+			Initial:         testService(80, map[string]string{"app": "tutorial-web-app", "tier": "frontend"}),
+			Desired:         testService(80, map[string]string{"app": "tutorial-web-app"}),
+			ExpectedChanged: true,
+			Verify: func(live *unstructured.Unstructured, t *testing.T) {
+				sel, _, _ := unstructured.NestedStringMap(live.Object, "spec", "selector")
+				if _, ok := sel["tier"]; ok {
+					t.Fatalf("expected selector tier to be removed, got %v", sel)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cluster := newFakeCluster()
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, &openshift.OSClientInterfaceMock{}, cluster.clientFactory, &SdkCruderMock{}, openshift.PlatformOpenShift, "", "")
+
+			result, err := wh.applyObject(tc.Initial, cr, nil)
+			if err != nil {
+				t.Fatalf("unexpected error creating the object: %v", err)
+			}
+			if !result.created || cluster.creates != 1 {
+				t.Fatalf("expected the object to be created")
+			}
+
+			live := cluster.get("Service", "tutorial-web-app")
+			if live.GetNamespace() != cr.Namespace {
+				t.Fatalf("expected namespace %s, got %s", cr.Namespace, live.GetNamespace())
+			}
+			lastApplied := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(live.GetAnnotations()[lastAppliedAnnotation]), &lastApplied); err != nil {
+				t.Fatalf("expected valid %s annotation: %v", lastAppliedAnnotation, err)
+			}
+			if _, ok := lastApplied["status"]; ok {
+				t.Fatalf("expected status to be left out of the last applied configuration")
+			}
+			if tc.Mutate != nil {
+				tc.Mutate(live)
+			}
+
+			result, err = wh.applyObject(tc.Desired, cr, nil)
+			if err != nil {
+				t.Fatalf("unexpected error applying the object: %v", err)
+			}
+			if result.changed() != tc.ExpectedChanged {
+				t.Fatalf("expected changed to be %v, got %v", tc.ExpectedChanged, result.changed())
+			}
+			if tc.Verify != nil {
+				tc.Verify(cluster.get("Service", "tutorial-web-app"), t)
+			}
+		})
+	}
+}
+
+func TestDesiredState(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Annotations: map[string]string{"empty": ""}},
+		Data:       map[string]string{"EMPTY": "", "SET": "value"},
+	}
+	desired, err := desiredState(cm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := desired.Object["metadata"].(map[string]interface{})["creationTimestamp"]; ok {
+		t.Fatalf("expected the unset creationTimestamp to be pruned, got %v", desired.Object["metadata"])
+	}
+	if data, _, _ := unstructured.NestedStringMap(desired.Object, "data"); len(data) != 2 {
+		t.Fatalf("expected the empty value set in the data to be kept, got %v", data)
+	}
+	if annotations := desired.GetAnnotations(); len(annotations) != 1 {
+		t.Fatalf("expected the empty annotation to be kept, got %v", annotations)
+	}
+
+	route := &routev1.Route{
+		TypeMeta:   metav1.TypeMeta{Kind: "Route", APIVersion: "route.openshift.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"},
+		Spec:       routev1.RouteSpec{To: routev1.RouteTargetReference{Kind: "Service", Name: "tutorial-web-app"}},
+	}
+	desired, err = desiredState(route)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := unstructured.NestedFieldNoCopy(desired.Object, "spec", "host"); ok {
+		t.Fatalf("expected the unset host to be pruned so the cluster can generate it, got %v", desired.Object["spec"])
+	}
+	if name, _, _ := unstructured.NestedString(desired.Object, "spec", "to", "name"); name != "tutorial-web-app" {
+		t.Fatalf("expected spec.to to be kept, got %v", desired.Object["spec"])
+	}
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// BackupImage runs the backups and restores of a WebApp that does not set an image
+	BackupImage = "docker.io/minio/mc:latest"
+
+	backupName  = "tutorial-web-app-backup"
+	restoreName = "tutorial-web-app-restore"
+	// backupLabel is set on the backup jobs of a WebApp to the name of the WebApp
+	backupLabel = "integreatly.org/webapp-backup"
+
+	dataDir     = "/data"
+	archivesDir = "/archives"
+)
+
+// backupScript archives the data directory to an object store when S3_PATH is set and to the ARCHIVES
+// directory otherwise. The archive names sort by the time of the backup.
+const backupScript = `set -e
+archive=walkthroughs-$(date -u +%Y%m%dT%H%M%SZ).tar.gz
+tar -czf "/tmp/$archive" -C "$DATA" .
+if [ -n "$S3_PATH" ]; then
+  mc alias set target "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY" > /dev/null
+  mc cp "/tmp/$archive" "target/$S3_PATH/$archive"
+else
+  mkdir -p "$ARCHIVES"
+  cp "/tmp/$archive" "$ARCHIVES/.$archive"
+  mv "$ARCHIVES/.$archive" "$ARCHIVES/$archive"
+fi
+echo "backed up $DATA to $archive"
+`
+
+// restoreScript extracts ARCHIVE, or the latest archive, into an empty data directory. A data directory
+// that is not empty was restored before or holds the progress of the users, it is left alone. A source
+// without archives has nothing to restore, the web app starts with an empty data directory.
+const restoreScript = `set -e
+if [ -n "$(ls -A "$DATA" | grep -v '^lost+found$')" ]; then
+  echo "$DATA is not empty, skipping the restore"
+  exit 0
+fi
+if [ -n "$S3_PATH" ]; then
+  mc alias set source "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY" > /dev/null
+  list() { mc ls "source/$S3_PATH/" | awk '{print $NF}'; }
+  fetch() { mc cp "source/$S3_PATH/$1" /tmp/archive.tar.gz; }
+else
+  list() { ls "$ARCHIVES"; }
+  fetch() { cp "$ARCHIVES/$1" /tmp/archive.tar.gz; }
+fi
+archive="$ARCHIVE"
+if [ -z "$archive" ]; then
+  archive=$(list | grep '^walkthroughs-.*\.tar\.gz$' | sort | tail -n 1)
+fi
+if [ -z "$archive" ]; then
+  echo "no archive to restore"
+  exit 0
+fi
+fetch "$archive"
+tar -xzf /tmp/archive.tar.gz -C "$DATA"
+echo "restored $archive to $DATA"
+`
+
+// reconcileBackup restores the data of the web app and returns the objects to provision with the backup
+// CronJob. The CronJob of a WebApp that no longer has backups is deleted with its jobs.
+func (h *AppHandler) reconcileBackup(cr *v1alpha1.WebApp, wl *workload, objects []runtime.Object) ([]runtime.Object, error) {
+	volume, mount, ok := wl.dataVolume()
+	if !ok && (cr.Spec.Backup != nil || cr.Spec.Restore != nil) {
+		return nil, fmt.Errorf("%s %s does not mount a data volume to back up or restore", wl.kind, wl.name)
+	}
+
+	if cr.Spec.Restore != nil {
+		var err error
+		objects, err = h.reconcileRestore(cr, wl, volume, mount, objects)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cr.Spec.Backup == nil {
+		// the backup status is only cleared once the CronJob was deleted
+		if cr.Status.Backup != nil {
+			return objects, h.deleteBackup(cr)
+		}
+		return objects, nil
+	}
+	if volume.PersistentVolumeClaim == nil {
+		return nil, fmt.Errorf("backups require the data of the web app on a volume claim")
+	}
+	claim := volume.PersistentVolumeClaim.ClaimName
+	if target := cr.Spec.Backup.Target.PersistentVolumeClaim; target != nil && target.ClaimName == claim {
+		return nil, fmt.Errorf("backups can not be kept on PersistentVolumeClaim %s, the web app keeps its data on it", claim)
+	}
+	return append(objects, h.backupCronJob(cr, wl, claim)), nil
+}
+
+// reconcileRestore restores the data of the web app from spec.restore. Each pod of a web app on an emptyDir
+// restores its own volume with an init container. The volume claim of a web app is restored once by a Job,
+// the web app is held at 0 replicas until the Job completed so no pod writes to the claim concurrently. The
+// restore only seeds a new web app, it is skipped for a web app provisioned before it was requested.
+func (h *AppHandler) reconcileRestore(cr *v1alpha1.WebApp, wl *workload, volume corev1.Volume, mount corev1.VolumeMount, objects []runtime.Object) ([]runtime.Object, error) {
+	restore := cr.Spec.Restore
+	image := restore.Image
+	if image == "" && cr.Spec.Backup != nil {
+		image = cr.Spec.Backup.Image
+	}
+	env, volumes, mounts := locationMounts(cr, restore.Source)
+	env = append(env, corev1.EnvVar{Name: "DATA", Value: mount.MountPath}, corev1.EnvVar{Name: "ARCHIVE", Value: restore.Archive})
+	container := corev1.Container{
+		Name:         "restore",
+		Image:        h.backupImage(image),
+		Command:      []string{"/bin/sh", "-c", restoreScript},
+		Env:          env,
+		VolumeMounts: append(mounts, corev1.VolumeMount{Name: volume.Name, MountPath: mount.MountPath}),
+	}
+
+	if volume.PersistentVolumeClaim == nil {
+		wl.template.Spec.Volumes = append(wl.template.Spec.Volumes, volumes...)
+		wl.template.Spec.InitContainers = append(wl.template.Spec.InitContainers, container)
+		return objects, nil
+	}
+
+	status := cr.Status.Restore
+	if status == nil {
+		status = &v1alpha1.WebAppRestoreStatus{}
+		cr.Status.Restore = status
+		if isProvisioned(cr) {
+			now := metav1.Now()
+			status.CompletionTime = &now
+			status.Message = "skipped, the web app was provisioned before the restore was requested"
+			return objects, nil
+		}
+	}
+	if status.CompletionTime != nil {
+		return objects, nil
+	}
+
+	status.Job = restoreName
+	job := &batchv1.Job{}
+	err := h.getLive("batch/v1", "Job", cr.Namespace, restoreName, job)
+	if errors2.IsNotFound(err) {
+		status.Message = ""
+		wl.setReplicas(0)
+		return append(objects, restoreJob(cr, container, append(volumes, volume))), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Job %s: %v", restoreName, err)
+	}
+	if job.Status.Succeeded > 0 {
+		status.CompletionTime = job.Status.CompletionTime
+		if status.CompletionTime == nil {
+			now := metav1.Now()
+			status.CompletionTime = &now
+		}
+		status.Message = ""
+		h.recorder.Eventf(cr, corev1.EventTypeNormal, eventRestored, "restored the data of the web app with Job %s", restoreName)
+		return objects, nil
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			message := fmt.Sprintf("restore %s failed: %s", restoreName, c.Message)
+			if message != status.Message {
+				h.recorder.Event(cr, corev1.EventTypeWarning, eventRestoreFailed, message)
+			}
+			status.Message = message
+		}
+	}
+	wl.setReplicas(0)
+	return objects, nil
+}
+
+// restoreJob restores the volume claim of the web app, the claim is mounted read write so it can only run
+// while the web app is scaled down
+func restoreJob(cr *v1alpha1.WebApp, container corev1.Container, volumes []corev1.Volume) *batchv1.Job {
+	backoffLimit := int32(2)
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   restoreName,
+			Labels: map[string]string{"app": cr.Spec.AppLabel},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{container},
+					Volumes:       volumes,
+				},
+			},
+		},
+	}
+}
+
+// backupCronJob runs the backups on the node of the web app pods, so a ReadWriteOnce claim can be mounted
+// by both. The claim is mounted read only.
+func (h *AppHandler) backupCronJob(cr *v1alpha1.WebApp, wl *workload, claim string) *batchv1beta1.CronJob {
+	backup := cr.Spec.Backup
+	env, volumes, mounts := locationMounts(cr, backup.Target)
+	env = append(env, corev1.EnvVar{Name: "DATA", Value: dataDir})
+	volumes = append(volumes, corev1.Volume{
+		Name: "data",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim, ReadOnly: true},
+		},
+	})
+	mounts = append(mounts, corev1.VolumeMount{Name: "data", MountPath: dataDir, ReadOnly: true})
+
+	var affinity *corev1.Affinity
+	if len(wl.template.Labels) > 0 {
+		affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: wl.template.Labels},
+					TopologyKey:   "kubernetes.io/hostname",
+				}},
+			},
+		}
+	}
+
+	backoffLimit := int32(2)
+	suspend := backup.Suspend
+	return &batchv1beta1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1beta1",
+			Kind:       "CronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   backupName,
+			Labels: map[string]string{"app": cr.Spec.AppLabel},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:          backup.Schedule,
+			Suspend:           &suspend,
+			ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{backupLabel: cr.Name},
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Affinity:      affinity,
+							Containers: []corev1.Container{{
+								Name:         "backup",
+								Image:        h.backupImage(backup.Image),
+								Command:      []string{"/bin/sh", "-c", backupScript},
+								Env:          env,
+								VolumeMounts: mounts,
+							}},
+							Volumes: volumes,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// locationMounts returns the environment, the volumes and the volume mounts of a container that reads or
+// writes the archives of a location
+func locationMounts(cr *v1alpha1.WebApp, location v1alpha1.BackupLocation) ([]corev1.EnvVar, []corev1.Volume, []corev1.VolumeMount) {
+	// the MinIO client keeps its configuration in the home directory, which is not writable for arbitrary users
+	env := []corev1.EnvVar{{Name: "MC_CONFIG_DIR", Value: "/tmp/.mc"}}
+	if s3 := location.S3; s3 != nil {
+		prefix := strings.Trim(s3.Prefix, "/")
+		if prefix == "" {
+			prefix = cr.Namespace + "/" + cr.Name
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+			corev1.EnvVar{Name: "S3_PATH", Value: s3.Bucket + "/" + prefix},
+			secretEnv("AWS_ACCESS_KEY_ID", s3.CredentialsSecret),
+			secretEnv("AWS_SECRET_ACCESS_KEY", s3.CredentialsSecret),
+		)
+		return env, nil, nil
+	}
+
+	claim := location.PersistentVolumeClaim
+	dir := claim.Path
+	if dir == "" {
+		dir = cr.Name
+	}
+	env = append(env, corev1.EnvVar{Name: "ARCHIVES", Value: path.Join(archivesDir, dir)})
+	volumes := []corev1.Volume{{
+		Name: "archives",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim.ClaimName},
+		},
+	}}
+	return env, volumes, []corev1.VolumeMount{{Name: "archives", MountPath: archivesDir}}
+}
+
+func secretEnv(key, secret string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: key,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// backupImage returns the image of the backup and restore containers, the image of the WebApp or the default
+// image of the operator
+func (h *AppHandler) backupImage(image string) string {
+	if image == "" {
+		return h.defaultBackupImage
+	}
+	return image
+}
+
+func (h *AppHandler) deleteBackup(cr *v1alpha1.WebApp) error {
+	client, _, err := h.dynamicResourceClientFactory("batch/v1beta1", "CronJob", cr.Namespace)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	err = client.Delete(backupName, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors2.IsNotFound(err) {
+		return fmt.Errorf("failed to delete CronJob %s: %v", backupName, err)
+	}
+	return nil
+}
+
+// observeBackup surfaces the state of the backup CronJob and the outcome of its latest jobs, a backup that
+// failed after the last successful one is recorded as a warning event
+func (h *AppHandler) observeBackup(cr *v1alpha1.WebApp) {
+	if cr.Spec.Backup == nil {
+		cr.Status.Backup = nil
+		return
+	}
+	previous := ""
+	if cr.Status.Backup != nil {
+		previous = cr.Status.Backup.Message
+	}
+	status := &v1alpha1.WebAppBackupStatus{}
+	cr.Status.Backup = status
+
+	cronJob := &batchv1beta1.CronJob{}
+	if err := h.getLive("batch/v1beta1", "CronJob", cr.Namespace, backupName, cronJob); err != nil {
+		status.Message = fmt.Sprintf("failed to get CronJob %s: %v", backupName, err)
+		return
+	}
+	status.LastScheduleTime = cronJob.Status.LastScheduleTime
+	status.Active = len(cronJob.Status.Active)
+
+	client, _, err := h.dynamicResourceClientFactory("batch/v1", "Job", cr.Namespace)
+	if err != nil {
+		status.Message = fmt.Sprintf("failed to list the backup jobs: %v", err)
+		return
+	}
+	list, err := client.List(metav1.ListOptions{LabelSelector: backupLabel + "=" + cr.Name})
+	if err != nil {
+		status.Message = fmt.Sprintf("failed to list the backup jobs: %v", err)
+		return
+	}
+	var lastFailure *metav1.Time
+	for _, item := range list.Items {
+		job := &batchv1.Job{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, job); err != nil {
+			continue
+		}
+		if t := job.Status.CompletionTime; t != nil && job.Status.Succeeded > 0 && (status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(t)) {
+			status.LastSuccessfulTime = t
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && (lastFailure == nil || lastFailure.Before(&c.LastTransitionTime)) {
+				lastFailure = c.LastTransitionTime.DeepCopy()
+				status.Message = fmt.Sprintf("backup %s failed: %s", job.Name, c.Message)
+			}
+		}
+	}
+	if lastFailure == nil || status.LastSuccessfulTime != nil && lastFailure.Before(status.LastSuccessfulTime) {
+		status.Message = ""
+		return
+	}
+	if status.Message != previous {
+		h.recorder.Event(cr, corev1.EventTypeWarning, eventBackupFailed, status.Message)
+	}
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/operator-framework/operator-sdk/pkg/sdk"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func backupHandler(cluster *fakeCluster, recorder record.EventRecorder) AppHandler {
+	osClient := &openshift.OSClientInterfaceMock{
+		ProcessTemplateFunc: processLocally,
+		GetPodsFunc:         podPhase(corev1.PodRunning),
+		GetEndpointsFunc:    serviceEndpoints,
+	}
+	cruder := &SdkCruderMock{
+		UpdateFunc:       func(object sdk.Object) error { return nil },
+		UpdateStatusFunc: func(object sdk.Object) error { return nil },
+	}
+	return NewWebHandler(nil, recorder, osClient, cluster.clientFactory, cruder, openshift.PlatformOpenShift, "", "")
+}
+
+func liveObject(t *testing.T, cluster *fakeCluster, kind, name string, into runtime.Object) {
+	obj := cluster.get(kind, name)
+	if obj == nil {
+		t.Fatalf("expected %s %s to be provisioned, got %v", kind, name, cluster.objects)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into); err != nil {
+		t.Fatalf("failed to convert %s %s: %v", kind, name, err)
+	}
+}
+
+func envValue(env []corev1.EnvVar, name string) (corev1.EnvVar, bool) {
+	for _, e := range env {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return corev1.EnvVar{}, false
+}
+
+func s3Location() v1alpha1.BackupLocation {
+	return v1alpha1.BackupLocation{
+		S3: &v1alpha1.S3Location{Endpoint: "http://minio:9000", Bucket: "webapps", CredentialsSecret: "minio"},
+	}
+}
+
+func TestReconcile_Backup(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Spec   v1alpha1.WebAppSpec
+		Verify func(*v1alpha1.WebApp, *fakeCluster, *testing.T)
+	}{
+		{
+			Name: "Back up to an object store",
+			Spec: v1alpha1.WebAppSpec{
+				Backup: &v1alpha1.WebAppBackup{Schedule: "0 2 * * *", Target: s3Location()},
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				cronJob := &batchv1beta1.CronJob{}
+				liveObject(t, cluster, "CronJob", backupName, cronJob)
+				if cronJob.Spec.Schedule != "0 2 * * *" || cronJob.Spec.ConcurrencyPolicy != batchv1beta1.ForbidConcurrent {
+					t.Fatalf("expected a daily CronJob without concurrent backups, got %+v", cronJob.Spec)
+				}
+				if cronJob.Spec.JobTemplate.Labels[backupLabel] != wa.Name {
+					t.Fatalf("expected the jobs to be labelled with the WebApp, got %v", cronJob.Spec.JobTemplate.Labels)
+				}
+				pod := cronJob.Spec.JobTemplate.Spec.Template.Spec
+				container := pod.Containers[0]
+				if container.Image != BackupImage {
+					t.Fatalf("expected image %s, got %s", BackupImage, container.Image)
+				}
+				if path, _ := envValue(container.Env, "S3_PATH"); path.Value != "webapps/webapp/tutorial-web-app" {
+					t.Fatalf("expected the archives to be prefixed with the namespace and the name, got %q", path.Value)
+				}
+				if key, _ := envValue(container.Env, "AWS_SECRET_ACCESS_KEY"); key.ValueFrom == nil || key.ValueFrom.SecretKeyRef.Name != "minio" {
+					t.Fatalf("expected the secret key to be read from secret minio, got %+v", key)
+				}
+				if len(pod.Volumes) != 1 || pod.Volumes[0].PersistentVolumeClaim == nil || pod.Volumes[0].PersistentVolumeClaim.ClaimName != "user-walkthroughs" || !pod.Volumes[0].PersistentVolumeClaim.ReadOnly {
+					t.Fatalf("expected claim user-walkthroughs to be mounted read only, got %+v", pod.Volumes)
+				}
+				terms := pod.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+				if len(terms) != 1 || terms[0].LabelSelector.MatchLabels["app"] != "tutorial-web-app" {
+					t.Fatalf("expected the backups to run next to the web app pods, got %+v", terms)
+				}
+				if wa.Status.Backup == nil {
+					t.Fatalf("expected a backup status")
+				}
+			},
+		},
+		{
+			Name: "Back up to a volume claim",
+			Spec: v1alpha1.WebAppSpec{
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "@hourly",
+					Target:   v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups", Path: "workshop"}},
+					Image:    "quay.io/example/backup:1.0",
+				},
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				cronJob := &batchv1beta1.CronJob{}
+				liveObject(t, cluster, "CronJob", backupName, cronJob)
+				pod := cronJob.Spec.JobTemplate.Spec.Template.Spec
+				if pod.Containers[0].Image != "quay.io/example/backup:1.0" {
+					t.Fatalf("expected the image of the CR, got %s", pod.Containers[0].Image)
+				}
+				if archives, _ := envValue(pod.Containers[0].Env, "ARCHIVES"); archives.Value != "/archives/workshop" {
+					t.Fatalf("expected the archives in /archives/workshop, got %q", archives.Value)
+				}
+				if len(pod.Volumes) != 2 || pod.Volumes[0].PersistentVolumeClaim == nil || pod.Volumes[0].PersistentVolumeClaim.ClaimName != "backups" {
+					t.Fatalf("expected claim backups to be mounted, got %+v", pod.Volumes)
+				}
+			},
+		},
+		{
+			Name: "Back up to the data claim",
+			Spec: v1alpha1.WebAppSpec{
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "@hourly",
+					Target:   v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "user-walkthroughs"}},
+				},
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				c := wa.Status.GetCondition(v1alpha1.ObjectsProvisioned)
+				if c == nil || c.Reason != v1alpha1.ReasonReconcileFailed || !strings.Contains(c.Message, "the web app keeps its data on it") {
+					t.Fatalf("expected condition %s with reason %s, got %v", v1alpha1.ObjectsProvisioned, v1alpha1.ReasonReconcileFailed, c)
+				}
+			},
+		},
+		{
+			Name: "Restore the latest archive into an emptyDir",
+			Spec: v1alpha1.WebAppSpec{
+				Storage: &v1alpha1.WebAppStorage{EmptyDir: true},
+				Restore: &v1alpha1.WebAppRestore{Source: s3Location()},
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := &appsv1.DeploymentConfig{}
+				liveObject(t, cluster, "DeploymentConfig", "tutorial-web-app", dc)
+				pod := dc.Spec.Template.Spec
+				if len(pod.InitContainers) != 1 || pod.InitContainers[0].Name != "restore" {
+					t.Fatalf("expected a restore init container, got %+v", pod.InitContainers)
+				}
+				restore := pod.InitContainers[0]
+				if data, _ := envValue(restore.Env, "DATA"); data.Value != "/opt/user-walkthroughs" {
+					t.Fatalf("expected the data to be restored to the database location, got %q", data.Value)
+				}
+				if archive, _ := envValue(restore.Env, "ARCHIVE"); archive.Value != "" {
+					t.Fatalf("expected the latest archive to be restored, got %q", archive.Value)
+				}
+				if len(restore.VolumeMounts) != 1 || restore.VolumeMounts[0].Name != "user-walkthroughs" || restore.VolumeMounts[0].MountPath != "/opt/user-walkthroughs" {
+					t.Fatalf("expected the data volume to be mounted, got %+v", restore.VolumeMounts)
+				}
+				if cluster.get("CronJob", backupName) != nil {
+					t.Fatalf("expected no backups to be scheduled")
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cluster := newFakeCluster()
+			wh := backupHandler(cluster, &record.FakeRecorder{})
+			tc.Spec.Template = v1alpha1.WebAppTemplate{Path: testTemplate}
+			cr := &v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"}, Spec: tc.Spec}
+			wh.Reconcile(context.TODO(), cr)
+			tc.Verify(cr, cluster, t)
+		})
+	}
+}
+
+func TestReconcile_BackupRemoved(t *testing.T) {
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec: v1alpha1.WebAppSpec{
+			Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+			Backup:   &v1alpha1.WebAppBackup{Schedule: "@daily", Target: s3Location()},
+		},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("CronJob", backupName) == nil {
+		t.Fatalf("expected the backups to be scheduled")
+	}
+
+	cr.Spec.Backup = nil
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("CronJob", backupName) != nil {
+		t.Fatalf("expected CronJob %s to be deleted", backupName)
+	}
+	if cr.Status.Backup != nil {
+		t.Fatalf("expected the backup status to be cleared, got %+v", cr.Status.Backup)
+	}
+}
+
+func TestReconcile_Restore(t *testing.T) {
+	cluster := newFakeCluster()
+	recorder := record.NewFakeRecorder(20)
+	wh := backupHandler(cluster, recorder)
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec: v1alpha1.WebAppSpec{
+			Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+			Restore:  &v1alpha1.WebAppRestore{Source: s3Location(), Archive: "walkthroughs-20190412T020000Z.tar.gz"},
+		},
+	}
+	replicas := func() int32 {
+		dc := &appsv1.DeploymentConfig{}
+		liveObject(t, cluster, "DeploymentConfig", "tutorial-web-app", dc)
+		return dc.Spec.Replicas
+	}
+
+	// the web app is held at 0 replicas while the Job restores the claim
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job := &batchv1.Job{}
+	liveObject(t, cluster, "Job", restoreName, job)
+	pod := job.Spec.Template.Spec
+	if pod.RestartPolicy != corev1.RestartPolicyNever || pod.Containers[0].Image != BackupImage {
+		t.Fatalf("expected a restore pod that is not restarted, got %+v", pod)
+	}
+	if archive, _ := envValue(pod.Containers[0].Env, "ARCHIVE"); archive.Value != "walkthroughs-20190412T020000Z.tar.gz" {
+		t.Fatalf("expected the archive of the CR to be restored, got %q", archive.Value)
+	}
+	if len(pod.Volumes) != 1 || pod.Volumes[0].PersistentVolumeClaim == nil || pod.Volumes[0].PersistentVolumeClaim.ClaimName != "user-walkthroughs" {
+		t.Fatalf("expected the data claim to be restored, got %+v", pod.Volumes)
+	}
+	if r := replicas(); r != 0 {
+		t.Fatalf("expected the web app to be held at 0 replicas, got %d", r)
+	}
+	dc := &appsv1.DeploymentConfig{}
+	liveObject(t, cluster, "DeploymentConfig", "tutorial-web-app", dc)
+	if len(dc.Spec.Template.Spec.InitContainers) != 0 {
+		t.Fatalf("expected no restore init container, got %+v", dc.Spec.Template.Spec.InitContainers)
+	}
+	if c := cr.Status.GetCondition(v1alpha1.Ready); c == nil || c.Status != corev1.ConditionFalse || !strings.Contains(c.Message, "restoring the data") {
+		t.Fatalf("expected the web app not to be ready during the restore, got %+v", c)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	cluster.put(t, "Job", job)
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := cr.Status.GetCondition(v1alpha1.DeploymentAvailable); c == nil || c.Reason != v1alpha1.ReasonRestoreFailed {
+		t.Fatalf("expected the failed restore to be reported, got %+v", c)
+	}
+	if r := replicas(); r != 0 {
+		t.Fatalf("expected the web app to be held at 0 replicas after a failed restore, got %d", r)
+	}
+
+	// the web app starts on the restored claim and the restore does not run again
+	job.Status.Conditions = nil
+	job.Status.Succeeded = 1
+	cluster.put(t, "Job", job)
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cr.Status.Restore == nil || cr.Status.Restore.CompletionTime == nil {
+		t.Fatalf("expected the restore to be completed, got %+v", cr.Status.Restore)
+	}
+	if r := replicas(); r != 1 {
+		t.Fatalf("expected the replicas of the template, got %d", r)
+	}
+	delete(cluster.objects, "Job/"+restoreName)
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("Job", restoreName) != nil {
+		t.Fatalf("expected the restore to run once")
+	}
+	events := strings.Join(recorded(recorder), "\n")
+	if !strings.Contains(events, "Warning RestoreFailed restore tutorial-web-app-restore failed: BackoffLimitExceeded") || !strings.Contains(events, "Normal Restored restored the data of the web app with Job tutorial-web-app-restore") {
+		t.Fatalf("expected the failed and the completed restore to be recorded, got %q", events)
+	}
+}
+
+func TestReconcile_RestoreProvisioned(t *testing.T) {
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the data of a running web app is never replaced
+	cr.Spec.Restore = &v1alpha1.WebAppRestore{Source: s3Location()}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("Job", restoreName) != nil {
+		t.Fatalf("expected no restore of a provisioned web app")
+	}
+	if cr.Status.Restore == nil || cr.Status.Restore.CompletionTime == nil || !strings.Contains(cr.Status.Restore.Message, "skipped") {
+		t.Fatalf("expected the restore to be skipped, got %+v", cr.Status.Restore)
+	}
+	if !cr.Status.IsConditionTrue(v1alpha1.Ready) {
+		t.Fatalf("expected the web app to stay ready, got %+v", cr.Status.Conditions)
+	}
+}
+
+func backupJob(name string, finished time.Time, failed bool) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{backupLabel: "tutorial-web-app"}},
+	}
+	if failed {
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type:               batchv1.JobFailed,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(finished),
+			Message:            "Job has reached the specified backoff limit",
+		}}
+		return job
+	}
+	job.Status.Succeeded = 1
+	completion := metav1.NewTime(finished)
+	job.Status.CompletionTime = &completion
+	return job
+}
+
+func TestObserveBackup(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	cluster := newFakeCluster()
+	scheduled := metav1.NewTime(now)
+	cluster.put(t, "CronJob", &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: backupName},
+		Status: batchv1beta1.CronJobStatus{
+			LastScheduleTime: &scheduled,
+			Active:           []corev1.ObjectReference{{Name: "tutorial-web-app-backup-3"}},
+		},
+	})
+	cluster.put(t, "Job", backupJob("tutorial-web-app-backup-1", now.Add(-2*time.Hour), false))
+	cluster.put(t, "Job", backupJob("tutorial-web-app-backup-2", now.Add(-time.Hour), true))
+	// jobs of other WebApps are ignored
+	other := backupJob("other-backup-1", now, false)
+	other.Labels[backupLabel] = "other"
+	cluster.put(t, "Job", other)
+
+	recorder := record.NewFakeRecorder(10)
+	wh := backupHandler(cluster, recorder)
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Backup: &v1alpha1.WebAppBackup{Schedule: "@hourly", Target: s3Location()}},
+	}
+
+	wh.observeBackup(cr)
+	status := cr.Status.Backup
+	if status.Active != 1 || !status.LastScheduleTime.Equal(&scheduled) {
+		t.Fatalf("expected one active backup scheduled at %v, got %+v", scheduled, status)
+	}
+	if status.LastSuccessfulTime == nil || !status.LastSuccessfulTime.Time.Equal(now.Add(-2*time.Hour)) {
+		t.Fatalf("expected the last successful backup two hours ago, got %v", status.LastSuccessfulTime)
+	}
+	if !strings.Contains(status.Message, "backup tutorial-web-app-backup-2 failed") {
+		t.Fatalf("expected the failed backup to be reported, got %q", status.Message)
+	}
+	// the failure is recorded once
+	wh.observeBackup(cr)
+	expectEvents(t, recorder, "Warning BackupFailed "+status.Message)
+
+	cluster.put(t, "Job", backupJob("tutorial-web-app-backup-3", now, false))
+	wh.observeBackup(cr)
+	if cr.Status.Backup.Message != "" {
+		t.Fatalf("expected a successful backup to clear the failure, got %q", cr.Status.Backup.Message)
+	}
+}
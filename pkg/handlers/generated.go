@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	v1template "github.com/openshift/api/template/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// generatedParametersSecret is the Secret the values of generated template parameters are kept in
+func generatedParametersSecret(cr *v1alpha1.WebApp) string {
+	return cr.Name + "-generated-parameters"
+}
+
+// generatedParameters sets the template parameters with a generator that are not set otherwise to the values
+// generated for the WebApp before. Values are generated once and kept in a Secret owned by the WebApp, so the
+// objects rendered from the template do not change on every reconcile.
+func (h *AppHandler) generatedParameters(cr *v1alpha1.WebApp, tmpl *v1template.Template, params map[string]string) (map[string]string, error) {
+	var generated []v1template.Parameter
+	for _, p := range tmpl.Parameters {
+		if p.Generate != "" && p.Value == "" && params[p.Name] == "" {
+			generated = append(generated, p)
+		}
+	}
+	if len(generated) == 0 {
+		return params, nil
+	}
+
+	name := generatedParametersSecret(cr)
+	client, _, err := h.dynamicResourceClientFactory("v1", "Secret", cr.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource client: %v", err)
+	}
+	secret := &corev1.Secret{}
+	live, err := client.Get(name, metav1.GetOptions{})
+	switch {
+	case errors2.IsNotFound(err):
+		live = nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get Secret %s: %v", name, err)
+	default:
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, secret); err != nil {
+			return nil, err
+		}
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	values := make(map[string]string, len(params)+len(generated))
+	for k, v := range params {
+		values[k] = v
+	}
+	changed := false
+	for _, p := range generated {
+		if value, ok := secret.Data[p.Name]; ok {
+			values[p.Name] = string(value)
+			continue
+		}
+		value, err := openshift.GenerateValue(p)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[p.Name] = []byte(value)
+		values[p.Name] = value
+		changed = true
+	}
+	if !changed {
+		return values, nil
+	}
+
+	secret.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	secret.Name = name
+	secret.Namespace = cr.Namespace
+	secret.OwnerReferences = []metav1.OwnerReference{cr.OwnerReference()}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		_, err = client.Create(&unstructured.Unstructured{Object: obj})
+	} else {
+		_, err = client.Update(&unstructured.Unstructured{Object: obj})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store the generated parameters in Secret %s: %v", name, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	v1template "github.com/openshift/api/template/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGeneratedParameters(t *testing.T) {
+	tmpl := &v1template.Template{Parameters: []v1template.Parameter{
+		{Name: "PASSWORD", Generate: "expression", From: "[a-z]{16}"},
+		{Name: "TOKEN", Generate: "expression", From: "[a-z]{16}"},
+		{Name: "DEFAULTED", Generate: "expression", From: "[a-z]{16}", Value: "default"},
+		{Name: "PLAIN"},
+	}}
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	cr := &v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"}}
+
+	first, err := wh.generatedParameters(cr, tmpl, map[string]string{"TOKEN": "set"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first["PASSWORD"]) != 16 || first["TOKEN"] != "set" {
+		t.Fatalf("expected PASSWORD to be generated and TOKEN to be kept, got %v", first)
+	}
+	if _, ok := first["DEFAULTED"]; ok {
+		t.Fatalf("expected a parameter with a value to be left to the template, got %v", first)
+	}
+
+	secret := &corev1.Secret{}
+	liveObject(t, cluster, "Secret", "tutorial-web-app-generated-parameters", secret)
+	if string(secret.Data["PASSWORD"]) != first["PASSWORD"] || len(secret.Data) != 1 {
+		t.Fatalf("expected only the generated value to be stored, got %v", secret.Data)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Kind != "WebApp" {
+		t.Fatalf("expected the Secret to be owned by the WebApp, got %v", secret.OwnerReferences)
+	}
+
+	second, err := wh.generatedParameters(cr, tmpl, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second["PASSWORD"] != first["PASSWORD"] || len(second["TOKEN"]) != 16 {
+		t.Fatalf("expected PASSWORD to be reused and TOKEN to be generated, got %v", second)
+	}
+	liveObject(t, cluster, "Secret", "tutorial-web-app-generated-parameters", secret)
+	if string(secret.Data["TOKEN"]) != second["TOKEN"] {
+		t.Fatalf("expected the generated TOKEN to be stored, got %v", secret.Data)
+	}
+}
@@ -1,7 +0,0 @@
-package handlers
-
-func NewHandler(webHandler Handler) Handlers {
-	return Handlers{
-		WebAppHandler: webHandler,
-	}
-}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// desiredImage returns the image the web app should run, the image of the CR takes precedence over
+// the default image of the operator
+func (h *AppHandler) desiredImage(cr *v1alpha1.WebApp) string {
+	if cr.Spec.Image != "" {
+		return cr.Spec.Image
+	}
+	return h.defaultImage
+}
+
+// reconcileImage sets the image of the web app container according to the image policy of the CR.
+// Policies other than Enforce keep the image of a running workload, so it has to be looked up.
+func (h *AppHandler) reconcileImage(cr *v1alpha1.WebApp, wl *workload, templateImage string) (string, error) {
+	switch cr.Spec.ImagePolicy {
+	case "", v1alpha1.ImagePolicyEnforce:
+		return h.desiredImage(cr), nil
+	case v1alpha1.ImagePolicyIfNotSet, v1alpha1.ImagePolicyIgnore:
+		live, err := h.liveImage(cr.Namespace, wl)
+		if err != nil {
+			return "", err
+		}
+		if live != "" {
+			return live, nil
+		}
+		if cr.Spec.ImagePolicy == v1alpha1.ImagePolicyIgnore {
+			return templateImage, nil
+		}
+		return h.desiredImage(cr), nil
+	}
+	return "", fmt.Errorf("unknown image policy %q", cr.Spec.ImagePolicy)
+}
+
+// liveImage returns the image of the first container of the workload in the cluster, or an empty string
+// when the workload does not exist yet
+func (h *AppHandler) liveImage(ns string, wl *workload) (string, error) {
+	var containers []string
+	if h.platform == openshift.PlatformKubernetes {
+		deployment, err := h.osClient.GetDeployment(ns, wl.name)
+		if errors2.IsNotFound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, c := range deployment.Spec.Template.Spec.Containers {
+			containers = append(containers, c.Image)
+		}
+	} else {
+		dc, err := h.osClient.GetDC(ns, wl.name)
+		if errors2.IsNotFound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if dc.Spec.Template != nil {
+			for _, c := range dc.Spec.Template.Spec.Containers {
+				containers = append(containers, c.Image)
+			}
+		}
+	}
+
+	if len(containers) == 0 {
+		return "", nil
+	}
+	return containers[0], nil
+}
+
+// imageVersion returns the digest of an image referenced by digest, otherwise its tag
+func imageVersion(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return "latest"
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// migration moves objects of a WebApp that were provisioned by an earlier operator version, or with earlier
+// settings, to the layout the current version renders. Migrations run in the order of their version before the
+// objects are provisioned and are recorded in the status once the workload was provisioned. A recorded migration
+// does not run again until its target changed.
+type migration struct {
+	version int32
+	name    string
+	// event is the reason of the event recorded when the migration changed something
+	event string
+	// target returns the state the migration moves the web app to, empty for migrations that only run once
+	target func(h *AppHandler, s *migrationState) string
+	// migrate returns what it migrated, or an empty string when there was nothing to migrate
+	migrate func(h *AppHandler, s *migrationState) (string, error)
+	// migrated runs once the workload of a migration that changed something was provisioned, optional
+	migrated func(h *AppHandler)
+}
+
+// migrations are ordered by version, new migrations are appended with the next version
+var migrations = []migration{
+	{version: 1, name: "Image", event: eventImageMigrated, target: desiredImage, migrate: (*AppHandler).migrateImage, migrated: imageMigrated},
+	{version: 2, name: "LegacyEnvVars", event: eventMigrated, migrate: (*AppHandler).migrateEnvVars},
+	{version: 3, name: "LegacyRoute", event: eventMigrated, target: currentRoute, migrate: (*AppHandler).migrateRoute},
+}
+
+// legacyEnvVars are the environment variables the 1.x operator set on the web app container from the parameters
+// of the WebApp. The workloads it provisioned have no last applied state, so the three-way merge keeps the ones
+// the template no longer renders.
+var legacyEnvVars = []string{
+	"OPENSHIFT_OAUTHCLIENT_ID", "OPENSHIFT_HOST", "OPENSHIFT_OAUTH_HOST", "SSO_ROUTE", "OPENSHIFT_API",
+	"OPENSHIFT_VERSION", "INTEGREATLY_VERSION", "WALKTHROUGH_LOCATIONS", "CLUSTER_TYPE", "INSTALLED_SERVICES",
+	"INSTALLATION_TYPE", "UPGRADE_DATA",
+}
+
+// migrationState is shared by the migrations of a reconcile, the live workload is nil until it was created
+type migrationState struct {
+	cr      *v1alpha1.WebApp
+	desired *workload
+	live    *unstructured.Unstructured
+	// legacyEnv are the environment variables removed from the workload with its patch
+	legacyEnv []string
+}
+
+// migrationRun is a migration that ran in a reconcile
+type migrationRun struct {
+	migration
+	target  string
+	message string
+}
+
+// migrationResult is the outcome of the migrations of a reconcile
+type migrationResult struct {
+	runs []migrationRun
+	// workload is the desired workload, its patch removes legacyEnv
+	workload  runtime.Object
+	legacyEnv []string
+}
+
+// migrate runs the migrations against the objects rendered for the WebApp that did not run for their target yet
+func (h *AppHandler) migrate(cr *v1alpha1.WebApp, objects []runtime.Object) (*migrationResult, error) {
+	wl, err := findWorkload(objects, workloadName)
+	if err != nil {
+		return nil, err
+	}
+	gvk := wl.object.GetObjectKind().GroupVersionKind()
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	client, _, err := h.dynamicResourceClientFactory(apiVersion, kind, cr.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource client: %v", err)
+	}
+	live, err := client.Get(wl.name, metav1.GetOptions{})
+	if errors2.IsNotFound(err) {
+		live = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %v", wl.kind, wl.name, err)
+	}
+
+	s := &migrationState{cr: cr, desired: wl, live: live}
+	result := &migrationResult{workload: wl.object}
+	for _, m := range migrations {
+		target := ""
+		if m.target != nil {
+			target = m.target(h, s)
+		}
+		if record := findMigration(cr.Status, m.version); record != nil && record.Target == target {
+			continue
+		}
+		msg, err := m.migrate(h, s)
+		if err != nil {
+			return nil, fmt.Errorf("migration %d %s failed: %v", m.version, m.name, err)
+		}
+		result.runs = append(result.runs, migrationRun{migration: m, target: target, message: msg})
+	}
+	result.legacyEnv = s.legacyEnv
+	return result, nil
+}
+
+// recordMigrations records the migrations of a reconcile once the workload was provisioned, the ones that changed
+// something are recorded as events
+func (h *AppHandler) recordMigrations(cr *v1alpha1.WebApp, result *migrationResult) {
+	for _, run := range result.runs {
+		if run.message != "" {
+			logrus.Infof("Migration %d %s of WebApp %s/%s: %s", run.version, run.name, cr.Namespace, cr.Name, run.message)
+			h.recorder.Event(cr, corev1.EventTypeNormal, run.event, run.message)
+			if run.migrated != nil {
+				run.migrated(h)
+			}
+		}
+		recordMigration(&cr.Status, run)
+	}
+}
+
+func findMigration(status v1alpha1.WebAppStatus, version int32) *v1alpha1.WebAppMigration {
+	for i := range status.Migrations {
+		if status.Migrations[i].Version == version {
+			return &status.Migrations[i]
+		}
+	}
+	return nil
+}
+
+// recordMigration keeps the last run of each migration in the status, ordered by version
+func recordMigration(status *v1alpha1.WebAppStatus, run migrationRun) {
+	record := v1alpha1.WebAppMigration{Version: run.version, Name: run.name, Message: run.message, Target: run.target, Time: metav1.Now()}
+	if previous := findMigration(*status, run.version); previous != nil {
+		*previous = record
+		return
+	}
+	status.Migrations = append(status.Migrations, record)
+	sort.Slice(status.Migrations, func(i, j int) bool {
+		return status.Migrations[i].Version < status.Migrations[j].Version
+	})
+}
+
+// desiredImage is the target of the image migration, it runs again when the web app moves to another image
+func desiredImage(h *AppHandler, s *migrationState) string {
+	if len(s.desired.template.Spec.Containers) == 0 {
+		return ""
+	}
+	return s.desired.template.Spec.Containers[0].Image
+}
+
+func imageMigrated(h *AppHandler) {
+	h.metrics.ImageMigrated()
+}
+
+// currentRoute is the target of the route migration, it runs again when the routing subdomain renames the route
+func currentRoute(h *AppHandler, s *migrationState) string {
+	return routeNameForCR(s.cr)
+}
+
+// migrateImage reports a web app that moves to another image, the image itself is changed by the patch of the
+// workload
+func (h *AppHandler) migrateImage(s *migrationState) (string, error) {
+	if s.live == nil || len(s.desired.template.Spec.Containers) == 0 {
+		return "", nil
+	}
+	containers, _, _ := unstructured.NestedSlice(s.live.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return "", nil
+	}
+	live, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "image")
+	desired := s.desired.template.Spec.Containers[0].Image
+	if live == "" || live == desired {
+		return "", nil
+	}
+	return fmt.Sprintf("migrated from version %s to %s", imageVersion(live), imageVersion(desired)), nil
+}
+
+// migrateEnvVars removes the environment variables set by the 1.x operator that the template no longer renders
+// with the patch of the workload, so the web app is rolled out once. Variables recorded in the last applied
+// state are left to the three-way merge.
+func (h *AppHandler) migrateEnvVars(s *migrationState) (string, error) {
+	if s.live == nil || len(s.desired.template.Spec.Containers) == 0 {
+		return "", nil
+	}
+	keep := map[string]bool{}
+	for _, env := range s.desired.template.Spec.Containers[0].Env {
+		keep[env.Name] = true
+	}
+	if lastApplied := s.live.GetAnnotations()[lastAppliedAnnotation]; lastApplied != "" {
+		original := &unstructured.Unstructured{}
+		if err := original.UnmarshalJSON([]byte(lastApplied)); err == nil {
+			for name := range envVars(original) {
+				keep[name] = true
+			}
+		}
+	}
+	legacy := map[string]bool{}
+	for _, name := range legacyEnvVars {
+		legacy[name] = !keep[name]
+	}
+
+	containers, _, _ := unstructured.NestedSlice(s.live.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return "", nil
+	}
+	env, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "env")
+	var removed []string
+	for _, e := range env {
+		name, _, _ := unstructured.NestedString(e.(map[string]interface{}), "name")
+		if legacy[name] {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) == 0 {
+		return "", nil
+	}
+	sort.Strings(removed)
+	s.legacyEnv = removed
+	return fmt.Sprintf("removed environment variables %s of %s %s", strings.Join(removed, ", "), s.desired.kind, s.desired.name), nil
+}
+
+// migrateRoute deletes the route of the web app that was provisioned under the other name, the route is named
+// solution-explorer once the routing subdomain is set (RHMI 2.x) and tutorial-web-app before. The 1.x operator
+// did not set owner references, so a route without a WebApp owner is deleted when it sends requests to the
+// service of the web app. Routes owned by another WebApp are left alone.
+func (h *AppHandler) migrateRoute(s *migrationState) (string, error) {
+	current := routeNameForCR(s.cr)
+	legacy := solutionExplorerRouteName
+	if current == solutionExplorerRouteName {
+		legacy = routeName
+	}
+	apiVersion, kind := "route.openshift.io/v1", "Route"
+	if h.platform == openshift.PlatformKubernetes {
+		apiVersion, kind = "extensions/v1beta1", "Ingress"
+	}
+
+	client, _, err := h.dynamicResourceClientFactory(apiVersion, kind, s.cr.Namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to get resource client: %v", err)
+	}
+	live, err := client.Get(legacy, metav1.GetOptions{})
+	if errors2.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s %s: %v", kind, legacy, err)
+	}
+	if !servesWebApp(live, s.cr) {
+		return "", nil
+	}
+	if err := client.Delete(legacy, &metav1.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) {
+		return "", fmt.Errorf("failed to delete %s %s: %v", kind, legacy, err)
+	}
+	return fmt.Sprintf("deleted %s %s, the web app is served by %s %s", kind, legacy, kind, current), nil
+}
+
+// servesWebApp reports whether a Route or an Ingress belongs to the web app of the WebApp: it is owned by the
+// WebApp, or it has no WebApp owner and sends requests to the service of the web app
+func servesWebApp(obj *unstructured.Unstructured, cr *v1alpha1.WebApp) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "WebApp" {
+			return ref.UID == cr.UID
+		}
+	}
+	return routeService(obj) == serviceName
+}
+
+// routeService returns the service a Route or an Ingress sends requests to
+func routeService(obj *unstructured.Unstructured) string {
+	if name, ok, _ := unstructured.NestedString(obj.Object, "spec", "to", "name"); ok {
+		return name
+	}
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		for _, p := range paths {
+			path, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, ok, _ := unstructured.NestedString(path, "backend", "serviceName"); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
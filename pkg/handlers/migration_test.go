@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func legacyRoute(name string, owner types.UID) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"spec": map[string]interface{}{
+			"to": map[string]interface{}{"kind": "Service", "name": serviceName},
+		},
+	}}
+	route.SetName(name)
+	route.SetNamespace("webapp")
+	route.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "integreatly.org/v1alpha1", Kind: "WebApp", Name: "tutorial-web-app", UID: owner}})
+	return route
+}
+
+func migrationRecord(t *testing.T, status v1alpha1.WebAppStatus, version int32) v1alpha1.WebAppMigration {
+	record := findMigration(status, version)
+	if record == nil {
+		t.Fatalf("expected migration %d to be recorded, got %v", version, status.Migrations)
+	}
+	return *record
+}
+
+func TestReconcile_Migrations(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Parameters map[string]string
+		Prepare    func(*fakeCluster, *AppHandler)
+		Verify     func(*v1alpha1.WebApp, *fakeCluster, *record.FakeRecorder, *testing.T)
+	}{
+		{
+			Name:       "Delete the previous route once the routing subdomain is set",
+			Parameters: map[string]string{routingSubdomain: "apps.example.com"},
+			Prepare: func(cluster *fakeCluster, wh *AppHandler) {
+				cluster.objects["Route/tutorial-web-app"] = legacyRoute("tutorial-web-app", "webapp-uid")
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, recorder *record.FakeRecorder, t *testing.T) {
+				if cluster.get("Route", "tutorial-web-app") != nil {
+					t.Fatalf("expected the legacy route to be deleted")
+				}
+				if cluster.get("Route", "solution-explorer") == nil {
+					t.Fatalf("expected the solution-explorer route to be provisioned")
+				}
+				if m := migrationRecord(t, wa.Status, 3); m.Name != "LegacyRoute" || m.Target != "solution-explorer" || m.Message == "" {
+					t.Fatalf("expected the route migration to be recorded, got %v", m)
+				}
+				found := false
+				for _, e := range recorded(recorder) {
+					found = found || e == "Normal Migrated deleted Route tutorial-web-app, the web app is served by Route solution-explorer"
+				}
+				if !found {
+					t.Fatalf("expected a Migrated event")
+				}
+			},
+		},
+		{
+			Name:       "Keep a route of another WebApp of the same name",
+			Parameters: map[string]string{routingSubdomain: "apps.example.com"},
+			Prepare: func(cluster *fakeCluster, wh *AppHandler) {
+				cluster.objects["Route/tutorial-web-app"] = legacyRoute("tutorial-web-app", "other-uid")
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, recorder *record.FakeRecorder, t *testing.T) {
+				if cluster.get("Route", "tutorial-web-app") == nil {
+					t.Fatalf("expected the route of another WebApp to be kept")
+				}
+				if m := migrationRecord(t, wa.Status, 3); m.Message != "" {
+					t.Fatalf("expected nothing to be migrated, got %v", m)
+				}
+			},
+		},
+		{
+			Name:       "Delete the route of the 1.x operator that serves the web app",
+			Parameters: map[string]string{routingSubdomain: "apps.example.com"},
+			Prepare: func(cluster *fakeCluster, wh *AppHandler) {
+				route := legacyRoute("tutorial-web-app", "")
+				route.SetOwnerReferences(nil)
+				cluster.objects["Route/tutorial-web-app"] = route
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, recorder *record.FakeRecorder, t *testing.T) {
+				if cluster.get("Route", "tutorial-web-app") != nil {
+					t.Fatalf("expected the route of the 1.x operator to be deleted")
+				}
+				if m := migrationRecord(t, wa.Status, 3); m.Message == "" {
+					t.Fatalf("expected the route migration to be recorded, got %v", m)
+				}
+			},
+		},
+		{
+			Name:       "Keep a route without owner that serves another service",
+			Parameters: map[string]string{routingSubdomain: "apps.example.com"},
+			Prepare: func(cluster *fakeCluster, wh *AppHandler) {
+				route := legacyRoute("tutorial-web-app", "")
+				route.SetOwnerReferences(nil)
+				unstructured.SetNestedField(route.Object, "other", "spec", "to", "name")
+				cluster.objects["Route/tutorial-web-app"] = route
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, recorder *record.FakeRecorder, t *testing.T) {
+				if cluster.get("Route", "tutorial-web-app") == nil {
+					t.Fatalf("expected a route of another service to be kept")
+				}
+			},
+		},
+		{
+			Name: "Record the migration to another image",
+			Prepare: func(cluster *fakeCluster, wh *AppHandler) {
+				wh.defaultImage = "quay.io/integreatly/tutorial-web-app:2.10.0"
+				wh.Reconcile(context.TODO(), &v1alpha1.WebApp{
+					ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+					Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+				})
+				wh.defaultImage = "quay.io/integreatly/tutorial-web-app:2.11.0"
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, recorder *record.FakeRecorder, t *testing.T) {
+				if m := migrationRecord(t, wa.Status, 1); m.Message != "migrated from version 2.10.0 to 2.11.0" || m.Target != "quay.io/integreatly/tutorial-web-app:2.11.0" {
+					t.Fatalf("expected the image migration to be recorded, got %v", m)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cluster := newFakeCluster()
+			recorder := record.NewFakeRecorder(20)
+			wh := backupHandler(cluster, recorder)
+			if tc.Prepare != nil {
+				tc.Prepare(cluster, &wh)
+			}
+			recorded(recorder)
+			cr := &v1alpha1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp", UID: "webapp-uid"},
+				Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate, Parameters: tc.Parameters}},
+			}
+			if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tc.Verify(cr, cluster, recorder, t)
+		})
+	}
+}
+
+func TestReconcile_MigrationsRunOnce(t *testing.T) {
+	cluster := newFakeCluster()
+	recorder := record.NewFakeRecorder(50)
+	wh := backupHandler(cluster, recorder)
+	wh.defaultImage = "quay.io/integreatly/tutorial-web-app:2.10.0"
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp", UID: "webapp-uid"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cr.Status.Migrations) != len(migrations) {
+		t.Fatalf("expected every migration to be recorded, got %v", cr.Status.Migrations)
+	}
+
+	// the route migration ran for the current route name already
+	cluster.objects["Route/solution-explorer"] = legacyRoute("solution-explorer", "webapp-uid")
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("Route", "solution-explorer") == nil {
+		t.Fatalf("expected the recorded route migration not to run again")
+	}
+
+	// the image migration is recorded once the workload was provisioned
+	recorded(recorder)
+	wh.defaultImage = "quay.io/integreatly/tutorial-web-app:2.11.0"
+	cluster.patchErr = fmt.Errorf("admission denied")
+	if _, err := wh.Reconcile(context.TODO(), cr); err == nil {
+		t.Fatalf("expected the provisioning to fail")
+	}
+	for _, e := range recorded(recorder) {
+		if strings.HasPrefix(e, "Normal ImageMigrated") {
+			t.Fatalf("expected no migration event before the objects were provisioned, got %q", e)
+		}
+	}
+	if m := migrationRecord(t, cr.Status, 1); m.Message != "" || m.Target != "quay.io/integreatly/tutorial-web-app:2.10.0" {
+		t.Fatalf("expected the image migration not to be recorded, got %v", m)
+	}
+
+	cluster.patchErr = nil
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := migrationRecord(t, cr.Status, 1); m.Message != "migrated from version 2.10.0 to 2.11.0" {
+		t.Fatalf("expected the image migration to be recorded, got %v", m)
+	}
+}
+
+func TestMigrateEnvVars(t *testing.T) {
+	desired := &appsv1.DeploymentConfig{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig"},
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"},
+		Spec: appsv1.DeploymentConfigSpec{Template: &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "tutorial-web-app",
+			Env:  []corev1.EnvVar{{Name: "OPENSHIFT_HOST", Value: "openshift.example.com"}},
+		}}}}},
+	}
+	env := func(names ...string) []interface{} {
+		vars := []interface{}{}
+		for _, name := range names {
+			vars = append(vars, map[string]interface{}{"name": name, "value": "1"})
+		}
+		return vars
+	}
+	liveDC := func(lastApplied string, names ...string) *unstructured.Unstructured {
+		dc := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps.openshift.io/v1",
+			"kind":       "DeploymentConfig",
+			"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "tutorial-web-app", "env": env(names...)}},
+			}}},
+		}}
+		dc.SetName("tutorial-web-app")
+		if lastApplied != "" {
+			dc.SetAnnotations(map[string]string{lastAppliedAnnotation: lastApplied})
+		}
+		return dc
+	}
+
+	cases := []struct {
+		Name            string
+		Live            *unstructured.Unstructured
+		ExpectedMessage string
+		ExpectedEnv     []string
+	}{
+		{
+			Name:            "Remove the variables of the 1.x operator",
+			Live:            liveDC("", "OPENSHIFT_HOST", "SSO_ROUTE", "UPGRADE_DATA", "CUSTOM"),
+			ExpectedMessage: "removed environment variables SSO_ROUTE, UPGRADE_DATA of DeploymentConfig tutorial-web-app",
+			ExpectedEnv:     []string{"CUSTOM", "OPENSHIFT_HOST"},
+		},
+		{
+			Name:        "Leave applied variables to the three-way merge",
+			Live:        liveDC(`{"apiVersion":"apps.openshift.io/v1","kind":"DeploymentConfig","spec":{"template":{"spec":{"containers":[{"name":"tutorial-web-app","env":[{"name":"SSO_ROUTE"}]}]}}}}`, "OPENSHIFT_HOST", "SSO_ROUTE"),
+			ExpectedEnv: []string{"OPENSHIFT_HOST"},
+		},
+		{
+			Name:        "Nothing to migrate",
+			Live:        liveDC("", "OPENSHIFT_HOST"),
+			ExpectedEnv: []string{"OPENSHIFT_HOST"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cluster := newFakeCluster()
+			cluster.objects["DeploymentConfig/tutorial-web-app"] = tc.Live.DeepCopy()
+			wh := backupHandler(cluster, &record.FakeRecorder{})
+			wl, err := findWorkload([]runtime.Object{desired.DeepCopy()}, "tutorial-web-app")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cr := &v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"}}
+			s := &migrationState{cr: cr, desired: wl, live: tc.Live}
+			msg, err := wh.migrateEnvVars(s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg != tc.ExpectedMessage {
+				t.Fatalf("expected message %q, got %q", tc.ExpectedMessage, msg)
+			}
+			if !reflect.DeepEqual(cluster.get("DeploymentConfig", "tutorial-web-app"), tc.Live) {
+				t.Fatalf("expected the migration to leave the removal to the patch of the workload")
+			}
+
+			if _, err := wh.applyObject(wl.object, cr, s.legacyEnv); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cluster.patches != 1 {
+				t.Fatalf("expected the workload to be patched once, got %d patches", cluster.patches)
+			}
+			names := []string{}
+			for name := range envVars(cluster.get("DeploymentConfig", "tutorial-web-app")) {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.ExpectedEnv) {
+				t.Fatalf("expected env %v, got %v", tc.ExpectedEnv, names)
+			}
+		})
+	}
+}
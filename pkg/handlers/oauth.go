@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// oauthClientEnv is the environment variable the web app reads the id of its OAuthClient from
+	oauthClientEnv = "OPENSHIFT_OAUTHCLIENT_ID"
+	// oauthClientOwnerAnnotation holds the namespace and the name of the WebApp an OAuthClient is managed for.
+	// OAuthClients are cluster scoped and can not be owned by a WebApp.
+	oauthClientOwnerAnnotation = "integreatly.org/webapp"
+	// legacyOAuthClientID was the default of the OPENSHIFT_OAUTHCLIENT_ID parameter before it was derived from
+	// the WebApp, the OAuthClient was applied by hand
+	legacyOAuthClientID = "tutorial-web-app"
+)
+
+// reconcileOAuthClient creates the OAuthClient the web app authenticates users with and keeps its redirect URIs
+// on the URL of the route. An OAuthClient that was applied by hand is adopted, one managed for another WebApp
+// is left alone.
+func (h *AppHandler) reconcileOAuthClient(cr *v1alpha1.WebApp, objects []runtime.Object) error {
+	name := oauthClientID(objects)
+	if previous := cr.Status.OAuthClient; previous != "" && previous != name {
+		if err := h.deleteOAuthClient(cr, previous); err != nil {
+			return err
+		}
+		cr.Status.OAuthClient = ""
+	}
+	if name == "" {
+		return nil
+	}
+
+	redirectURIs, err := h.redirectURIs(cr)
+	if err != nil {
+		return err
+	}
+	if len(redirectURIs) == 0 {
+		cr.Status.SetCondition(v1alpha1.OAuthClientReady, corev1.ConditionUnknown, v1alpha1.ReasonRouteHostPending, fmt.Sprintf("waiting for the host of Route %s", routeNameForCR(cr)), cr.Generation)
+		return nil
+	}
+
+	client, _, err := h.dynamicResourceClientFactory("oauth.openshift.io/v1", "OAuthClient", "")
+	if err != nil {
+		return err
+	}
+	live, err := client.Get(name, metav1.GetOptions{})
+	if errors2.IsForbidden(err) {
+		// installs limited to a namespace can not manage cluster scoped objects, the OAuthClient is left to the admin
+		cr.Status.SetCondition(v1alpha1.OAuthClientReady, corev1.ConditionFalse, v1alpha1.ReasonOAuthClientFailed, fmt.Sprintf("the operator is not allowed to manage OAuthClient %s: %v", name, err), cr.Generation)
+		return nil
+	}
+	if errors2.IsNotFound(err) {
+		secret, err := generateSecret()
+		if err != nil {
+			return err
+		}
+		oauthClient := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion":   "oauth.openshift.io/v1",
+			"kind":         "OAuthClient",
+			"secret":       secret,
+			"grantMethod":  "auto",
+			"redirectURIs": redirectURIs,
+		}}
+		oauthClient.SetName(name)
+		oauthClient.SetAnnotations(map[string]string{oauthClientOwnerAnnotation: webAppKey(cr)})
+		if _, err := client.Create(oauthClient); err != nil {
+			return fmt.Errorf("failed to create OAuthClient %s: %v", name, err)
+		}
+		h.recorder.Eventf(cr, corev1.EventTypeNormal, eventCreated, "created OAuthClient %s", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to get OAuthClient %s: %v", name, err)
+	} else {
+		if owner := live.GetAnnotations()[oauthClientOwnerAnnotation]; owner != "" && owner != webAppKey(cr) {
+			return fmt.Errorf("OAuthClient %s is managed for WebApp %s, set a different %s", name, owner, oauthClientEnv)
+		}
+		updated := live.DeepCopy()
+		annotations := updated.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[oauthClientOwnerAnnotation] = webAppKey(cr)
+		updated.SetAnnotations(annotations)
+		updated.Object["redirectURIs"] = redirectURIs
+		if secret, _, _ := unstructured.NestedString(updated.Object, "secret"); secret == "" {
+			if updated.Object["secret"], err = generateSecret(); err != nil {
+				return err
+			}
+		}
+		if grantMethod, _, _ := unstructured.NestedString(updated.Object, "grantMethod"); grantMethod == "" {
+			updated.Object["grantMethod"] = "auto"
+		}
+		if !reflect.DeepEqual(live.Object, updated.Object) {
+			if _, err := client.Update(updated); err != nil {
+				return fmt.Errorf("failed to update OAuthClient %s: %v", name, err)
+			}
+		}
+	}
+
+	cr.Status.OAuthClient = name
+	cr.Status.SetCondition(v1alpha1.OAuthClientReady, corev1.ConditionTrue, v1alpha1.ReasonOAuthClientSynced, "", cr.Generation)
+	return nil
+}
+
+// deleteOAuthClient deletes an OAuthClient managed for the WebApp
+func (h *AppHandler) deleteOAuthClient(cr *v1alpha1.WebApp, name string) error {
+	client, _, err := h.dynamicResourceClientFactory("oauth.openshift.io/v1", "OAuthClient", "")
+	if err != nil {
+		return err
+	}
+	live, err := client.Get(name, metav1.GetOptions{})
+	if errors2.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get OAuthClient %s: %v", name, err)
+	}
+	if live.GetAnnotations()[oauthClientOwnerAnnotation] != webAppKey(cr) {
+		return nil
+	}
+	if err := client.Delete(name, &metav1.DeleteOptions{}); err != nil && !errors2.IsNotFound(err) {
+		return fmt.Errorf("failed to delete OAuthClient %s: %v", name, err)
+	}
+	return nil
+}
+
+// redirectURIs returns the URLs the route serves the web app at, none until the router generated the host of
+// a route that does not set one. A route that allows plain HTTP next to TLS serves both.
+func (h *AppHandler) redirectURIs(cr *v1alpha1.WebApp) ([]interface{}, error) {
+	route := &routev1.Route{}
+	if err := h.getLive("route.openshift.io/v1", "Route", cr.Namespace, routeNameForCR(cr), route); err != nil {
+		return nil, fmt.Errorf("failed to get Route %s: %v", routeNameForCR(cr), err)
+	}
+	host := route.Spec.Host
+	for _, ingress := range route.Status.Ingress {
+		if host != "" {
+			break
+		}
+		host = ingress.Host
+	}
+	if host == "" {
+		return nil, nil
+	}
+	uris := []interface{}{routeURL(route, host)}
+	if tls := route.Spec.TLS; tls != nil && tls.InsecureEdgeTerminationPolicy == routev1.InsecureEdgeTerminationPolicyAllow {
+		uris = append(uris, "http://"+host+route.Spec.Path)
+	}
+	return uris, nil
+}
+
+// oauthClientIDForCR is the default of the OPENSHIFT_OAUTHCLIENT_ID parameter, unique for each WebApp since
+// OAuthClients are cluster scoped. A WebApp keeps the OAuthClient that is managed for it already, and a WebApp
+// that was provisioned with the legacy default keeps the legacy OAuthClient while it exists.
+func (h *AppHandler) oauthClientIDForCR(cr *v1alpha1.WebApp) (string, error) {
+	if cr.Status.OAuthClient != "" {
+		return cr.Status.OAuthClient, nil
+	}
+	if isProvisioned(cr) {
+		legacy, err := h.hasLegacyOAuthClient(cr)
+		if err != nil {
+			return "", err
+		}
+		if legacy {
+			return legacyOAuthClientID, nil
+		}
+	}
+	return cr.Namespace + "-" + cr.Name, nil
+}
+
+// hasLegacyOAuthClient reports whether the OAuthClient of the legacy default exists and is not managed for
+// another WebApp. An operator that is not allowed to read OAuthClients uses the default of the WebApp.
+func (h *AppHandler) hasLegacyOAuthClient(cr *v1alpha1.WebApp) (bool, error) {
+	client, _, err := h.dynamicResourceClientFactory("oauth.openshift.io/v1", "OAuthClient", "")
+	if err != nil {
+		return false, err
+	}
+	live, err := client.Get(legacyOAuthClientID, metav1.GetOptions{})
+	if errors2.IsNotFound(err) || errors2.IsForbidden(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get OAuthClient %s: %v", legacyOAuthClientID, err)
+	}
+	owner := live.GetAnnotations()[oauthClientOwnerAnnotation]
+	return owner == "" || owner == webAppKey(cr), nil
+}
+
+// oauthClientID returns the id of the OAuthClient the web app was rendered with, an id that is read from a
+// secret is not managed
+func oauthClientID(objects []runtime.Object) string {
+	wl, err := findWorkload(objects, workloadName)
+	if err != nil || len(wl.template.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, env := range wl.template.Spec.Containers[0].Env {
+		if env.Name == oauthClientEnv {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+func webAppKey(cr *v1alpha1.WebApp) string {
+	return cr.Namespace + "/" + cr.Name
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate the OAuthClient secret: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
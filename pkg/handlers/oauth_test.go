@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+)
+
+func oauthClient(name, owner, secret string, redirectURIs ...interface{}) *unstructured.Unstructured {
+	client := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion":   "oauth.openshift.io/v1",
+		"kind":         "OAuthClient",
+		"redirectURIs": redirectURIs,
+	}}
+	client.SetName(name)
+	if secret != "" {
+		client.Object["secret"] = secret
+	}
+	if owner != "" {
+		client.SetAnnotations(map[string]string{oauthClientOwnerAnnotation: owner})
+	}
+	return client
+}
+
+// forbiddenOAuthClients rejects every request for an OAuthClient like a cluster without the ClusterRole
+type forbiddenOAuthClients struct {
+	*fakeResourceClient
+}
+
+func (f *forbiddenOAuthClients) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, errors2.NewForbidden(schema.GroupResource{Group: "oauth.openshift.io", Resource: "oauthclients"}, name, nil)
+}
+
+func TestReconcile_OAuthClient(t *testing.T) {
+	cases := []struct {
+		Name     string
+		ClientID string
+		Route    *v1alpha1.WebAppRoute
+		Prepare  func(*fakeCluster, *v1alpha1.WebApp)
+		Verify   func(*v1alpha1.WebApp, *fakeCluster, *testing.T)
+	}{
+		{
+			Name: "Create the OAuthClient of the web app",
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				client := cluster.get("OAuthClient", "webapp-tutorial-web-app")
+				if client == nil {
+					t.Fatalf("expected OAuthClient webapp-tutorial-web-app to be created, got %v", cluster.objects)
+				}
+				if uris, _, _ := unstructured.NestedStringSlice(client.Object, "redirectURIs"); len(uris) != 1 || uris[0] != "https://tutorial-web-app-webapp.apps.example.com" {
+					t.Fatalf("expected a redirect to the generated route host, got %v", uris)
+				}
+				if secret, _, _ := unstructured.NestedString(client.Object, "secret"); len(secret) < 32 {
+					t.Fatalf("expected a generated secret, got %q", secret)
+				}
+				if owner := client.GetAnnotations()[oauthClientOwnerAnnotation]; owner != "webapp/tutorial-web-app" {
+					t.Fatalf("expected the OAuthClient to be managed for the WebApp, got %q", owner)
+				}
+				if wa.Status.OAuthClient != "webapp-tutorial-web-app" || !wa.Status.IsConditionTrue(v1alpha1.OAuthClientReady) {
+					t.Fatalf("expected the OAuthClient to be ready, got %q %v", wa.Status.OAuthClient, wa.Status.Conditions)
+				}
+				dc := &appsv1.DeploymentConfig{}
+				liveObject(t, cluster, "DeploymentConfig", "tutorial-web-app", dc)
+				if id, _ := envValue(dc.Spec.Template.Spec.Containers[0].Env, oauthClientEnv); id.Value != "webapp-tutorial-web-app" {
+					t.Fatalf("expected the web app to use the OAuthClient of the WebApp, got %q", id.Value)
+				}
+			},
+		},
+		{
+			Name: "Redirect to the path of the route over HTTP and HTTPS",
+			Route: &v1alpha1.WebAppRoute{
+				Path: "/tutorials",
+				TLS:  &v1alpha1.WebAppRouteTLS{InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyAllow},
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				client := cluster.get("OAuthClient", "webapp-tutorial-web-app")
+				expected := []string{"https://tutorial-web-app-webapp.apps.example.com/tutorials", "http://tutorial-web-app-webapp.apps.example.com/tutorials"}
+				if uris, _, _ := unstructured.NestedStringSlice(client.Object, "redirectURIs"); !reflect.DeepEqual(uris, expected) {
+					t.Fatalf("expected redirects %v, got %v", expected, uris)
+				}
+			},
+		},
+		{
+			Name: "Keep the OAuthClient managed for the WebApp",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "webapp/tutorial-web-app", "s3cr3t")
+				wa.Status.OAuthClient = "tutorial-web-app"
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("OAuthClient", "webapp-tutorial-web-app") != nil || wa.Status.OAuthClient != "tutorial-web-app" {
+					t.Fatalf("expected the OAuthClient to be kept, got %q", wa.Status.OAuthClient)
+				}
+			},
+		},
+		{
+			Name: "Keep the legacy OAuthClient of a provisioned WebApp",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "", "s3cr3t", "https://")
+				wa.Status.Message = "OK"
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("OAuthClient", "webapp-tutorial-web-app") != nil || wa.Status.OAuthClient != "tutorial-web-app" {
+					t.Fatalf("expected the legacy OAuthClient to be kept, got %q", wa.Status.OAuthClient)
+				}
+				client := cluster.get("OAuthClient", "tutorial-web-app")
+				if owner := client.GetAnnotations()[oauthClientOwnerAnnotation]; owner != "webapp/tutorial-web-app" {
+					t.Fatalf("expected the legacy OAuthClient to be adopted, got %q", owner)
+				}
+				dc := &appsv1.DeploymentConfig{}
+				liveObject(t, cluster, "DeploymentConfig", "tutorial-web-app", dc)
+				if id, _ := envValue(dc.Spec.Template.Spec.Containers[0].Env, oauthClientEnv); id.Value != "tutorial-web-app" {
+					t.Fatalf("expected the web app to keep the legacy OAuthClient, got %q", id.Value)
+				}
+			},
+		},
+		{
+			Name: "Ignore the legacy OAuthClient for a new WebApp",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "", "s3cr3t", "https://")
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("OAuthClient", "webapp-tutorial-web-app") == nil || wa.Status.OAuthClient != "webapp-tutorial-web-app" {
+					t.Fatalf("expected the OAuthClient of the WebApp to be created, got %q", wa.Status.OAuthClient)
+				}
+			},
+		},
+		{
+			Name: "Ignore the legacy OAuthClient managed for another WebApp",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "other/tutorial-web-app", "s3cr3t", "https://other.apps.example.com")
+				wa.Status.Message = "OK"
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("OAuthClient", "webapp-tutorial-web-app") == nil || wa.Status.OAuthClient != "webapp-tutorial-web-app" {
+					t.Fatalf("expected the OAuthClient of the WebApp to be created, got %q", wa.Status.OAuthClient)
+				}
+			},
+		},
+		{
+			Name: "Adopt an OAuthClient applied by hand",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/webapp-tutorial-web-app"] = oauthClient("webapp-tutorial-web-app", "", "s3cr3t", "https://")
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				client := cluster.get("OAuthClient", "webapp-tutorial-web-app")
+				if uris, _, _ := unstructured.NestedStringSlice(client.Object, "redirectURIs"); len(uris) != 1 || uris[0] != "https://tutorial-web-app-webapp.apps.example.com" {
+					t.Fatalf("expected the redirect to be fixed, got %v", uris)
+				}
+				if secret, _, _ := unstructured.NestedString(client.Object, "secret"); secret != "s3cr3t" {
+					t.Fatalf("expected the secret to be kept, got %q", secret)
+				}
+				if owner := client.GetAnnotations()[oauthClientOwnerAnnotation]; owner != "webapp/tutorial-web-app" {
+					t.Fatalf("expected the OAuthClient to be adopted, got %q", owner)
+				}
+			},
+		},
+		{
+			Name:     "OAuthClient managed for another WebApp",
+			ClientID: "tutorial-web-app",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "other/tutorial-web-app", "s3cr3t", "https://other.apps.example.com")
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				c := wa.Status.GetCondition(v1alpha1.OAuthClientReady)
+				if c == nil || c.Status != corev1.ConditionFalse || c.Reason != v1alpha1.ReasonOAuthClientFailed {
+					t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.OAuthClientReady, v1alpha1.ReasonOAuthClientFailed, c)
+				}
+				client := cluster.get("OAuthClient", "tutorial-web-app")
+				if uris, _, _ := unstructured.NestedStringSlice(client.Object, "redirectURIs"); uris[0] != "https://other.apps.example.com" {
+					t.Fatalf("expected the OAuthClient of the other WebApp to be left alone, got %v", uris)
+				}
+			},
+		},
+		{
+			Name:     "Replace the OAuthClient when its id changes",
+			ClientID: "workshop",
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "webapp/tutorial-web-app", "s3cr3t")
+				wa.Status.OAuthClient = "tutorial-web-app"
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("OAuthClient", "tutorial-web-app") != nil {
+					t.Fatalf("expected the previous OAuthClient to be deleted")
+				}
+				if cluster.get("OAuthClient", "workshop") == nil || wa.Status.OAuthClient != "workshop" {
+					t.Fatalf("expected OAuthClient workshop to be created, got %q", wa.Status.OAuthClient)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cluster := newFakeCluster()
+			wh := backupHandler(cluster, &record.FakeRecorder{})
+			cr := &v1alpha1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+				Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+			}
+			if tc.ClientID != "" {
+				cr.Spec.Template.Parameters = map[string]string{oauthClientEnv: tc.ClientID}
+			}
+			cr.Spec.Route = tc.Route
+			if tc.Prepare != nil {
+				tc.Prepare(cluster, cr)
+			}
+			wh.Reconcile(context.TODO(), cr)
+			tc.Verify(cr, cluster, t)
+		})
+	}
+}
+
+func TestReconcile_OAuthClientForbidden(t *testing.T) {
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	wh.dynamicResourceClientFactory = func(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error) {
+		if kind == "OAuthClient" {
+			return &forbiddenOAuthClients{&fakeResourceClient{cluster: cluster, kind: kind}}, "", nil
+		}
+		return cluster.clientFactory(apiVersion, kind, namespace)
+	}
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("expected a namespaced install to reconcile without the OAuthClient, got %v", err)
+	}
+	c := cr.Status.GetCondition(v1alpha1.OAuthClientReady)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != v1alpha1.ReasonOAuthClientFailed {
+		t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.OAuthClientReady, v1alpha1.ReasonOAuthClientFailed, c)
+	}
+	if cr.Status.Phase != v1alpha1.PhaseReady {
+		t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReady, cr.Status.Phase)
+	}
+}
+
+func TestDelete_OAuthClient(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.objects["OAuthClient/tutorial-web-app"] = oauthClient("tutorial-web-app", "webapp/tutorial-web-app", "s3cr3t")
+	cluster.objects["OAuthClient/other"] = oauthClient("other", "other/tutorial-web-app", "s3cr3t")
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+
+	for _, name := range []string{"tutorial-web-app", "other"} {
+		cr := &v1alpha1.WebApp{
+			ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp", Finalizers: []string{v1alpha1.WebAppFinalizer}},
+			Status:     v1alpha1.WebAppStatus{OAuthClient: name},
+		}
+		if err := wh.Delete(cr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if cluster.get("OAuthClient", "tutorial-web-app") != nil {
+		t.Fatalf("expected the OAuthClient of the WebApp to be deleted")
+	}
+	if cluster.get("OAuthClient", "other") == nil {
+		t.Fatalf("expected the OAuthClient of another WebApp to be kept")
+	}
+}
+
+var _ dynamic.ResourceInterface = &forbiddenOAuthClients{}
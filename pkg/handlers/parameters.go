@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// parametersHashAnnotation is set on the pod template of the web app when parameters are referenced, so
+// changes to the referenced Secrets and ConfigMaps roll out the web app. It is a hash of the resource
+// versions of the references, a hash of the values would expose short secret values to a brute force.
+const parametersHashAnnotation = "integreatly.org/parameters-hash"
+
+// templateParameters are the parameters of a WebApp with the references to Secrets and ConfigMaps resolved
+type templateParameters struct {
+	values map[string]string
+	// secretRefs are the parameters set from Secrets, by parameter name
+	secretRefs map[string]*corev1.SecretKeySelector
+	// hash of the versions of the referenced objects, empty when no parameter is referenced
+	hash string
+}
+
+func (h *AppHandler) resolveParameters(cr *v1alpha1.WebApp) (*templateParameters, error) {
+	params := &templateParameters{
+		values:     map[string]string{},
+		secretRefs: map[string]*corev1.SecretKeySelector{},
+	}
+	for k, v := range cr.Spec.Template.Parameters {
+		params.values[k] = v
+	}
+
+	referenced := make([]string, 0, len(cr.Spec.Template.ParametersFrom))
+	for _, p := range cr.Spec.Template.ParametersFrom {
+		if _, ok := params.values[p.Name]; ok {
+			return nil, fmt.Errorf("parameter %s is set in both parameters and parametersFrom", p.Name)
+		}
+		value, version, found, err := h.resolveParameter(cr.Namespace, p)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		params.values[p.Name] = value
+		if p.ValueFrom.SecretKeyRef != nil {
+			params.secretRefs[p.Name] = p.ValueFrom.SecretKeyRef
+		}
+		referenced = append(referenced, p.Name+"="+version)
+	}
+	if _, ok := params.values[oauthClientEnv]; !ok {
+		id, err := h.oauthClientIDForCR(cr)
+		if err != nil {
+			return nil, err
+		}
+		params.values[oauthClientEnv] = id
+	}
+
+	if len(referenced) > 0 {
+		sort.Strings(referenced)
+		sum := sha256.New()
+		for _, r := range referenced {
+			sum.Write([]byte(r))
+			sum.Write([]byte{0})
+		}
+		params.hash = hex.EncodeToString(sum.Sum(nil))
+	}
+	return params, nil
+}
+
+// resolveParameter returns the value of a referenced parameter and the version of the object it is read from,
+// missing optional references are not found
+func (h *AppHandler) resolveParameter(ns string, p v1alpha1.TemplateParameterSource) (value string, version string, found bool, err error) {
+	switch {
+	case p.ValueFrom.SecretKeyRef != nil && p.ValueFrom.ConfigMapKeyRef != nil:
+		return "", "", false, fmt.Errorf("parameter %s must reference either a secret or a config map", p.Name)
+	case p.ValueFrom.SecretKeyRef != nil:
+		ref := p.ValueFrom.SecretKeyRef
+		secret, err := h.osClient.GetSecret(ns, ref.Name)
+		if errors2.IsNotFound(err) && isOptional(ref.Optional) {
+			return "", "", false, nil
+		}
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to get secret %s for parameter %s: %v", ref.Name, p.Name, err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			if isOptional(ref.Optional) {
+				return "", "", false, nil
+			}
+			return "", "", false, fmt.Errorf("key %s not found in secret %s for parameter %s", ref.Key, ref.Name, p.Name)
+		}
+		return string(value), objectVersion("secret", &secret.ObjectMeta), true, nil
+	case p.ValueFrom.ConfigMapKeyRef != nil:
+		ref := p.ValueFrom.ConfigMapKeyRef
+		cm, err := h.osClient.GetConfigMap(ns, ref.Name)
+		if errors2.IsNotFound(err) && isOptional(ref.Optional) {
+			return "", "", false, nil
+		}
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to get config map %s for parameter %s: %v", ref.Name, p.Name, err)
+		}
+		value, ok := cm.Data[ref.Key]
+		if !ok {
+			if isOptional(ref.Optional) {
+				return "", "", false, nil
+			}
+			return "", "", false, fmt.Errorf("key %s not found in config map %s for parameter %s", ref.Key, ref.Name, p.Name)
+		}
+		return value, objectVersion("configmap", &cm.ObjectMeta), true, nil
+	}
+	return "", "", false, fmt.Errorf("parameter %s must reference a secret or a config map", p.Name)
+}
+
+// objectVersion identifies a version of an object, it changes when the object is changed or recreated
+func objectVersion(kind string, meta *metav1.ObjectMeta) string {
+	return kind + "/" + meta.Name + "/" + string(meta.UID) + "/" + meta.ResourceVersion
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// projectSecretParameters makes env vars named after parameters from Secrets reference the Secret, so the
+// value is not stored in the workload
+func projectSecretParameters(container corev1.Container, params *templateParameters) corev1.Container {
+	for i, env := range container.Env {
+		if ref, ok := params.secretRefs[env.Name]; ok {
+			container.Env[i].Value = ""
+			container.Env[i].ValueFrom = &corev1.EnvVarSource{SecretKeyRef: ref.DeepCopy()}
+		}
+	}
+	return container
+}
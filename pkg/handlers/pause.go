@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const pausedMessage = "reconciliation is paused by spec.paused, the objects of the web app are not changed"
+
+// reconcilePaused only reports the status of a paused WebApp. Its objects are left as they are, so they can be
+// edited by hand, until spec.paused is unset.
+func (h *AppHandler) reconcilePaused(cr *v1alpha1.WebApp) (reconcile.Result, error) {
+	if !cr.Status.IsConditionTrue(v1alpha1.Paused) {
+		logrus.Infof("Reconciliation of WebApp %s/%s is paused", cr.Namespace, cr.Name)
+		h.recorder.Event(cr, corev1.EventTypeNormal, eventPaused, pausedMessage)
+	}
+	cr.Status.SetCondition(v1alpha1.Paused, corev1.ConditionTrue, v1alpha1.ReasonPaused, pausedMessage, cr.Generation)
+	h.observeStorage(cr)
+	h.observeBackup(cr)
+
+	ready, err := h.setReadyStatus(cr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !ready {
+		return reconcile.Result{RequeueAfter: readinessRequeue}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// resume records that a paused WebApp is reconciled again
+func (h *AppHandler) resume(cr *v1alpha1.WebApp) {
+	if !cr.Status.IsConditionTrue(v1alpha1.Paused) {
+		return
+	}
+	logrus.Infof("Reconciliation of WebApp %s/%s is resumed", cr.Namespace, cr.Name)
+	h.recorder.Event(cr, corev1.EventTypeNormal, eventResumed, "reconciliation is resumed")
+	cr.Status.SetCondition(v1alpha1.Paused, corev1.ConditionFalse, v1alpha1.ReasonResumed, "", cr.Generation)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func setImage(cluster *fakeCluster, image string) {
+	dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+	containers, _, _ := unstructured.NestedSlice(dc.Object, "spec", "template", "spec", "containers")
+	containers[0].(map[string]interface{})["image"] = image
+	unstructured.SetNestedSlice(dc.Object, containers, "spec", "template", "spec", "containers")
+}
+
+func liveImage(cluster *fakeCluster) string {
+	containers, _, _ := unstructured.NestedSlice(cluster.get("DeploymentConfig", "tutorial-web-app").Object, "spec", "template", "spec", "containers")
+	image, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "image")
+	return image
+}
+
+func TestReconcile_Paused(t *testing.T) {
+	cluster := newFakeCluster()
+	recorder := record.NewFakeRecorder(20)
+	wh := backupHandler(cluster, recorder)
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorded(recorder)
+
+	// the DeploymentConfig is edited by hand while the WebApp is paused
+	cr.Spec.Paused = true
+	setImage(cluster, "quay.io/integreatly/tutorial-web-app:debug")
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image := liveImage(cluster); image != "quay.io/integreatly/tutorial-web-app:debug" {
+		t.Fatalf("expected the image edited by hand to be kept, got %s", image)
+	}
+	c := cr.Status.GetCondition(v1alpha1.Paused)
+	if c == nil || c.Status != corev1.ConditionTrue || c.Reason != v1alpha1.ReasonPaused {
+		t.Fatalf("expected condition %s to be true with reason %s, got %v", v1alpha1.Paused, v1alpha1.ReasonPaused, c)
+	}
+	if cr.Status.Phase != v1alpha1.PhaseReady || cr.Status.URL == "" {
+		t.Fatalf("expected the readiness of a paused web app to be reported, got phase %s and url %q", cr.Status.Phase, cr.Status.URL)
+	}
+	expectEvents(t, recorder, "Normal Paused "+pausedMessage)
+
+	// a paused WebApp is planned as if it was resumed
+	cr.Annotations = map[string]string{dryRunAnnotation: "true"}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plannedChange(cr.Status.Plan, v1alpha1.PlanActionPatch, "DeploymentConfig", "tutorial-web-app") == nil {
+		t.Fatalf("expected the image to be planned as a patch of the DeploymentConfig, got %v", cr.Status.Plan.Changes)
+	}
+	delete(cr.Annotations, dryRunAnnotation)
+
+	cr.Spec.Paused = false
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image := liveImage(cluster); image != WebAppImage {
+		t.Fatalf("expected the image to be reconciled once resumed, got %s", image)
+	}
+	c = cr.Status.GetCondition(v1alpha1.Paused)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != v1alpha1.ReasonResumed {
+		t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.Paused, v1alpha1.ReasonResumed, c)
+	}
+	found := false
+	for _, e := range recorded(recorder) {
+		found = found || e == "Normal Resumed reconciliation is resumed"
+	}
+	if !found {
+		t.Fatalf("expected a Resumed event")
+	}
+}
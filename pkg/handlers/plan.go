@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/sdk"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// dryRunAnnotation set to "true" on a WebApp makes the operator plan the changes of a reconcile instead of
+	// making them
+	dryRunAnnotation = "integreatly.org/dry-run"
+	// redacted replaces secrets in the planned changes
+	redacted = "REDACTED"
+)
+
+func isDryRun(cr *v1alpha1.WebApp) bool {
+	return cr.GetAnnotations()[dryRunAnnotation] == "true"
+}
+
+// plan reconciles a copy of the WebApp against a cluster that only records the changes and writes them to the
+// status of the WebApp. Everything is read from the cluster, nothing is written to it and no events are recorded.
+func (h *AppHandler) plan(ctx context.Context, cr *v1alpha1.WebApp) (reconcile.Result, error) {
+	p := &planner{factory: h.dynamicResourceClientFactory, overlay: map[string]*unstructured.Unstructured{}}
+	dry := *h
+	dry.dynamicResourceClientFactory = p.clientFactory
+	dry.recorder = discardRecorder{}
+	dry.metrics = nil
+	dry.sdkCruder = discardCruder{}
+
+	preview := cr.DeepCopy()
+	delete(preview.Annotations, dryRunAnnotation)
+	// a paused WebApp is planned as if it was resumed
+	preview.Spec.Paused = false
+	_, err := dry.reconcileWebApp(ctx, preview)
+
+	cr.Status.Plan = &v1alpha1.WebAppPlan{ObservedGeneration: cr.Generation, Time: metav1.Now(), Changes: p.changes}
+	if err != nil {
+		cr.Status.Plan.Error = err.Error()
+	}
+	return reconcile.Result{}, h.sdkCruder.UpdateStatus(cr)
+}
+
+// planner hands out resource clients that read from the cluster and record the changes instead of making them.
+// Created, updated and deleted objects are kept in an overlay, so later steps of the reconcile read them back.
+type planner struct {
+	factory ClientFactory
+	overlay map[string]*unstructured.Unstructured
+	changes []v1alpha1.PlannedChange
+}
+
+func (p *planner) clientFactory(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error) {
+	client, resource, err := p.factory(apiVersion, kind, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	return &plannedClient{ResourceInterface: client, planner: p, apiVersion: apiVersion, kind: kind, namespace: namespace}, resource, nil
+}
+
+func (p *planner) record(action v1alpha1.PlanAction, apiVersion, kind, name string, diff map[string]interface{}) {
+	change := v1alpha1.PlannedChange{Action: action, APIVersion: apiVersion, Kind: kind, Name: name}
+	if len(diff) > 0 {
+		redact(kind, diff)
+		if data, err := json.Marshal(diff); err == nil {
+			change.Diff = string(data)
+		}
+	}
+	p.changes = append(p.changes, change)
+}
+
+// plannedClient embeds the client of the cluster for the reads, the writes are recorded by the planner
+type plannedClient struct {
+	dynamic.ResourceInterface
+	planner    *planner
+	apiVersion string
+	kind       string
+	namespace  string
+}
+
+func (c *plannedClient) key(name string) string {
+	return c.apiVersion + "/" + c.kind + "/" + c.namespace + "/" + name
+}
+
+func (c *plannedClient) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if obj, ok := c.planner.overlay[c.key(name)]; ok {
+		if obj == nil {
+			return nil, errors2.NewNotFound(schema.GroupResource{Resource: c.kind}, name)
+		}
+		return obj.DeepCopy(), nil
+	}
+	return c.ResourceInterface.Get(name, options, subresources...)
+}
+
+func (c *plannedClient) Create(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	c.planner.record(v1alpha1.PlanActionCreate, c.apiVersion, c.kind, obj.GetName(), obj.DeepCopy().Object)
+	c.planner.overlay[c.key(obj.GetName())] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (c *plannedClient) Update(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	live, err := c.Get(obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if diff := mergeDiff(live.Object, obj.Object); len(diff) > 0 {
+		c.planner.record(v1alpha1.PlanActionUpdate, c.apiVersion, c.kind, obj.GetName(), diff)
+	}
+	c.planner.overlay[c.key(obj.GetName())] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (c *plannedClient) UpdateStatus(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return obj, nil
+}
+
+func (c *plannedClient) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*unstructured.Unstructured, error) {
+	live, err := c.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	c.planner.record(v1alpha1.PlanActionPatch, c.apiVersion, c.kind, name, patch)
+	return live, nil
+}
+
+func (c *plannedClient) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	if _, err := c.Get(name, metav1.GetOptions{}); err != nil {
+		return err
+	}
+	c.planner.record(v1alpha1.PlanActionDelete, c.apiVersion, c.kind, name, nil)
+	c.planner.overlay[c.key(name)] = nil
+	return nil
+}
+
+func (c *plannedClient) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return fmt.Errorf("deleting a collection of %s can not be planned", c.kind)
+}
+
+// mergeDiff returns the fields of modified that differ from original, fields missing from modified are null
+func mergeDiff(original, modified map[string]interface{}) map[string]interface{} {
+	diff := map[string]interface{}{}
+	for key, value := range modified {
+		previous, ok := original[key]
+		if ok && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		previousMap, isMap := previous.(map[string]interface{})
+		valueMap, bothMaps := value.(map[string]interface{})
+		if isMap && bothMaps {
+			if d := mergeDiff(previousMap, valueMap); len(d) > 0 {
+				diff[key] = d
+			}
+			continue
+		}
+		diff[key] = value
+	}
+	for key := range original {
+		if _, ok := modified[key]; !ok {
+			diff[key] = nil
+		}
+	}
+	return diff
+}
+
+// redact removes the last applied configuration, which repeats the whole object, the status and the secrets
+// from a planned change
+func redact(kind string, diff map[string]interface{}) {
+	delete(diff, "status")
+	if annotations, ok, _ := unstructured.NestedMap(diff, "metadata", "annotations"); ok {
+		delete(annotations, lastAppliedAnnotation)
+		if len(annotations) == 0 {
+			unstructured.RemoveNestedField(diff, "metadata", "annotations")
+		} else {
+			unstructured.SetNestedMap(diff, annotations, "metadata", "annotations")
+		}
+	}
+	var secrets [][]string
+	switch kind {
+	case "Route":
+		secrets = [][]string{{"spec", "tls", "key"}}
+	case "OAuthClient":
+		secrets = [][]string{{"secret"}}
+	}
+	if kind == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			if data, ok := diff[field].(map[string]interface{}); ok {
+				for key, value := range data {
+					if value != nil {
+						data[key] = redacted
+					}
+				}
+			}
+		}
+	}
+	for _, path := range secrets {
+		if value, ok, _ := unstructured.NestedFieldNoCopy(diff, path...); ok && value != nil {
+			unstructured.SetNestedField(diff, redacted, path...)
+		}
+	}
+}
+
+// discardRecorder drops the events of the WebApp copy a plan is computed with
+type discardRecorder struct{}
+
+func (discardRecorder) Event(object runtime.Object, eventType, reason, message string) {}
+
+func (discardRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+}
+
+func (discardRecorder) PastEventf(object runtime.Object, timestamp metav1.Time, eventType, reason, messageFmt string, args ...interface{}) {
+}
+
+func (discardRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+}
+
+// discardCruder drops the updates of the WebApp copy a plan is computed with
+type discardCruder struct{}
+
+func (discardCruder) Create(object sdk.Object) error                           { return nil }
+func (discardCruder) Update(object sdk.Object) error                           { return nil }
+func (discardCruder) UpdateStatus(object sdk.Object) error                     { return nil }
+func (discardCruder) Delete(object sdk.Object, opts ...sdk.DeleteOption) error { return nil }
+func (discardCruder) Get(object sdk.Object, opts ...sdk.GetOption) error       { return nil }
+func (discardCruder) List(namespace string, into sdk.Object, opts ...sdk.ListOption) error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func plannedChange(plan *v1alpha1.WebAppPlan, action v1alpha1.PlanAction, kind, name string) *v1alpha1.PlannedChange {
+	for i, c := range plan.Changes {
+		if c.Action == action && c.Kind == kind && c.Name == name {
+			return &plan.Changes[i]
+		}
+	}
+	return nil
+}
+
+func TestReconcile_DryRun(t *testing.T) {
+	cluster := newFakeCluster()
+	recorder := record.NewFakeRecorder(20)
+	wh := backupHandler(cluster, recorder)
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp", Annotations: map[string]string{dryRunAnnotation: "true"}},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cluster.objects) != 0 {
+		t.Fatalf("expected a dry run to leave the cluster alone, got %v", cluster.objects)
+	}
+	if events := recorded(recorder); len(events) != 0 {
+		t.Fatalf("expected a dry run to record no events, got %v", events)
+	}
+	if cr.HasFinalizer(v1alpha1.WebAppFinalizer) || cr.Status.Phase != "" {
+		t.Fatalf("expected a dry run to leave the WebApp alone, got finalizers %v and phase %q", cr.Finalizers, cr.Status.Phase)
+	}
+	plan := cr.Status.Plan
+	if plan == nil || plan.Error != "" {
+		t.Fatalf("expected a plan without error, got %v", plan)
+	}
+	for _, kind := range []string{"DeploymentConfig", "Service", "Route"} {
+		if plannedChange(plan, v1alpha1.PlanActionCreate, kind, "tutorial-web-app") == nil {
+			t.Fatalf("expected the %s to be planned for creation, got %v", kind, plan.Changes)
+		}
+	}
+
+	// once the annotation is removed the changes are made and the plan is dropped
+	delete(cr.Annotations, dryRunAnnotation)
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster.get("DeploymentConfig", "tutorial-web-app") == nil {
+		t.Fatalf("expected the DeploymentConfig to be provisioned")
+	}
+	if cr.Status.Plan != nil {
+		t.Fatalf("expected the plan to be dropped, got %v", cr.Status.Plan)
+	}
+}
+
+func TestReconcile_DryRunChanges(t *testing.T) {
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := cluster.get("DeploymentConfig", "tutorial-web-app").DeepCopy()
+
+	cr.Annotations = map[string]string{dryRunAnnotation: "true"}
+	cr.Spec.Template.Parameters = map[string]string{"OPENSHIFT_HOST": "openshift.example.com", routingSubdomain: "apps.example.com"}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cluster.get("DeploymentConfig", "tutorial-web-app"), before) || cluster.get("Route", "tutorial-web-app") == nil {
+		t.Fatalf("expected a dry run to leave the cluster alone")
+	}
+	if plannedChange(cr.Status.Plan, v1alpha1.PlanActionDelete, "Route", "tutorial-web-app") == nil {
+		t.Fatalf("expected the migration of the previous route to be planned, got %v", cr.Status.Plan.Changes)
+	}
+	patch := plannedChange(cr.Status.Plan, v1alpha1.PlanActionPatch, "DeploymentConfig", "tutorial-web-app")
+	if patch == nil || !strings.Contains(patch.Diff, "openshift.example.com") {
+		t.Fatalf("expected the new parameter to be planned as a patch of the DeploymentConfig, got %v", cr.Status.Plan.Changes)
+	}
+	if strings.Contains(patch.Diff, lastAppliedAnnotation) {
+		t.Fatalf("expected the last applied configuration to be left out of the plan, got %s", patch.Diff)
+	}
+	if plannedChange(cr.Status.Plan, v1alpha1.PlanActionPatch, "Service", "tutorial-web-app") != nil {
+		t.Fatalf("expected unchanged objects to be left out of the plan, got %v", cr.Status.Plan.Changes)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Kind     string
+		Diff     map[string]interface{}
+		Expected map[string]interface{}
+	}{
+		{
+			Name:     "Secret of an OAuthClient",
+			Kind:     "OAuthClient",
+			Diff:     map[string]interface{}{"secret": "s3cr3t", "redirectURIs": []interface{}{"https://"}},
+			Expected: map[string]interface{}{"secret": redacted, "redirectURIs": []interface{}{"https://"}},
+		},
+		{
+			Name:     "Key of a Route",
+			Kind:     "Route",
+			Diff:     map[string]interface{}{"spec": map[string]interface{}{"tls": map[string]interface{}{"termination": "edge", "key": "-----BEGIN"}}},
+			Expected: map[string]interface{}{"spec": map[string]interface{}{"tls": map[string]interface{}{"termination": "edge", "key": redacted}}},
+		},
+		{
+			Name:     "Removed key of a Route",
+			Kind:     "Route",
+			Diff:     map[string]interface{}{"spec": map[string]interface{}{"tls": map[string]interface{}{"key": nil}}},
+			Expected: map[string]interface{}{"spec": map[string]interface{}{"tls": map[string]interface{}{"key": nil}}},
+		},
+		{
+			Name: "Last applied configuration and status",
+			Kind: "Service",
+			Diff: map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{lastAppliedAnnotation: "{}"}},
+				"status":   map[string]interface{}{},
+			},
+			Expected: map[string]interface{}{"metadata": map[string]interface{}{}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			redact(tc.Kind, tc.Diff)
+			if !reflect.DeepEqual(tc.Diff, tc.Expected) {
+				t.Fatalf("expected %v, got %v", tc.Expected, tc.Diff)
+			}
+		})
+	}
+}
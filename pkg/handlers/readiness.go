@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	workloadName = "tutorial-web-app"
+
+	// reason of the Progressing condition of a DeploymentConfig whose latest version was rolled out
+	dcRolloutComplete = "NewReplicationControllerAvailable"
+	// reason of the Progressing condition of a DeploymentConfig or a Deployment whose rollout timed out
+	progressDeadlineExceeded = "ProgressDeadlineExceeded"
+	// deploymentLabel is set by OpenShift on the pods of a DeploymentConfig version
+	deploymentLabel = "deployment"
+)
+
+// check is the outcome of one readiness check, it is surfaced as a condition of the WebApp
+type check struct {
+	status  corev1.ConditionStatus
+	reason  string
+	message string
+}
+
+func passed(reason string) check {
+	return check{status: corev1.ConditionTrue, reason: reason}
+}
+
+func failed(reason, format string, args ...interface{}) check {
+	return check{status: corev1.ConditionFalse, reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+func (c check) ok() bool {
+	return c.status == corev1.ConditionTrue
+}
+
+// readiness is the observed state of a provisioned web app
+type readiness struct {
+	deployment check
+	endpoints  check
+	route      check
+	url        string
+}
+
+// ready reports whether the web app serves requests. Ingress controllers on Kubernetes do not all publish
+// the load balancer status, an Ingress that was not admitted yet does not block readiness there. A web app
+// scaled down to 0 replicas is ready as it is, it has no endpoints.
+func (r readiness) ready(platform openshift.Platform) check {
+	scaledDown := r.deployment.reason == v1alpha1.ReasonScaledDown
+	switch {
+	case !r.deployment.ok():
+		return failed(v1alpha1.ReasonDeploymentUnavailable, "%s", r.deployment.message)
+	case !r.endpoints.ok() && !scaledDown:
+		return failed(v1alpha1.ReasonNoEndpoints, "%s", r.endpoints.message)
+	case !r.route.ok() && platform != openshift.PlatformKubernetes:
+		return failed(v1alpha1.ReasonRouteNotAdmitted, "%s", r.route.message)
+	case scaledDown:
+		return r.deployment
+	}
+	return passed(v1alpha1.ReasonReady)
+}
+
+// checkReadiness observes the rollout of the workload, the readiness of the pods of its latest version,
+// the endpoints of the service and the admission of the route
+func (h *AppHandler) checkReadiness(cr *v1alpha1.WebApp) readiness {
+	r := readiness{
+		deployment: checkRestore(cr),
+		endpoints:  h.checkEndpoints(cr),
+	}
+	if r.deployment.ok() {
+		r.deployment = h.checkDeployment(cr)
+	}
+	if h.platform == openshift.PlatformKubernetes {
+		r.route, r.url = h.checkIngress(cr)
+	} else {
+		r.route, r.url = h.checkRoute(cr)
+	}
+	return r
+}
+
+// checkRestore checks that the restore of the volume claim completed, the web app is held at 0 replicas
+// until it did
+func checkRestore(cr *v1alpha1.WebApp) check {
+	restore := cr.Status.Restore
+	switch {
+	case restore == nil || restore.CompletionTime != nil:
+		return passed(v1alpha1.ReasonRolloutComplete)
+	case restore.Message != "":
+		return failed(v1alpha1.ReasonRestoreFailed, "%s", restore.Message)
+	}
+	return failed(v1alpha1.ReasonRestoreInProgress, "restoring the data of the web app with Job %s", restore.Job)
+}
+
+func (h *AppHandler) checkDeployment(cr *v1alpha1.WebApp) check {
+	var (
+		rollout  check
+		selector string
+		replicas int32
+	)
+	if h.platform == openshift.PlatformKubernetes {
+		deployment := &k8sappsv1.Deployment{}
+		if err := h.getLive("apps/v1", "Deployment", cr.Namespace, workloadName, deployment); err != nil {
+			return failed(v1alpha1.ReasonRolloutInProgress, "failed to get Deployment %s: %v", workloadName, err)
+		}
+		rollout, selector, replicas = deploymentRollout(deployment)
+	} else {
+		dc := &appsv1.DeploymentConfig{}
+		if err := h.getLive("apps.openshift.io/v1", "DeploymentConfig", cr.Namespace, workloadName, dc); err != nil {
+			return failed(v1alpha1.ReasonRolloutInProgress, "failed to get DeploymentConfig %s: %v", workloadName, err)
+		}
+		rollout, selector, replicas = dcRollout(dc)
+	}
+	if !rollout.ok() {
+		return rollout
+	}
+	if replicas == 0 {
+		return check{status: corev1.ConditionTrue, reason: v1alpha1.ReasonScaledDown, message: "scaled down to 0 replicas"}
+	}
+
+	pods, err := h.osClient.GetPods(cr.Namespace, selector)
+	if err != nil {
+		return failed(v1alpha1.ReasonPodsNotReady, "failed to list pods: %v", err)
+	}
+	ready := int32(0)
+	for i := range pods {
+		if isPodReady(&pods[i]) {
+			ready++
+		}
+	}
+	if ready < replicas {
+		return failed(v1alpha1.ReasonPodsNotReady, "%d of %d pods are ready", ready, replicas)
+	}
+	return rollout
+}
+
+// dcRollout checks that the latest version of a DeploymentConfig was rolled out, it returns the selector
+// of the pods of the latest version and the number of replicas
+func dcRollout(dc *appsv1.DeploymentConfig) (check, string, int32) {
+	if dc.Status.ObservedGeneration < dc.Generation || dc.Status.LatestVersion == 0 {
+		return failed(v1alpha1.ReasonRolloutInProgress, "DeploymentConfig %s has not been rolled out yet", dc.Name), "", 0
+	}
+	version := fmt.Sprintf("%s-%d", dc.Name, dc.Status.LatestVersion)
+	for _, c := range dc.Status.Conditions {
+		if c.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+		if c.Reason == progressDeadlineExceeded {
+			return failed(v1alpha1.ReasonRolloutFailed, "rollout of %s failed: %s", version, c.Message), "", 0
+		}
+		if c.Status != corev1.ConditionTrue || c.Reason != dcRolloutComplete {
+			return failed(v1alpha1.ReasonRolloutInProgress, "%s is being rolled out", version), "", 0
+		}
+	}
+
+	selector := labels.Set(dc.Spec.Selector)
+	pods := labels.Set{deploymentLabel: version}
+	return passed(v1alpha1.ReasonRolloutComplete), labels.Merge(selector, pods).String(), dc.Spec.Replicas
+}
+
+// deploymentRollout checks that all replicas of a Deployment run its current pod template, it returns the
+// selector of its pods and the number of replicas
+func deploymentRollout(deployment *k8sappsv1.Deployment) (check, string, int32) {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == k8sappsv1.DeploymentProgressing && c.Reason == progressDeadlineExceeded {
+			return failed(v1alpha1.ReasonRolloutFailed, "rollout of Deployment %s failed: %s", deployment.Name, c.Message), "", 0
+		}
+	}
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation || status.UpdatedReplicas < replicas || status.Replicas > status.UpdatedReplicas {
+		return failed(v1alpha1.ReasonRolloutInProgress, "Deployment %s is being rolled out, %d of %d replicas updated", deployment.Name, status.UpdatedReplicas, replicas), "", 0
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return failed(v1alpha1.ReasonRolloutFailed, "invalid selector of Deployment %s: %v", deployment.Name, err), "", 0
+	}
+	return passed(v1alpha1.ReasonRolloutComplete), selector.String(), replicas
+}
+
+// isPodReady reports whether a running pod and all of its containers passed their readiness probes
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (h *AppHandler) checkEndpoints(cr *v1alpha1.WebApp) check {
+	endpoints, err := h.osClient.GetEndpoints(cr.Namespace, serviceName)
+	if err != nil {
+		return failed(v1alpha1.ReasonNoEndpoints, "failed to get the endpoints of service %s: %v", serviceName, err)
+	}
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return passed(v1alpha1.ReasonReady)
+		}
+	}
+	return failed(v1alpha1.ReasonNoEndpoints, "service %s has no ready endpoints", serviceName)
+}
+
+// checkRoute checks that a router admitted the route and returns the URL it serves the web app at
+func (h *AppHandler) checkRoute(cr *v1alpha1.WebApp) (check, string) {
+	name := routeNameForCR(cr)
+	route := &routev1.Route{}
+	if err := h.getLive("route.openshift.io/v1", "Route", cr.Namespace, name, route); err != nil {
+		return check{status: corev1.ConditionUnknown, reason: v1alpha1.ReasonRouteCreated, message: fmt.Sprintf("failed to get Route %s: %v", name, err)}, ""
+	}
+	for _, ingress := range route.Status.Ingress {
+		for _, c := range ingress.Conditions {
+			if c.Type != routev1.RouteAdmitted {
+				continue
+			}
+			if c.Status == corev1.ConditionTrue {
+				return passed(v1alpha1.ReasonRouteAdmitted), routeURL(route, ingress.Host)
+			}
+			return failed(v1alpha1.ReasonRouteRejected, "Route %s was rejected by router %s: %s", name, ingress.RouterName, c.Message), ""
+		}
+	}
+	return check{status: corev1.ConditionUnknown, reason: v1alpha1.ReasonRouteCreated, message: fmt.Sprintf("Route %s has not been admitted yet", name)}, ""
+}
+
+// routeURL is the URL a route serves the web app at on one of its hosts, routes without TLS serve plain HTTP
+func routeURL(route *routev1.Route, host string) string {
+	if route.Spec.TLS == nil {
+		return "http://" + host + route.Spec.Path
+	}
+	return "https://" + host + route.Spec.Path
+}
+
+// checkIngress checks that an ingress controller published the address of the ingress, the URL is only
+// known when the ingress has a host
+func (h *AppHandler) checkIngress(cr *v1alpha1.WebApp) (check, string) {
+	name := routeNameForCR(cr)
+	ingress := &extv1beta1.Ingress{}
+	if err := h.getLive("extensions/v1beta1", "Ingress", cr.Namespace, name, ingress); err != nil {
+		return check{status: corev1.ConditionUnknown, reason: v1alpha1.ReasonRouteCreated, message: fmt.Sprintf("failed to get Ingress %s: %v", name, err)}, ""
+	}
+	url := ""
+	if len(ingress.Spec.Rules) > 0 && ingress.Spec.Rules[0].Host != "" {
+		url = "http://" + ingress.Spec.Rules[0].Host
+		if len(ingress.Spec.TLS) > 0 {
+			url = "https://" + ingress.Spec.Rules[0].Host
+		}
+		if paths := ingress.Spec.Rules[0].HTTP; paths != nil && len(paths.Paths) > 0 {
+			url += paths.Paths[0].Path
+		}
+	}
+	if len(ingress.Status.LoadBalancer.Ingress) == 0 {
+		return check{status: corev1.ConditionUnknown, reason: v1alpha1.ReasonRouteCreated, message: fmt.Sprintf("Ingress %s has no address yet", name)}, url
+	}
+	return passed(v1alpha1.ReasonRouteAdmitted), url
+}
+
+// getLive reads the live state of an object into a typed object
+func (h *AppHandler) getLive(apiVersion, kind, namespace, name string, into runtime.Object) error {
+	client, _, err := h.dynamicResourceClientFactory(apiVersion, kind, namespace)
+	if err != nil {
+		return err
+	}
+	live, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, into)
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/operator-framework/operator-sdk/pkg/sdk"
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+func (c *fakeCluster) put(t *testing.T, kind string, o runtime.Object) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		t.Fatalf("failed to convert %s: %v", kind, err)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	c.objects[kind+"/"+obj.GetName()] = obj
+}
+
+func rolledOutDC(version int64, progressing appsv1.DeploymentCondition) *appsv1.DeploymentConfig {
+	return &appsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Generation: 2},
+		Spec: appsv1.DeploymentConfigSpec{
+			Replicas: 1,
+			Selector: map[string]string{"app": "tutorial-web-app"},
+		},
+		Status: appsv1.DeploymentConfigStatus{
+			ObservedGeneration: 2,
+			LatestVersion:      version,
+			Conditions:         []appsv1.DeploymentCondition{progressing},
+		},
+	}
+}
+
+func admittedRoute(status corev1.ConditionStatus) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"},
+		Spec:       routev1.RouteSpec{TLS: &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}},
+		Status: routev1.RouteStatus{
+			Ingress: []routev1.RouteIngress{{
+				Host:       "tutorial-web-app-webapp.apps.example.com",
+				RouterName: "default",
+				Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: status, Message: "host already claimed"}},
+			}},
+		},
+	}
+}
+
+func readyPod(version string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "tutorial-web-app", deploymentLabel: version}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestCheckReadiness(t *testing.T) {
+	complete := appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: dcRolloutComplete}
+	cases := []struct {
+		Name             string
+		Platform         openshift.Platform
+		Objects          func(t *testing.T, cluster *fakeCluster)
+		Pods             []corev1.Pod
+		Endpoints        []corev1.EndpointSubset
+		Ready            string
+		Deployment       string
+		Route            string
+		ExpectedURL      string
+		ExpectedSelector string
+	}{
+		{
+			Name: "Ready",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, complete))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Pods:             []corev1.Pod{readyPod("tutorial-web-app-3")},
+			Endpoints:        []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:            v1alpha1.ReasonReady,
+			Deployment:       v1alpha1.ReasonRolloutComplete,
+			Route:            v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL:      "https://tutorial-web-app-webapp.apps.example.com",
+			ExpectedSelector: "app=tutorial-web-app,deployment=tutorial-web-app-3",
+		},
+		{
+			Name: "Latest version is being rolled out",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "ReplicationControllerUpdated"}))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Pods:        []corev1.Pod{readyPod("tutorial-web-app-2")},
+			Endpoints:   []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:       v1alpha1.ReasonDeploymentUnavailable,
+			Deployment:  v1alpha1.ReasonRolloutInProgress,
+			Route:       v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL: "https://tutorial-web-app-webapp.apps.example.com",
+		},
+		{
+			Name: "Rollout timed out",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: progressDeadlineExceeded}))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Ready:       v1alpha1.ReasonDeploymentUnavailable,
+			Deployment:  v1alpha1.ReasonRolloutFailed,
+			Route:       v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL: "https://tutorial-web-app-webapp.apps.example.com",
+		},
+		{
+			Name: "Containers not ready",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, complete))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Pods: []corev1.Pod{{Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+			}}},
+			Endpoints:   []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:       v1alpha1.ReasonDeploymentUnavailable,
+			Deployment:  v1alpha1.ReasonPodsNotReady,
+			Route:       v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL: "https://tutorial-web-app-webapp.apps.example.com",
+		},
+		{
+			Name: "Service without endpoints",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, complete))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Pods:        []corev1.Pod{readyPod("tutorial-web-app-3")},
+			Endpoints:   []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:       v1alpha1.ReasonNoEndpoints,
+			Deployment:  v1alpha1.ReasonRolloutComplete,
+			Route:       v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL: "https://tutorial-web-app-webapp.apps.example.com",
+		},
+		{
+			Name: "Scaled down to 0 replicas",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				dc := rolledOutDC(3, complete)
+				dc.Spec.Replicas = 0
+				cluster.put(t, "DeploymentConfig", dc)
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+			},
+			Ready:       v1alpha1.ReasonScaledDown,
+			Deployment:  v1alpha1.ReasonScaledDown,
+			Route:       v1alpha1.ReasonRouteAdmitted,
+			ExpectedURL: "https://tutorial-web-app-webapp.apps.example.com",
+		},
+		{
+			Name: "Route not admitted yet",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, complete))
+				cluster.put(t, "Route", &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"}})
+			},
+			Pods:       []corev1.Pod{readyPod("tutorial-web-app-3")},
+			Endpoints:  []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:      v1alpha1.ReasonRouteNotAdmitted,
+			Deployment: v1alpha1.ReasonRolloutComplete,
+			Route:      v1alpha1.ReasonRouteCreated,
+		},
+		{
+			Name: "Route rejected",
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				cluster.put(t, "DeploymentConfig", rolledOutDC(3, complete))
+				cluster.put(t, "Route", admittedRoute(corev1.ConditionFalse))
+			},
+			Pods:       []corev1.Pod{readyPod("tutorial-web-app-3")},
+			Endpoints:  []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:      v1alpha1.ReasonRouteNotAdmitted,
+			Deployment: v1alpha1.ReasonRolloutComplete,
+			Route:      v1alpha1.ReasonRouteRejected,
+		},
+		{
+			Name:     "Ingress without an address does not block readiness on Kubernetes",
+			Platform: openshift.PlatformKubernetes,
+			Objects: func(t *testing.T, cluster *fakeCluster) {
+				replicas := int32(1)
+				cluster.put(t, "Deployment", &k8sappsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"},
+					Spec: k8sappsv1.DeploymentSpec{
+						Replicas: &replicas,
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "tutorial-web-app"}},
+					},
+					Status: k8sappsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+				})
+				cluster.put(t, "Ingress", &extv1beta1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app"},
+					Spec: extv1beta1.IngressSpec{
+						Rules: []extv1beta1.IngressRule{{Host: "webapp.example.com"}},
+						TLS:   []extv1beta1.IngressTLS{{Hosts: []string{"webapp.example.com"}}},
+					},
+				})
+			},
+			Pods:             []corev1.Pod{readyPod("")},
+			Endpoints:        []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "172.1.0.3"}}}},
+			Ready:            v1alpha1.ReasonReady,
+			Deployment:       v1alpha1.ReasonRolloutComplete,
+			Route:            v1alpha1.ReasonRouteCreated,
+			ExpectedURL:      "https://webapp.example.com",
+			ExpectedSelector: "app=tutorial-web-app",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			platform := tc.Platform
+			if platform == "" {
+				platform = openshift.PlatformOpenShift
+			}
+			cluster := newFakeCluster()
+			tc.Objects(t, cluster)
+			selector := ""
+			osClient := &openshift.OSClientInterfaceMock{
+				GetPodsFunc: func(ns string, s string) ([]corev1.Pod, error) {
+					selector = s
+					return tc.Pods, nil
+				},
+				GetEndpointsFunc: func(ns string, name string) (corev1.Endpoints, error) {
+					return corev1.Endpoints{Subsets: tc.Endpoints}, nil
+				},
+			}
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, osClient, cluster.clientFactory, &SdkCruderMock{}, platform, "", "")
+
+			r := wh.checkReadiness(&v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Namespace: "webapp"}})
+			ready := r.ready(platform)
+			if ready.reason != tc.Ready {
+				t.Fatalf("expected ready reason %s, got %+v", tc.Ready, ready)
+			}
+			if expected := tc.Ready == v1alpha1.ReasonReady || tc.Ready == v1alpha1.ReasonScaledDown; ready.ok() != expected {
+				t.Fatalf("expected the web app to be ready %v, got %+v", expected, ready)
+			}
+			if r.deployment.reason != tc.Deployment {
+				t.Fatalf("expected deployment reason %s, got %+v", tc.Deployment, r.deployment)
+			}
+			if r.route.reason != tc.Route {
+				t.Fatalf("expected route reason %s, got %+v", tc.Route, r.route)
+			}
+			if r.url != tc.ExpectedURL {
+				t.Fatalf("expected url %q, got %q", tc.ExpectedURL, r.url)
+			}
+			if tc.ExpectedSelector != "" && selector != tc.ExpectedSelector {
+				t.Fatalf("expected the pods of %q, got %q", tc.ExpectedSelector, selector)
+			}
+		})
+	}
+}
+
+func TestSetReadyStatus(t *testing.T) {
+	cluster := newFakeCluster()
+	cluster.put(t, "DeploymentConfig", rolledOutDC(1, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: dcRolloutComplete}))
+	cluster.put(t, "Route", admittedRoute(corev1.ConditionTrue))
+	osClient := &openshift.OSClientInterfaceMock{
+		GetPodsFunc: func(ns string, selector string) ([]corev1.Pod, error) {
+			return nil, errors.New("forbidden")
+		},
+		GetEndpointsFunc: serviceEndpoints,
+	}
+	cruder := &SdkCruderMock{
+		UpdateFunc:       func(object sdk.Object) error { return nil },
+		UpdateStatusFunc: func(object sdk.Object) error { return nil },
+	}
+	wh := NewWebHandler(nil, &record.FakeRecorder{}, osClient, cluster.clientFactory, cruder, openshift.PlatformOpenShift, "", "")
+
+	cr := &v1alpha1.WebApp{ObjectMeta: metav1.ObjectMeta{Namespace: "webapp"}}
+	if ready, err := wh.setReadyStatus(cr); err != nil || ready {
+		t.Fatalf("expected the web app not to be ready without pods, got %v %v", ready, err)
+	}
+	c := cr.Status.GetCondition(v1alpha1.Ready)
+	if c == nil || c.Status != corev1.ConditionFalse || c.Reason != v1alpha1.ReasonDeploymentUnavailable {
+		t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.Ready, v1alpha1.ReasonDeploymentUnavailable, c)
+	}
+	if cr.Status.Phase != v1alpha1.PhaseReconciling || cr.Status.URL != "https://tutorial-web-app-webapp.apps.example.com" {
+		t.Fatalf("expected phase %s with the route URL, got %s %q", v1alpha1.PhaseReconciling, cr.Status.Phase, cr.Status.URL)
+	}
+
+	osClient.GetPodsFunc = podPhase(corev1.PodRunning)
+	transition := c.LastTransitionTime
+	time.Sleep(time.Millisecond)
+	if ready, err := wh.setReadyStatus(cr); err != nil || !ready {
+		t.Fatalf("expected the web app to be ready, got %v %v", ready, err)
+	}
+	c = cr.Status.GetCondition(v1alpha1.Ready)
+	if c.Status != corev1.ConditionTrue || c.Reason != v1alpha1.ReasonReady || c.LastTransitionTime.Equal(&transition) {
+		t.Fatalf("expected condition %s to transition to true, got %v", v1alpha1.Ready, c)
+	}
+	if cr.Status.Phase != v1alpha1.PhaseReady {
+		t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReady, cr.Status.Phase)
+	}
+}
@@ -9,40 +9,48 @@ import (
 )
 
 var (
-	lockSdkCruderMockCreate sync.RWMutex
-	lockSdkCruderMockDelete sync.RWMutex
-	lockSdkCruderMockGet    sync.RWMutex
-	lockSdkCruderMockList   sync.RWMutex
-	lockSdkCruderMockUpdate sync.RWMutex
+	lockSdkCruderMockCreate       sync.RWMutex
+	lockSdkCruderMockDelete       sync.RWMutex
+	lockSdkCruderMockGet          sync.RWMutex
+	lockSdkCruderMockList         sync.RWMutex
+	lockSdkCruderMockUpdate       sync.RWMutex
+	lockSdkCruderMockUpdateStatus sync.RWMutex
 )
 
+// Ensure, that SdkCruderMock does implement SdkCruder.
+// If this is not the case, regenerate this file with moq.
+var _ SdkCruder = &SdkCruderMock{}
+
 // SdkCruderMock is a mock implementation of SdkCruder.
 //
-//     func TestSomethingThatUsesSdkCruder(t *testing.T) {
+//	    func TestSomethingThatUsesSdkCruder(t *testing.T) {
 //
-//         // make and configure a mocked SdkCruder
-//         mockedSdkCruder := &SdkCruderMock{
-//             CreateFunc: func(object sdk.Object) error {
-// 	               panic("mock out the Create method")
-//             },
-//             DeleteFunc: func(object sdk.Object, opts ...sdk.DeleteOption) error {
-// 	               panic("mock out the Delete method")
-//             },
-//             GetFunc: func(object sdk.Object, opts ...sdk.GetOption) error {
-// 	               panic("mock out the Get method")
-//             },
-//             ListFunc: func(namespace string, into sdk.Object, opts ...sdk.ListOption) error {
-// 	               panic("mock out the List method")
-//             },
-//             UpdateFunc: func(object sdk.Object) error {
-// 	               panic("mock out the Update method")
-//             },
-//         }
+//	        // make and configure a mocked SdkCruder
+//	        mockedSdkCruder := &SdkCruderMock{
+//	            CreateFunc: func(object sdk.Object) error {
+//		               panic("mock out the Create method")
+//	            },
+//	            DeleteFunc: func(object sdk.Object, opts ...sdk.DeleteOption) error {
+//		               panic("mock out the Delete method")
+//	            },
+//	            GetFunc: func(object sdk.Object, opts ...sdk.GetOption) error {
+//		               panic("mock out the Get method")
+//	            },
+//	            ListFunc: func(namespace string, into sdk.Object, opts ...sdk.ListOption) error {
+//		               panic("mock out the List method")
+//	            },
+//	            UpdateFunc: func(object sdk.Object) error {
+//		               panic("mock out the Update method")
+//	            },
+//	            UpdateStatusFunc: func(object sdk.Object) error {
+//		               panic("mock out the UpdateStatus method")
+//	            },
+//	        }
 //
-//         // use mockedSdkCruder in code that requires SdkCruder
-//         // and then make assertions.
+//	        // use mockedSdkCruder in code that requires SdkCruder
+//	        // and then make assertions.
 //
-//     }
+//	    }
 type SdkCruderMock struct {
 	// CreateFunc mocks the Create method.
 	CreateFunc func(object sdk.Object) error
@@ -59,6 +67,9 @@ type SdkCruderMock struct {
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(object sdk.Object) error
 
+	// UpdateStatusFunc mocks the UpdateStatus method.
+	UpdateStatusFunc func(object sdk.Object) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Create holds details about calls to the Create method.
@@ -94,6 +105,11 @@ type SdkCruderMock struct {
 			// Object is the object argument value.
 			Object sdk.Object
 		}
+		// UpdateStatus holds details about calls to the UpdateStatus method.
+		UpdateStatus []struct {
+			// Object is the object argument value.
+			Object sdk.Object
+		}
 	}
 }
 
@@ -115,7 +131,8 @@ func (mock *SdkCruderMock) Create(object sdk.Object) error {
 
 // CreateCalls gets all the calls that were made to Create.
 // Check the length with:
-//     len(mockedSdkCruder.CreateCalls())
+//
+//	len(mockedSdkCruder.CreateCalls())
 func (mock *SdkCruderMock) CreateCalls() []struct {
 	Object sdk.Object
 } {
@@ -148,7 +165,8 @@ func (mock *SdkCruderMock) Delete(object sdk.Object, opts ...sdk.DeleteOption) e
 
 // DeleteCalls gets all the calls that were made to Delete.
 // Check the length with:
-//     len(mockedSdkCruder.DeleteCalls())
+//
+//	len(mockedSdkCruder.DeleteCalls())
 func (mock *SdkCruderMock) DeleteCalls() []struct {
 	Object sdk.Object
 	Opts   []sdk.DeleteOption
@@ -183,7 +201,8 @@ func (mock *SdkCruderMock) Get(object sdk.Object, opts ...sdk.GetOption) error {
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
-//     len(mockedSdkCruder.GetCalls())
+//
+//	len(mockedSdkCruder.GetCalls())
 func (mock *SdkCruderMock) GetCalls() []struct {
 	Object sdk.Object
 	Opts   []sdk.GetOption
@@ -220,7 +239,8 @@ func (mock *SdkCruderMock) List(namespace string, into sdk.Object, opts ...sdk.L
 
 // ListCalls gets all the calls that were made to List.
 // Check the length with:
-//     len(mockedSdkCruder.ListCalls())
+//
+//	len(mockedSdkCruder.ListCalls())
 func (mock *SdkCruderMock) ListCalls() []struct {
 	Namespace string
 	Into      sdk.Object
@@ -255,7 +275,8 @@ func (mock *SdkCruderMock) Update(object sdk.Object) error {
 
 // UpdateCalls gets all the calls that were made to Update.
 // Check the length with:
-//     len(mockedSdkCruder.UpdateCalls())
+//
+//	len(mockedSdkCruder.UpdateCalls())
 func (mock *SdkCruderMock) UpdateCalls() []struct {
 	Object sdk.Object
 } {
@@ -267,3 +288,35 @@ func (mock *SdkCruderMock) UpdateCalls() []struct {
 	lockSdkCruderMockUpdate.RUnlock()
 	return calls
 }
+
+// UpdateStatus calls UpdateStatusFunc.
+func (mock *SdkCruderMock) UpdateStatus(object sdk.Object) error {
+	if mock.UpdateStatusFunc == nil {
+		panic("SdkCruderMock.UpdateStatusFunc: method is nil but SdkCruder.UpdateStatus was just called")
+	}
+	callInfo := struct {
+		Object sdk.Object
+	}{
+		Object: object,
+	}
+	lockSdkCruderMockUpdateStatus.Lock()
+	mock.calls.UpdateStatus = append(mock.calls.UpdateStatus, callInfo)
+	lockSdkCruderMockUpdateStatus.Unlock()
+	return mock.UpdateStatusFunc(object)
+}
+
+// UpdateStatusCalls gets all the calls that were made to UpdateStatus.
+// Check the length with:
+//
+//	len(mockedSdkCruder.UpdateStatusCalls())
+func (mock *SdkCruderMock) UpdateStatusCalls() []struct {
+	Object sdk.Object
+} {
+	var calls []struct {
+		Object sdk.Object
+	}
+	lockSdkCruderMockUpdateStatus.RLock()
+	calls = mock.calls.UpdateStatus
+	lockSdkCruderMockUpdateStatus.RUnlock()
+	return calls
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// reconcileStorage applies the storage settings of the WebApp to the claims mounted by the web app and returns
+// the objects to provision. With an emptyDir the claims are left out, a claim that was provisioned before is
+// kept with its data until the WebApp is deleted.
+func (h *AppHandler) reconcileStorage(cr *v1alpha1.WebApp, wl *workload, objects []runtime.Object) ([]runtime.Object, error) {
+	storage := v1alpha1.WebAppStorage{}
+	if cr.Spec.Storage != nil {
+		storage = *cr.Spec.Storage
+	}
+
+	claims := findClaims(objects, wl.claims())
+	if storage.EmptyDir {
+		wl.useEmptyDir(storage.Size)
+		cr.Status.Storage = &v1alpha1.WebAppStorageStatus{EmptyDir: true}
+		return withoutClaims(objects, claims), nil
+	}
+
+	status := &v1alpha1.WebAppStorageStatus{}
+	var messages []string
+	for _, claim := range claims {
+		live := &corev1.PersistentVolumeClaim{}
+		err := h.getLive("v1", "PersistentVolumeClaim", cr.Namespace, claim.Name, live)
+		if errors2.IsNotFound(err) {
+			live = nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get PersistentVolumeClaim %s: %v", claim.Name, err)
+		}
+
+		msg, err := h.reconcileClaim(cr, storage, claim, live)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			messages = append(messages, msg)
+		}
+		status.ClaimName = claim.Name
+	}
+	status.Message = strings.Join(messages, ", ")
+	cr.Status.Storage = status
+	return objects, nil
+}
+
+// reconcileClaim applies the access mode, the storage class and the size to a claim rendered from the template.
+// The access modes and the storage class of a claim are immutable and claims can not be shrunk, patching them
+// would fail on every reconcile. It returns why the claim does not get the desired size.
+func (h *AppHandler) reconcileClaim(cr *v1alpha1.WebApp, storage v1alpha1.WebAppStorage, claim, live *corev1.PersistentVolumeClaim) (string, error) {
+	if mode := storage.AccessMode; mode != "" {
+		if live != nil && !hasAccessMode(live, mode) {
+			return "", fmt.Errorf("PersistentVolumeClaim %s has access modes %v, it has to be deleted to be recreated with access mode %s", claim.Name, live.Spec.AccessModes, mode)
+		}
+		claim.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{mode}
+	}
+
+	if class := storage.StorageClassName; class != nil {
+		if live != nil && (live.Spec.StorageClassName == nil || *live.Spec.StorageClassName != *class) {
+			return "", fmt.Errorf("PersistentVolumeClaim %s has storage class %q, it has to be deleted to be recreated with storage class %q", claim.Name, storageClassName(live), *class)
+		}
+		claim.Spec.StorageClassName = class
+	}
+
+	// without a size the claim keeps the size of the template, unless it was expanded before
+	size, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if storage.Size != nil {
+		size, ok = *storage.Size, true
+	}
+	if !ok {
+		return "", nil
+	}
+	if live == nil {
+		setStorageRequest(claim, size)
+		return "", nil
+	}
+
+	current := live.Spec.Resources.Requests[corev1.ResourceStorage]
+	switch size.Cmp(current) {
+	case 0:
+		setStorageRequest(claim, size)
+	case -1:
+		setStorageRequest(claim, current)
+		return fmt.Sprintf("PersistentVolumeClaim %s requests %s and can not be shrunk to %s", claim.Name, current.String(), size.String()), nil
+	case 1:
+		if msg := h.checkExpansion(live); msg != "" {
+			setStorageRequest(claim, current)
+			return fmt.Sprintf("PersistentVolumeClaim %s keeps %s: %s", claim.Name, current.String(), msg), nil
+		}
+		setStorageRequest(claim, size)
+		h.recorder.Eventf(cr, corev1.EventTypeNormal, eventStorageResized, "expanding PersistentVolumeClaim %s from %s to %s", claim.Name, current.String(), size.String())
+	}
+	return "", nil
+}
+
+// checkExpansion returns why the storage class of a claim does not allow to expand it, the operator needs to
+// read storage classes to find out
+func (h *AppHandler) checkExpansion(claim *corev1.PersistentVolumeClaim) string {
+	name := storageClassName(claim)
+	if name == "" {
+		return "it has no storage class that allows volume expansion"
+	}
+	class := &storagev1.StorageClass{}
+	if err := h.getLive("storage.k8s.io/v1", "StorageClass", "", name, class); err != nil {
+		return fmt.Sprintf("failed to get StorageClass %s: %v", name, err)
+	}
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		return fmt.Sprintf("StorageClass %s does not allow volume expansion", name)
+	}
+	return ""
+}
+
+// observeStorage completes the storage status with the live state of the provisioned claim
+func (h *AppHandler) observeStorage(cr *v1alpha1.WebApp) {
+	status := cr.Status.Storage
+	if status == nil || status.ClaimName == "" {
+		return
+	}
+	live := &corev1.PersistentVolumeClaim{}
+	if err := h.getLive("v1", "PersistentVolumeClaim", cr.Namespace, status.ClaimName, live); err != nil {
+		status.Message = fmt.Sprintf("failed to get PersistentVolumeClaim %s: %v", status.ClaimName, err)
+		return
+	}
+
+	status.Phase = live.Status.Phase
+	if requested, ok := live.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		status.Requested = &requested
+	}
+	if capacity, ok := live.Status.Capacity[corev1.ResourceStorage]; ok {
+		status.Capacity = &capacity
+	}
+	if status.Message != "" {
+		return
+	}
+	for _, c := range live.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case corev1.PersistentVolumeClaimResizing:
+			status.Message = "the volume is being expanded"
+		case corev1.PersistentVolumeClaimFileSystemResizePending:
+			status.Message = "the file system of the volume is expanded when the pod is restarted"
+		}
+	}
+}
+
+// findClaims returns the claims with the given names out of the objects rendered from the template
+func findClaims(objects []runtime.Object, names []string) []*corev1.PersistentVolumeClaim {
+	var claims []*corev1.PersistentVolumeClaim
+	for _, o := range objects {
+		claim, ok := o.(*corev1.PersistentVolumeClaim)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if claim.Name == name {
+				claims = append(claims, claim)
+			}
+		}
+	}
+	return claims
+}
+
+func withoutClaims(objects []runtime.Object, claims []*corev1.PersistentVolumeClaim) []runtime.Object {
+	var kept []runtime.Object
+	for _, o := range objects {
+		keep := true
+		for _, claim := range claims {
+			if o == runtime.Object(claim) {
+				keep = false
+			}
+		}
+		if keep {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+func hasAccessMode(claim *corev1.PersistentVolumeClaim, mode corev1.PersistentVolumeAccessMode) bool {
+	return len(claim.Spec.AccessModes) == 1 && claim.Spec.AccessModes[0] == mode
+}
+
+func storageClassName(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *claim.Spec.StorageClassName
+}
+
+func setStorageRequest(claim *corev1.PersistentVolumeClaim, size resource.Quantity) {
+	if claim.Spec.Resources.Requests == nil {
+		claim.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	v1 "github.com/openshift/api/template/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	checksumPrefix        = "sha256:"
+	maxRemoteTemplateSize = 10 << 20
+)
+
+// templateLoader loads the template a WebApp references. Templates from ConfigMaps are cached until the
+// ConfigMap changes and remote templates are pinned by their checksum, so they are only fetched once.
+type templateLoader struct {
+	osClient   openshift.OSClientInterface
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedTemplate
+}
+
+type cachedTemplate struct {
+	version  string
+	template *v1.Template
+}
+
+func newTemplateLoader(osClient openshift.OSClientInterface) *templateLoader {
+	return &templateLoader{
+		osClient:   osClient,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cache:      map[string]cachedTemplate{},
+	}
+}
+
+// loadLocal returns the template of the WebApp when it is loaded without a request to the cluster or a remote
+// server, from the file system of the operator or inline. The admission webhooks only look at these templates.
+func (l *templateLoader) loadLocal(cr *v1alpha1.WebApp) (*v1.Template, bool, error) {
+	if cr.Spec.Template.ConfigMap != nil || cr.Spec.Template.Remote != nil {
+		return nil, false, nil
+	}
+	tmpl, err := l.load(cr)
+	return tmpl, true, err
+}
+
+// load returns a copy of the template of the WebApp that is safe to process
+func (l *templateLoader) load(cr *v1alpha1.WebApp) (*v1.Template, error) {
+	src := cr.Spec.Template
+	sources := 0
+	for _, set := range []bool{src.ConfigMap != nil, src.Inline != nil, src.Remote != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("only one of template configMap, inline and remote can be set")
+	}
+
+	switch {
+	case src.ConfigMap != nil:
+		return l.fromConfigMap(cr.Namespace, src.ConfigMap)
+	case src.Inline != nil:
+		return decodeTemplate(src.Inline.Raw, "inline template")
+	case src.Remote != nil:
+		return l.fromRemote(src.Remote)
+	}
+
+	if src.Path == "" {
+		return nil, fmt.Errorf("no template source set")
+	}
+	res, err := openshift.LoadKubernetesResourceFromFile(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	return asTemplate(res, src.Path)
+}
+
+func (l *templateLoader) fromConfigMap(ns string, ref *v1alpha1.TemplateConfigMapSource) (*v1.Template, error) {
+	key := ref.Key
+	if key == "" {
+		key = v1alpha1.DefaultTemplateConfigMapKey
+	}
+	cm, err := l.osClient.GetConfigMap(ns, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template config map %s: %v", ref.Name, err)
+	}
+
+	name := fmt.Sprintf("config map %s key %s", ref.Name, key)
+	return l.cached("configmap/"+ns+"/"+ref.Name+"/"+key, cm.ResourceVersion, func() (*v1.Template, error) {
+		data, ok := cm.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		return decodeTemplate([]byte(data), name)
+	})
+}
+
+func (l *templateLoader) fromRemote(ref *v1alpha1.TemplateRemoteSource) (*v1.Template, error) {
+	if !strings.HasPrefix(ref.URL, "https://") && !strings.HasPrefix(ref.URL, "http://") {
+		return nil, fmt.Errorf("template url %s must use http or https", ref.URL)
+	}
+	if !strings.HasPrefix(ref.Checksum, checksumPrefix) {
+		return nil, fmt.Errorf("template checksum %q must be in the form %s<hex>", ref.Checksum, checksumPrefix)
+	}
+	expected := strings.ToLower(strings.TrimPrefix(ref.Checksum, checksumPrefix))
+
+	return l.cached("remote/"+ref.URL, expected, func() (*v1.Template, error) {
+		resp, err := l.httpClient.Get(ref.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch template %s: %v", ref.URL, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch template %s: %s", ref.URL, resp.Status)
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteTemplateSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %v", ref.URL, err)
+		}
+
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return nil, fmt.Errorf("checksum mismatch for template %s: expected %s%s, got %s%s", ref.URL, checksumPrefix, expected, checksumPrefix, actual)
+		}
+		return decodeTemplate(data, ref.URL)
+	})
+}
+
+// cached returns a copy of the cached template when its version matches, otherwise it loads and caches it.
+// The lock is not held while loading, so a slow remote template does not block the WebApps using other
+// templates. Concurrent loads of the same key may both fetch it, the last one is cached.
+func (l *templateLoader) cached(key, version string, load func() (*v1.Template, error)) (*v1.Template, error) {
+	l.mu.Lock()
+	entry, ok := l.cache[key]
+	l.mu.Unlock()
+	if ok && entry.version == version {
+		return entry.template.DeepCopy(), nil
+	}
+
+	tmpl, err := load()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.cache[key] = cachedTemplate{version: version, template: tmpl}
+	l.mu.Unlock()
+	return tmpl.DeepCopy(), nil
+}
+
+// decodeTemplate turns a YAML or JSON document into a template
+func decodeTemplate(data []byte, name string) (*v1.Template, error) {
+	data, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+	}
+	res, err := openshift.LoadKubernetesResource(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", name, err)
+	}
+	return asTemplate(res, name)
+}
+
+func asTemplate(res runtime.Object, name string) (*v1.Template, error) {
+	tmpl, ok := res.(*v1.Template)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a template: %s", name, res.GetObjectKind().GroupVersionKind().Kind)
+	}
+	return tmpl, nil
+}
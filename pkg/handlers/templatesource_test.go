@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testTemplateYAML = `apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: %s
+parameters:
+  - name: OPENSHIFT_OAUTHCLIENT_ID
+    value: tutorial-web-app
+objects: []
+`
+
+func templateYAML(name string) string {
+	return strings.Replace(testTemplateYAML, "%s", name, 1)
+}
+
+func TestTemplateLoader(t *testing.T) {
+	remote := templateYAML("remote")
+	sum := sha256.Sum256([]byte(remote))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(remote))
+	}))
+	defer server.Close()
+
+	configMap := v12.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp-template", ResourceVersion: "1"},
+		Data:       map[string]string{v1alpha1.DefaultTemplateConfigMapKey: templateYAML("configmap")},
+	}
+
+	cases := []struct {
+		Name          string
+		Template      v1alpha1.WebAppTemplate
+		ExpectedName  string
+		ExpectedError string
+	}{
+		{
+			Name:         "Template from path",
+			Template:     v1alpha1.WebAppTemplate{Path: testTemplate},
+			ExpectedName: "tutorial-web-app",
+		},
+		{
+			Name:         "Template from config map",
+			Template:     v1alpha1.WebAppTemplate{ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template"}},
+			ExpectedName: "configmap",
+		},
+		{
+			Name:          "Missing config map key",
+			Template:      v1alpha1.WebAppTemplate{ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template", Key: "missing.yml"}},
+			ExpectedError: "config map webapp-template key missing.yml not found",
+		},
+		{
+			Name: "Inline template",
+			Template: v1alpha1.WebAppTemplate{Inline: &runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"template.openshift.io/v1","kind":"Template","metadata":{"name":"inline"},"objects":[]}`),
+			}},
+			ExpectedName: "inline",
+		},
+		{
+			Name:         "Remote template",
+			Template:     v1alpha1.WebAppTemplate{Remote: &v1alpha1.TemplateRemoteSource{URL: server.URL, Checksum: checksum}},
+			ExpectedName: "remote",
+		},
+		{
+			Name:          "Remote template with wrong checksum",
+			Template:      v1alpha1.WebAppTemplate{Remote: &v1alpha1.TemplateRemoteSource{URL: server.URL + "/other", Checksum: "sha256:" + strings.Repeat("0", 64)}},
+			ExpectedError: "checksum mismatch",
+		},
+		{
+			Name:          "Remote template without checksum",
+			Template:      v1alpha1.WebAppTemplate{Remote: &v1alpha1.TemplateRemoteSource{URL: server.URL}},
+			ExpectedError: "must be in the form sha256:<hex>",
+		},
+		{
+			Name: "Multiple sources",
+			Template: v1alpha1.WebAppTemplate{
+				ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template"},
+				Remote:    &v1alpha1.TemplateRemoteSource{URL: server.URL, Checksum: checksum},
+			},
+			ExpectedError: "only one of template configMap, inline and remote can be set",
+		},
+		{
+			Name:          "No source",
+			ExpectedError: "no template source set",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			loader := newTemplateLoader(&openshift.OSClientInterfaceMock{
+				GetConfigMapFunc: func(ns string, name string) (v12.ConfigMap, error) {
+					return configMap, nil
+				},
+			})
+			tmpl, err := loader.load(&v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{Template: tc.Template}})
+			if tc.ExpectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tmpl.Name != tc.ExpectedName {
+				t.Fatalf("expected template %s, got %s", tc.ExpectedName, tmpl.Name)
+			}
+		})
+	}
+}
+
+func TestTemplateLoader_Cache(t *testing.T) {
+	configMap := v12.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "webapp-template", ResourceVersion: "1"},
+		Data:       map[string]string{"custom.yml": templateYAML("first")},
+	}
+	loader := newTemplateLoader(&openshift.OSClientInterfaceMock{
+		GetConfigMapFunc: func(ns string, name string) (v12.ConfigMap, error) {
+			return configMap, nil
+		},
+	})
+	cr := &v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{
+		ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template", Key: "custom.yml"},
+	}}}
+
+	tmpl, err := loader.load(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// processing fills the parameter values, this must not leak into the cached template
+	tmpl.Parameters[0].Value = "changed"
+
+	// the content changes without a new resource version, so the cached template is used
+	configMap.Data["custom.yml"] = templateYAML("second")
+	tmpl, err = loader.load(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmpl.Name != "first" || tmpl.Parameters[0].Value != "tutorial-web-app" {
+		t.Fatalf("expected the cached template to be unchanged, got %s with %v", tmpl.Name, tmpl.Parameters)
+	}
+
+	configMap.ResourceVersion = "2"
+	tmpl, err = loader.load(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmpl.Name != "second" {
+		t.Fatalf("expected the changed config map to be loaded, got %s", tmpl.Name)
+	}
+}
+
+func TestTemplateLoader_SlowRemote(t *testing.T) {
+	remote := templateYAML("remote")
+	sum := sha256.Sum256([]byte(remote))
+	fetching, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+		w.Write([]byte(remote))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	loader := newTemplateLoader(&openshift.OSClientInterfaceMock{
+		GetConfigMapFunc: func(ns string, name string) (v12.ConfigMap, error) {
+			return v12.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: "1"},
+				Data:       map[string]string{v1alpha1.DefaultTemplateConfigMapKey: templateYAML("configmap")},
+			}, nil
+		},
+	})
+	go loader.load(&v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{
+		Remote: &v1alpha1.TemplateRemoteSource{URL: server.URL, Checksum: "sha256:" + hex.EncodeToString(sum[:])},
+	}}})
+	<-fetching
+
+	// the remote template is still being fetched
+	tmpl, err := loader.load(&v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{
+		ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template"},
+	}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmpl.Name != "configmap" {
+		t.Fatalf("expected the config map template, got %s", tmpl.Name)
+	}
+}
@@ -6,30 +6,29 @@ import (
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/sdk"
-	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-type Handlers struct {
-	WebAppHandler Handler
-}
-
 //go:generate moq -out sdkCruder_moq.go . SdkCruder
 
 type SdkCruder interface {
 	Create(object sdk.Object) error
 	Update(object sdk.Object) error
+	// UpdateStatus writes the status of the object through the status subresource
+	UpdateStatus(object sdk.Object) error
 	Delete(object sdk.Object, opts ...sdk.DeleteOption) error
 	Get(object sdk.Object, opts ...sdk.GetOption) error
 	List(namespace string, into sdk.Object, opts ...sdk.ListOption) error
 }
 
 type Handler interface {
-	Handle(ctx context.Context, event sdk.Event) error
+	Reconcile(ctx context.Context, cr *v1alpha1.WebApp) (reconcile.Result, error)
 	Delete(cr *v1alpha1.WebApp) error
-	SetStatus(msg string, cr *v1alpha1.WebApp)
-	ProcessTemplate(cr *v1alpha1.WebApp) ([]runtime.RawExtension, error)
+	SetStatus(phase v1alpha1.WebAppPhase, msg string, cr *v1alpha1.WebApp) error
+	ProcessTemplate(cr *v1alpha1.WebApp, params map[string]string) ([]runtime.RawExtension, error)
 	GetRuntimeObjs(exts []runtime.RawExtension) ([]runtime.Object, error)
 	ProvisionObjects(objects []runtime.Object, cr *v1alpha1.WebApp) error
 	IsAppReady(cr *v1alpha1.WebApp) bool
@@ -38,12 +37,13 @@ type Handler interface {
 type ClientFactory func(apiVersion, kind, namespace string) (dynamic.ResourceInterface, string, error)
 
 type AppHandler struct {
+	platform                     openshift.Platform
+	defaultImage                 string
+	defaultBackupImage           string
+	templates                    *templateLoader
 	metrics                      *metrics.Metrics
+	recorder                     record.EventRecorder
 	osClient                     openshift.OSClientInterface
 	dynamicResourceClientFactory ClientFactory
 	sdkCruder                    SdkCruder
 }
-
-type Metrics struct {
-	operatorErrors prometheus.Counter
-}
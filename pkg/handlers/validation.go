@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	defaultAppLabel  = "tutorial-web-app"
+	routingSubdomain = "ROUTING_SUBDOMAIN"
+)
+
+// defaultedParameters are set from the template defaults at admission time, so the values a WebApp was
+// created with are kept when a later template changes its defaults
+var defaultedParameters = []string{"WALKTHROUGH_LOCATIONS", "OPENSHIFT_VERSION"}
+
+// Default fills in the defaults of a WebApp. Template defaults are only applied when the template is local and
+// can be loaded, an unloadable template is reported by Validate.
+func (h *AppHandler) Default(cr *v1alpha1.WebApp) {
+	if cr.Spec.AppLabel == "" {
+		cr.Spec.AppLabel = defaultAppLabel
+	}
+
+	tmpl, local, err := h.templates.loadLocal(cr)
+	if !local || err != nil {
+		return
+	}
+	referenced := map[string]bool{}
+	for _, p := range cr.Spec.Template.ParametersFrom {
+		referenced[p.Name] = true
+	}
+	for _, name := range defaultedParameters {
+		if _, ok := cr.Spec.Template.Parameters[name]; ok || referenced[name] {
+			continue
+		}
+		for _, p := range tmpl.Parameters {
+			if p.Name == name && p.Value != "" {
+				if cr.Spec.Template.Parameters == nil {
+					cr.Spec.Template.Parameters = map[string]string{}
+				}
+				cr.Spec.Template.Parameters[name] = p.Value
+			}
+		}
+	}
+}
+
+// Validate checks everything about a WebApp that can be checked without provisioning it. Templates from a
+// ConfigMap or a remote server are not loaded, the admission of a WebApp must not depend on them.
+func (h *AppHandler) Validate(cr *v1alpha1.WebApp) error {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	tmplPath := spec.Child("template")
+
+	switch cr.Spec.Template.Processor {
+	case "", v1alpha1.TemplateProcessorServer, v1alpha1.TemplateProcessorLocal:
+	default:
+		errs = append(errs, field.NotSupported(tmplPath.Child("processor"), cr.Spec.Template.Processor, []string{string(v1alpha1.TemplateProcessorServer), string(v1alpha1.TemplateProcessorLocal)}))
+	}
+	switch cr.Spec.ImagePolicy {
+	case "", v1alpha1.ImagePolicyEnforce, v1alpha1.ImagePolicyIfNotSet, v1alpha1.ImagePolicyIgnore:
+	default:
+		errs = append(errs, field.NotSupported(spec.Child("imagePolicy"), cr.Spec.ImagePolicy, []string{string(v1alpha1.ImagePolicyEnforce), string(v1alpha1.ImagePolicyIfNotSet), string(v1alpha1.ImagePolicyIgnore)}))
+	}
+
+	errs = append(errs, validateScaling(cr.Spec, spec)...)
+	if cr.Spec.PodTemplate != nil {
+		errs = append(errs, validatePodTemplate(*cr.Spec.PodTemplate, spec.Child("podTemplate"))...)
+	}
+	errs = append(errs, validateBackup(cr.Spec, spec)...)
+	if cr.Spec.Route != nil {
+		errs = append(errs, validateRoute(*cr.Spec.Route, h.platform, spec.Child("route"))...)
+	}
+
+	if subdomain, ok := cr.Spec.Template.Parameters[routingSubdomain]; ok && subdomain != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(subdomain) {
+			errs = append(errs, field.Invalid(tmplPath.Child("parameters").Key(routingSubdomain), subdomain, msg))
+		}
+	}
+
+	// the operator defaults the id of the OAuthClient for each WebApp
+	supplied := map[string]bool{oauthClientEnv: true}
+	for name := range cr.Spec.Template.Parameters {
+		supplied[name] = true
+	}
+	for i, p := range cr.Spec.Template.ParametersFrom {
+		path := tmplPath.Child("parametersFrom").Index(i)
+		if supplied[p.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), p.Name))
+		}
+		supplied[p.Name] = true
+		refs := 0
+		if p.ValueFrom.SecretKeyRef != nil {
+			refs++
+		}
+		if p.ValueFrom.ConfigMapKeyRef != nil {
+			refs++
+		}
+		if refs != 1 {
+			errs = append(errs, field.Invalid(path.Child("valueFrom"), p.ValueFrom, "exactly one of secretKeyRef and configMapKeyRef must be set"))
+		}
+	}
+
+	tmpl, local, err := h.templates.loadLocal(cr)
+	if !local {
+		return errs.ToAggregate()
+	}
+	if err != nil {
+		errs = append(errs, field.Invalid(tmplPath, templateSourceName(cr.Spec.Template), err.Error()))
+		return errs.ToAggregate()
+	}
+	var missing []string
+	for _, p := range tmpl.Parameters {
+		if p.Required && p.Value == "" && p.Generate == "" && !supplied[p.Name] {
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, field.Required(tmplPath.Child("parameters"), fmt.Sprintf("template %s requires parameters %s", tmpl.Name, strings.Join(missing, ", "))))
+	}
+
+	return errs.ToAggregate()
+}
+
+// validateScaling checks the replicas, the strategy and the storage of a WebApp. Pods of more than one replica,
+// or of the old and the new version during a rolling update, can be scheduled to different nodes, so they
+// need storage that can be mounted by several nodes. The pods of an emptyDir each have their own data.
+func validateScaling(spec v1alpha1.WebAppSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	mode := corev1.ReadWriteOnce
+	emptyDir := false
+	if spec.Storage != nil {
+		errs = append(errs, validateStorage(*spec.Storage, path.Child("storage"))...)
+		if spec.Storage.AccessMode != "" {
+			mode = spec.Storage.AccessMode
+		}
+		emptyDir = spec.Storage.EmptyDir
+	}
+
+	if spec.Replicas != nil {
+		if *spec.Replicas < 0 {
+			errs = append(errs, field.Invalid(path.Child("replicas"), *spec.Replicas, "must be greater than or equal to 0"))
+		}
+		if *spec.Replicas > 1 && emptyDir {
+			errs = append(errs, field.Invalid(path.Child("replicas"), *spec.Replicas, "more than one replica can not share the data of an emptyDir"))
+		} else if *spec.Replicas > 1 && mode != corev1.ReadWriteMany {
+			errs = append(errs, field.Invalid(path.Child("replicas"), *spec.Replicas, "more than one replica requires storage.accessMode ReadWriteMany"))
+		}
+	}
+
+	if spec.Strategy == nil {
+		return errs
+	}
+	strategyPath := path.Child("strategy")
+	switch spec.Strategy.Type {
+	case v1alpha1.StrategyRecreate:
+		if spec.Strategy.MaxSurge != nil || spec.Strategy.MaxUnavailable != nil {
+			errs = append(errs, field.Forbidden(strategyPath, "maxSurge and maxUnavailable may only be set for the Rolling strategy"))
+		}
+	case v1alpha1.StrategyRolling:
+		if mode != corev1.ReadWriteMany && !emptyDir {
+			errs = append(errs, field.Invalid(strategyPath.Child("type"), spec.Strategy.Type, "the Rolling strategy requires storage.accessMode ReadWriteMany"))
+		}
+		errs = append(errs, validateIntOrPercent(spec.Strategy.MaxSurge, strategyPath.Child("maxSurge"))...)
+		errs = append(errs, validateIntOrPercent(spec.Strategy.MaxUnavailable, strategyPath.Child("maxUnavailable"))...)
+		if isZero(spec.Strategy.MaxSurge) && isZero(spec.Strategy.MaxUnavailable) {
+			errs = append(errs, field.Invalid(strategyPath, spec.Strategy, "maxSurge and maxUnavailable may not both be 0"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(strategyPath.Child("type"), spec.Strategy.Type, []string{string(v1alpha1.StrategyRecreate), string(v1alpha1.StrategyRolling)}))
+	}
+	return errs
+}
+
+// validateStorage checks the storage settings of a WebApp, an emptyDir is not backed by a claim and has no
+// access mode or storage class
+func validateStorage(storage v1alpha1.WebAppStorage, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch storage.AccessMode {
+	case "", corev1.ReadWriteOnce, corev1.ReadWriteMany:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("accessMode"), storage.AccessMode, []string{string(corev1.ReadWriteOnce), string(corev1.ReadWriteMany)}))
+	}
+	if storage.Size != nil && storage.Size.Sign() <= 0 {
+		errs = append(errs, field.Invalid(path.Child("size"), storage.Size.String(), "must be greater than 0"))
+	}
+	if storage.StorageClassName != nil {
+		for _, msg := range validation.IsDNS1123Subdomain(*storage.StorageClassName) {
+			errs = append(errs, field.Invalid(path.Child("storageClassName"), *storage.StorageClassName, msg))
+		}
+	}
+	if storage.EmptyDir {
+		if storage.AccessMode != "" {
+			errs = append(errs, field.Forbidden(path.Child("accessMode"), "may not be set with emptyDir"))
+		}
+		if storage.StorageClassName != nil {
+			errs = append(errs, field.Forbidden(path.Child("storageClassName"), "may not be set with emptyDir"))
+		}
+	}
+	return errs
+}
+
+// validateBackup checks the backups and the restore of a WebApp. Backups read the data from the volume claim
+// of the web app, the data of an emptyDir can only be restored.
+func validateBackup(spec v1alpha1.WebAppSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if backup := spec.Backup; backup != nil {
+		backupPath := path.Child("backup")
+		if backup.Schedule == "" {
+			errs = append(errs, field.Required(backupPath.Child("schedule"), "a cron schedule is required"))
+		} else if !strings.HasPrefix(backup.Schedule, "@") && len(strings.Fields(backup.Schedule)) != 5 {
+			errs = append(errs, field.Invalid(backupPath.Child("schedule"), backup.Schedule, "must be a cron schedule of 5 fields or a predefined schedule such as @daily"))
+		}
+		if spec.Storage != nil && spec.Storage.EmptyDir {
+			errs = append(errs, field.Forbidden(backupPath, "the data of an emptyDir can not be backed up"))
+		}
+		errs = append(errs, validateLocation(backup.Target, backupPath.Child("target"))...)
+	}
+	if restore := spec.Restore; restore != nil {
+		restorePath := path.Child("restore")
+		errs = append(errs, validateLocation(restore.Source, restorePath.Child("source"))...)
+		if strings.Contains(restore.Archive, "/") {
+			errs = append(errs, field.Invalid(restorePath.Child("archive"), restore.Archive, "must be the name of an archive of the source"))
+		}
+	}
+	return errs
+}
+
+func validateLocation(location v1alpha1.BackupLocation, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	switch {
+	case location.S3 != nil && location.PersistentVolumeClaim != nil, location.S3 == nil && location.PersistentVolumeClaim == nil:
+		return append(errs, field.Invalid(path, location, "exactly one of s3 and persistentVolumeClaim must be set"))
+	case location.S3 != nil:
+		s3 := location.S3
+		if endpoint, err := url.Parse(s3.Endpoint); err != nil || endpoint.Host == "" || endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+			errs = append(errs, field.Invalid(path.Child("s3", "endpoint"), s3.Endpoint, "must be an http or https URL"))
+		}
+		if s3.Bucket == "" {
+			errs = append(errs, field.Required(path.Child("s3", "bucket"), ""))
+		}
+		if s3.CredentialsSecret == "" {
+			errs = append(errs, field.Required(path.Child("s3", "credentialsSecret"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(s3.CredentialsSecret) {
+				errs = append(errs, field.Invalid(path.Child("s3", "credentialsSecret"), s3.CredentialsSecret, msg))
+			}
+		}
+	default:
+		claim := location.PersistentVolumeClaim
+		for _, msg := range validation.IsDNS1123Subdomain(claim.ClaimName) {
+			errs = append(errs, field.Invalid(path.Child("persistentVolumeClaim", "claimName"), claim.ClaimName, msg))
+		}
+		for _, segment := range strings.Split(claim.Path, "/") {
+			if segment == ".." {
+				errs = append(errs, field.Invalid(path.Child("persistentVolumeClaim", "path"), claim.Path, "must not contain '..'"))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// validateRoute checks the route settings of a WebApp, a passthrough route hands the TLS connection to the web
+// app so the router can neither route by path nor serve a certificate. Ingresses are always edge terminated.
+func validateRoute(route v1alpha1.WebAppRoute, platform openshift.Platform, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if route.Host != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(route.Host) {
+			errs = append(errs, field.Invalid(path.Child("host"), route.Host, msg))
+		}
+	}
+	if route.Path != "" && !strings.HasPrefix(route.Path, "/") {
+		errs = append(errs, field.Invalid(path.Child("path"), route.Path, "must start with /"))
+	}
+	for key := range route.Annotations {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			errs = append(errs, field.Invalid(path.Child("annotations"), key, msg))
+		}
+	}
+	for key, value := range route.Labels {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path.Child("labels"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(path.Child("labels").Key(key), value, msg))
+		}
+	}
+
+	tls := route.TLS
+	if tls == nil {
+		return errs
+	}
+	tlsPath := path.Child("tls")
+	termination := tls.Termination
+	switch termination {
+	case "":
+		termination = routev1.TLSTerminationEdge
+	case routev1.TLSTerminationEdge, routev1.TLSTerminationReencrypt, routev1.TLSTerminationPassthrough:
+	default:
+		errs = append(errs, field.NotSupported(tlsPath.Child("termination"), termination, []string{string(routev1.TLSTerminationEdge), string(routev1.TLSTerminationReencrypt), string(routev1.TLSTerminationPassthrough)}))
+	}
+	if termination != routev1.TLSTerminationEdge && platform == openshift.PlatformKubernetes {
+		errs = append(errs, field.Invalid(tlsPath.Child("termination"), termination, "Ingresses only support edge termination"))
+	}
+	switch tls.InsecureEdgeTerminationPolicy {
+	case "", routev1.InsecureEdgeTerminationPolicyNone, routev1.InsecureEdgeTerminationPolicyAllow, routev1.InsecureEdgeTerminationPolicyRedirect:
+	default:
+		errs = append(errs, field.NotSupported(tlsPath.Child("insecureEdgeTerminationPolicy"), tls.InsecureEdgeTerminationPolicy, []string{string(routev1.InsecureEdgeTerminationPolicyNone), string(routev1.InsecureEdgeTerminationPolicyAllow), string(routev1.InsecureEdgeTerminationPolicyRedirect)}))
+	}
+	if tls.CertificateSecret != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(tls.CertificateSecret) {
+			errs = append(errs, field.Invalid(tlsPath.Child("certificateSecret"), tls.CertificateSecret, msg))
+		}
+	}
+	if tls.DestinationCACertificate != "" && termination != routev1.TLSTerminationReencrypt {
+		errs = append(errs, field.Forbidden(tlsPath.Child("destinationCACertificate"), "may only be set for reencrypt termination"))
+	}
+	if termination == routev1.TLSTerminationPassthrough {
+		if route.Path != "" {
+			errs = append(errs, field.Forbidden(path.Child("path"), "may not be set for passthrough termination"))
+		}
+		if tls.CertificateSecret != "" {
+			errs = append(errs, field.Forbidden(tlsPath.Child("certificateSecret"), "may not be set for passthrough termination"))
+		}
+		if tls.InsecureEdgeTerminationPolicy == routev1.InsecureEdgeTerminationPolicyAllow {
+			errs = append(errs, field.Invalid(tlsPath.Child("insecureEdgeTerminationPolicy"), tls.InsecureEdgeTerminationPolicy, "passthrough termination only supports None and Redirect"))
+		}
+	}
+	return errs
+}
+
+func validateIntOrPercent(value *intstr.IntOrString, path *field.Path) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+	if value.Type == intstr.Int {
+		if value.IntVal < 0 {
+			return field.ErrorList{field.Invalid(path, value.IntVal, "must be greater than or equal to 0")}
+		}
+		return nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(value.StrVal, "%"))
+	if !strings.HasSuffix(value.StrVal, "%") || err != nil || percent < 0 || percent > 100 {
+		return field.ErrorList{field.Invalid(path, value.StrVal, "must be a number or a percentage between 0% and 100%")}
+	}
+	return nil
+}
+
+// isZero reports whether a surge or unavailability setting is explicitly set to 0
+func isZero(value *intstr.IntOrString) bool {
+	return value != nil && (value.Type == intstr.Int && value.IntVal == 0 || value.Type == intstr.String && value.StrVal == "0%")
+}
+
+// validatePodTemplate checks the pod settings of a WebApp that would otherwise only be rejected by the cluster
+// when the workload is applied
+func validatePodTemplate(pod v1alpha1.WebAppPodTemplate, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if pod.Resources != nil {
+		for name, request := range pod.Resources.Requests {
+			if limit, ok := pod.Resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+				errs = append(errs, field.Invalid(path.Child("resources", "requests").Key(string(name)), request.String(), fmt.Sprintf("must be less than or equal to the %s limit %s", name, limit.String())))
+			}
+		}
+	}
+	errs = append(errs, validateProbe(pod.LivenessProbe, path.Child("livenessProbe"))...)
+	errs = append(errs, validateProbe(pod.ReadinessProbe, path.Child("readinessProbe"))...)
+
+	for key, value := range pod.NodeSelector {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path.Child("nodeSelector"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(path.Child("nodeSelector").Key(key), value, msg))
+		}
+	}
+	for i, toleration := range pod.Tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				errs = append(errs, field.Invalid(path.Child("tolerations").Index(i).Child("value"), toleration.Value, "must be empty when operator is Exists"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(path.Child("tolerations").Index(i).Child("operator"), toleration.Operator, []string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}))
+		}
+	}
+	if pod.PriorityClassName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(pod.PriorityClassName) {
+			errs = append(errs, field.Invalid(path.Child("priorityClassName"), pod.PriorityClassName, msg))
+		}
+	}
+	return errs
+}
+
+func validateProbe(probe *corev1.Probe, path *field.Path) field.ErrorList {
+	if probe == nil {
+		return nil
+	}
+	handlers := 0
+	if probe.Exec != nil {
+		handlers++
+	}
+	if probe.HTTPGet != nil {
+		handlers++
+	}
+	if probe.TCPSocket != nil {
+		handlers++
+	}
+	if handlers != 1 {
+		return field.ErrorList{field.Invalid(path, probe.Handler, "exactly one of exec, httpGet and tcpSocket must be set")}
+	}
+	return nil
+}
+
+func templateSourceName(src v1alpha1.WebAppTemplate) string {
+	switch {
+	case src.ConfigMap != nil:
+		return "configMap " + src.ConfigMap.Name
+	case src.Inline != nil:
+		return "inline"
+	case src.Remote != nil:
+		return "remote " + src.Remote.URL
+	}
+	return src.Path
+}
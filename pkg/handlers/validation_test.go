@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+const requiredParamTemplate = `{"apiVersion":"template.openshift.io/v1","kind":"Template","metadata":{"name":"required"},` +
+	`"parameters":[{"name":"REQUIRED","required":true},{"name":"GENERATED","required":true,"generate":"expression","from":"[a-z]{8}"}],"objects":[]}`
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Spec          v1alpha1.WebAppSpec
+		Platform      openshift.Platform
+		ExpectedError string
+	}{
+		{
+			Name: "Valid web app",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{
+					Path:       testTemplate,
+					Parameters: map[string]string{routingSubdomain: "apps.example.com"},
+				},
+			},
+		},
+		{
+			Name: "Unknown processor and image policy",
+			Spec: v1alpha1.WebAppSpec{
+				ImagePolicy: "Sometimes",
+				Template:    v1alpha1.WebAppTemplate{Path: testTemplate, Processor: "remote"},
+			},
+			ExpectedError: "spec.template.processor: Unsupported value",
+		},
+		{
+			Name: "Invalid routing subdomain",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{
+					Path:       testTemplate,
+					Parameters: map[string]string{routingSubdomain: "Apps_Example"},
+				},
+			},
+			ExpectedError: "spec.template.parameters[ROUTING_SUBDOMAIN]: Invalid value",
+		},
+		{
+			Name: "Parameter set twice",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{
+					Path:       testTemplate,
+					Parameters: map[string]string{"OPENSHIFT_OAUTHCLIENT_ID": "id"},
+					ParametersFrom: []v1alpha1.TemplateParameterSource{
+						{
+							Name:      "OPENSHIFT_OAUTHCLIENT_ID",
+							ValueFrom: v1alpha1.ParameterValueSource{ConfigMapKeyRef: &v12.ConfigMapKeySelector{Key: "id"}},
+						},
+					},
+				},
+			},
+			ExpectedError: "spec.template.parametersFrom[0].name: Duplicate value",
+		},
+		{
+			Name: "Parameter without a reference",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{
+					Path:           testTemplate,
+					ParametersFrom: []v1alpha1.TemplateParameterSource{{Name: "OPENSHIFT_OAUTHCLIENT_ID"}},
+				},
+			},
+			ExpectedError: "exactly one of secretKeyRef and configMapKeyRef must be set",
+		},
+		{
+			Name: "Template can not be loaded",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: "/does/not/exist.yml"},
+			},
+			ExpectedError: "spec.template: Invalid value: \"/does/not/exist.yml\"",
+		},
+		{
+			Name: "Missing required parameter",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Inline: &runtime.RawExtension{Raw: []byte(requiredParamTemplate)}},
+			},
+			ExpectedError: "template required requires parameters REQUIRED",
+		},
+		{
+			Name: "Required parameter supplied",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{
+					Inline:     &runtime.RawExtension{Raw: []byte(requiredParamTemplate)},
+					Parameters: map[string]string{"REQUIRED": "value"},
+				},
+			},
+		},
+		{
+			// the mocked client panics when the config map is fetched
+			Name: "Template from a config map is not loaded",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{ConfigMap: &v1alpha1.TemplateConfigMapSource{Name: "webapp-template"}},
+			},
+		},
+		{
+			Name: "Replicas and rolling strategy on ReadWriteMany storage",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Replicas: int32Ptr(3),
+				Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRolling, MaxSurge: intOrStringPtr(intstr.FromString("25%")), MaxUnavailable: intOrStringPtr(intstr.FromInt(0))},
+				Storage:  &v1alpha1.WebAppStorage{AccessMode: v12.ReadWriteMany},
+			},
+		},
+		{
+			Name: "Replicas on ReadWriteOnce storage",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Replicas: int32Ptr(2),
+			},
+			ExpectedError: "spec.replicas: Invalid value: 2: more than one replica requires storage.accessMode ReadWriteMany",
+		},
+		{
+			Name: "Rolling strategy on ReadWriteOnce storage",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRolling},
+				Storage:  &v1alpha1.WebAppStorage{AccessMode: v12.ReadWriteOnce},
+			},
+			ExpectedError: "spec.strategy.type: Invalid value: \"Rolling\"",
+		},
+		{
+			Name: "Surge settings of the Recreate strategy",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRecreate, MaxSurge: intOrStringPtr(intstr.FromInt(1))},
+			},
+			ExpectedError: "spec.strategy: Forbidden",
+		},
+		{
+			Name: "Invalid surge percentage",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRolling, MaxSurge: intOrStringPtr(intstr.FromString("150%"))},
+				Storage:  &v1alpha1.WebAppStorage{AccessMode: v12.ReadWriteMany},
+			},
+			ExpectedError: "spec.strategy.maxSurge: Invalid value",
+		},
+		{
+			Name: "Unsupported access mode",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Storage:  &v1alpha1.WebAppStorage{AccessMode: v12.ReadOnlyMany},
+			},
+			ExpectedError: "spec.storage.accessMode: Unsupported value",
+		},
+		{
+			Name: "Storage size and class",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Storage:  &v1alpha1.WebAppStorage{Size: quantityPtr("1Gi"), StorageClassName: stringPtr("gp2")},
+			},
+		},
+		{
+			Name: "Storage size of zero",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Storage:  &v1alpha1.WebAppStorage{Size: quantityPtr("0")},
+			},
+			ExpectedError: "spec.storage.size: Invalid value: \"0\": must be greater than 0",
+		},
+		{
+			Name: "Storage class of an emptyDir",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Storage:  &v1alpha1.WebAppStorage{EmptyDir: true, StorageClassName: stringPtr("gp2")},
+			},
+			ExpectedError: "spec.storage.storageClassName: Forbidden: may not be set with emptyDir",
+		},
+		{
+			Name: "Replicas on an emptyDir",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Replicas: int32Ptr(2),
+				Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRolling},
+				Storage:  &v1alpha1.WebAppStorage{EmptyDir: true},
+			},
+			ExpectedError: "spec.replicas: Invalid value: 2: more than one replica can not share the data of an emptyDir",
+		},
+		{
+			Name: "Backups and restore",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "0 2 * * *",
+					Target:   v1alpha1.BackupLocation{S3: &v1alpha1.S3Location{Endpoint: "http://minio:9000", Bucket: "webapps", CredentialsSecret: "minio"}},
+				},
+				Restore: &v1alpha1.WebAppRestore{
+					Source: v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups", Path: "workshop"}},
+				},
+			},
+		},
+		{
+			Name: "Backup without a schedule",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Backup: &v1alpha1.WebAppBackup{
+					Target: v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups"}},
+				},
+			},
+			ExpectedError: "spec.backup.schedule: Required value",
+		},
+		{
+			Name: "Backup to two targets",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "@daily",
+					Target: v1alpha1.BackupLocation{
+						S3:                    &v1alpha1.S3Location{Endpoint: "http://minio:9000", Bucket: "webapps", CredentialsSecret: "minio"},
+						PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups"},
+					},
+				},
+			},
+			ExpectedError: "spec.backup.target: Invalid value",
+		},
+		{
+			Name: "Backup to an endpoint that is not a URL",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "@daily",
+					Target:   v1alpha1.BackupLocation{S3: &v1alpha1.S3Location{Endpoint: "minio:9000", Bucket: "webapps", CredentialsSecret: "minio"}},
+				},
+			},
+			ExpectedError: "spec.backup.target.s3.endpoint: Invalid value",
+		},
+		{
+			Name: "Backup of an emptyDir",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Storage:  &v1alpha1.WebAppStorage{EmptyDir: true},
+				Backup: &v1alpha1.WebAppBackup{
+					Schedule: "@daily",
+					Target:   v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups"}},
+				},
+			},
+			ExpectedError: "spec.backup: Forbidden: the data of an emptyDir can not be backed up",
+		},
+		{
+			Name: "Restore from outside the archives",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Restore: &v1alpha1.WebAppRestore{
+					Source: v1alpha1.BackupLocation{PersistentVolumeClaim: &v1alpha1.ClaimLocation{ClaimName: "backups", Path: "../other"}},
+				},
+			},
+			ExpectedError: "spec.restore.source.persistentVolumeClaim.path: Invalid value",
+		},
+		{
+			Name: "Pod settings",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				PodTemplate: &v1alpha1.WebAppPodTemplate{
+					Resources: &v12.ResourceRequirements{
+						Requests: v12.ResourceList{v12.ResourceMemory: resource.MustParse("256Mi")},
+						Limits:   v12.ResourceList{v12.ResourceMemory: resource.MustParse("512Mi")},
+					},
+					ReadinessProbe:    &v12.Probe{Handler: v12.Handler{HTTPGet: &v12.HTTPGetAction{Path: "/", Port: intstr.FromString("http")}}},
+					NodeSelector:      map[string]string{"node-role.kubernetes.io/compute": "true"},
+					Tolerations:       []v12.Toleration{{Key: "workshop", Operator: v12.TolerationOpExists, Effect: v12.TaintEffectNoSchedule}},
+					PriorityClassName: "workshop",
+				},
+			},
+		},
+		{
+			Name: "Request above the limit",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				PodTemplate: &v1alpha1.WebAppPodTemplate{
+					Resources: &v12.ResourceRequirements{
+						Requests: v12.ResourceList{v12.ResourceCPU: resource.MustParse("2")},
+						Limits:   v12.ResourceList{v12.ResourceCPU: resource.MustParse("500m")},
+					},
+				},
+			},
+			ExpectedError: "spec.podTemplate.resources.requests[cpu]: Invalid value: \"2\": must be less than or equal to the cpu limit 500m",
+		},
+		{
+			Name: "Probe without a handler",
+			Spec: v1alpha1.WebAppSpec{
+				Template:    v1alpha1.WebAppTemplate{Path: testTemplate},
+				PodTemplate: &v1alpha1.WebAppPodTemplate{LivenessProbe: &v12.Probe{PeriodSeconds: 10}},
+			},
+			ExpectedError: "spec.podTemplate.livenessProbe: Invalid value",
+		},
+		{
+			Name: "Toleration with a value for any value",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				PodTemplate: &v1alpha1.WebAppPodTemplate{
+					Tolerations: []v12.Toleration{{Key: "workshop", Operator: v12.TolerationOpExists, Value: "true"}},
+				},
+			},
+			ExpectedError: "spec.podTemplate.tolerations[0].value: Invalid value",
+		},
+		{
+			Name: "Route settings",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route: &v1alpha1.WebAppRoute{
+					Host:        "workshop.example.com",
+					Path:        "/tutorials",
+					Annotations: map[string]string{"haproxy.router.openshift.io/timeout": "2m"},
+					Labels:      map[string]string{"router": "public"},
+					TLS: &v1alpha1.WebAppRouteTLS{
+						Termination:                   routev1.TLSTerminationReencrypt,
+						CertificateSecret:             "workshop-tls",
+						DestinationCACertificate:      "-----BEGIN CERTIFICATE-----",
+						InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+					},
+				},
+			},
+		},
+		{
+			Name: "Route host and path",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route:    &v1alpha1.WebAppRoute{Host: "Workshop_Example", Path: "tutorials"},
+			},
+			ExpectedError: "spec.route.path: Invalid value",
+		},
+		{
+			Name: "Invalid route label",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route:    &v1alpha1.WebAppRoute{Labels: map[string]string{"router": "public shard"}},
+			},
+			ExpectedError: "spec.route.labels[router]: Invalid value",
+		},
+		{
+			Name: "Unknown route termination",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route:    &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{Termination: "offload"}},
+			},
+			ExpectedError: "spec.route.tls.termination: Unsupported value",
+		},
+		{
+			Name: "Certificate of a passthrough route",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route: &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{
+					Termination:       routev1.TLSTerminationPassthrough,
+					CertificateSecret: "workshop-tls",
+				}},
+			},
+			ExpectedError: "spec.route.tls.certificateSecret: Forbidden",
+		},
+		{
+			Name: "Destination CA of an edge route",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route:    &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{DestinationCACertificate: "-----BEGIN CERTIFICATE-----"}},
+			},
+			ExpectedError: "spec.route.tls.destinationCACertificate: Forbidden",
+		},
+		{
+			Name: "Reencrypt termination on Kubernetes",
+			Spec: v1alpha1.WebAppSpec{
+				Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				Route:    &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{Termination: routev1.TLSTerminationReencrypt}},
+			},
+			Platform:      openshift.PlatformKubernetes,
+			ExpectedError: "Ingresses only support edge termination",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			platform := tc.Platform
+			if platform == "" {
+				platform = openshift.PlatformOpenShift
+			}
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, &openshift.OSClientInterfaceMock{}, MockGetResourcesClient, &SdkCruderMock{}, platform, "", "")
+			err := wh.Validate(&v1alpha1.WebApp{Spec: tc.Spec})
+			if tc.ExpectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+				t.Fatalf("expected error containing %q, got %v", tc.ExpectedError, err)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	wh := NewWebHandler(nil, &record.FakeRecorder{}, &openshift.OSClientInterfaceMock{}, MockGetResourcesClient, &SdkCruderMock{}, openshift.PlatformOpenShift, "", "")
+	cr := &v1alpha1.WebApp{
+		Spec: v1alpha1.WebAppSpec{
+			Template: v1alpha1.WebAppTemplate{
+				Path:       testTemplate,
+				Parameters: map[string]string{"OPENSHIFT_VERSION": "4"},
+			},
+		},
+	}
+	wh.Default(cr)
+
+	if cr.Spec.AppLabel != defaultAppLabel {
+		t.Fatalf("expected app label %s, got %s", defaultAppLabel, cr.Spec.AppLabel)
+	}
+	if v := cr.Spec.Template.Parameters["OPENSHIFT_VERSION"]; v != "4" {
+		t.Fatalf("expected OPENSHIFT_VERSION to be kept, got %s", v)
+	}
+	if v := cr.Spec.Template.Parameters["WALKTHROUGH_LOCATIONS"]; !strings.HasPrefix(v, "https://github.com/integr8ly/tutorial-web-app-walkthroughs.git") {
+		t.Fatalf("expected WALKTHROUGH_LOCATIONS to be defaulted from the template, got %s", v)
+	}
+}
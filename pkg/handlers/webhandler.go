@@ -2,222 +2,358 @@ package handlers
 
 import (
 	"context"
-	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
 
-	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/metrics"
 	routev1 "github.com/openshift/api/route/v1"
-	v1 "github.com/openshift/api/template/v1"
-	"github.com/operator-framework/operator-sdk/pkg/sdk"
-	"github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
-	WTLocations               = "WALKTHROUGH_LOCATIONS"
-	IntegreatlyVersion        = "INTEGREATLY_VERSION"
-	ClusterType               = "CLUSTER_TYPE"
-	OpenShiftVersion          = "OPENSHIFT_VERSION"
-	OpenShiftAPIHost          = "OPENSHIFT_API"
-	InstalledServices         = "INSTALLED_SERVICES"
-	InstallationType          = "INSTALLATION_TYPE"
-	WTLocationsDefault        = "https://github.com/integr8ly/tutorial-web-app-walkthroughs#v1.12.3"
-	IntegreatlyVersionDefault = "not set"
-	ClusterTypeDefault        = "not set"
-	OpenShiftVersionDefault   = "3"
-	OpenShiftAPIHostDefault   = "openshift.default.svc"
-	WebAppImage               = "quay.io/integreatly/tutorial-web-app:2.28.1"
-	serviceName               = "tutorial-web-app"
-	routeName                 = "tutorial-web-app"
-	upgradeData               = "UPGRADE_DATA"
+	WebAppImage = "quay.io/integreatly/tutorial-web-app:2.28.1"
+	serviceName = "tutorial-web-app"
+	routeName   = "tutorial-web-app"
+	// solutionExplorerRouteName is the name of the route of RHMI 2.x
+	solutionExplorerRouteName = "solution-explorer"
+	// routeCAKey is the key of the CA chain in the Secret of the route certificate
+	routeCAKey = "ca.crt"
+
+	// reasons of the events recorded on a WebApp, failures are recorded with the reason of the failed condition
+	eventTemplateProcessed = "TemplateProcessed"
+	eventCreated           = "Created"
+	eventImageMigrated     = "ImageMigrated"
+	eventMigrated          = "Migrated"
+	eventEnvVarChanged     = "EnvVarChanged"
+	eventStorageResized    = "StorageResized"
+	eventBackupFailed      = "BackupFailed"
+	eventRestored          = "Restored"
+	eventRestoreFailed     = "RestoreFailed"
+	eventReady             = "Ready"
+	eventPaused            = "Paused"
+	eventResumed           = "Resumed"
+	eventDeleting          = "Deleting"
+	eventDeleteFailed      = "DeleteFailed"
+
+	// readinessRequeue is the interval in which a WebApp is reconciled until it is ready, its pods and
+	// endpoints are not owned by the WebApp and do not trigger a reconcile when they become ready
+	readinessRequeue = 10 * time.Second
 )
 
-var webappParams = [...]string{"OPENSHIFT_OAUTHCLIENT_ID", "OPENSHIFT_HOST", "OPENSHIFT_OAUTH_HOST", "SSO_ROUTE", OpenShiftAPIHost, OpenShiftVersion, IntegreatlyVersion, WTLocations, ClusterType, InstalledServices, InstallationType, upgradeData}
-
-func NewWebHandler(m *metrics.Metrics, osClient openshift.OSClientInterface, factory ClientFactory, cruder SdkCruder) AppHandler {
+func NewWebHandler(m *metrics.Metrics, recorder record.EventRecorder, osClient openshift.OSClientInterface, factory ClientFactory, cruder SdkCruder, platform openshift.Platform, defaultImage, defaultBackupImage string) AppHandler {
+	if defaultImage == "" {
+		defaultImage = WebAppImage
+	}
+	if defaultBackupImage == "" {
+		defaultBackupImage = BackupImage
+	}
 	return AppHandler{
+		platform:                     platform,
+		defaultImage:                 defaultImage,
+		defaultBackupImage:           defaultBackupImage,
+		templates:                    newTemplateLoader(osClient),
 		metrics:                      m,
+		recorder:                     recorder,
 		osClient:                     osClient,
 		dynamicResourceClientFactory: factory,
 		sdkCruder:                    cruder,
 	}
 }
 
-func (h *AppHandler) Handle(ctx context.Context, event sdk.Event) error {
-	switch o := event.Object.(type) {
-	case *v1alpha1.WebApp:
-		if o.GetDeletionTimestamp() != nil {
-			err := h.Delete(o)
-			if err != nil {
-				logrus.Errorf("Error deleting all operator related resources: %v", err)
-				h.SetStatus(err.Error(), o)
-				return err
-			}
-			return nil
-		}
-
-		if o.Status.Message == "OK" {
-			//finished provision, move to reconcile
-			err := h.reconcile(o)
-			if err != nil {
-				h.SetStatus("Error: "+err.Error(), o)
-				return err
-			}
-			h.SetStatus("OK", o)
-			return nil
-		}
+// Reconcile provisions the web app of a WebApp and brings it back to its desired state. Errors are retried
+// by the controller with a backoff, a WebApp that is not ready yet is reconciled again after readinessRequeue.
+func (h *AppHandler) Reconcile(ctx context.Context, cr *v1alpha1.WebApp) (reconcile.Result, error) {
+	start := time.Now()
+	result, err := h.reconcileWebApp(ctx, cr)
+	h.metrics.ObserveReconcile(time.Since(start), err)
+	return result, err
+}
 
-		exts, err := h.ProcessTemplate(o)
-		if err != nil {
-			logrus.Errorf("Error while processing the template: %v", err)
-			h.SetStatus(err.Error(), o)
-			return err
+func (h *AppHandler) reconcileWebApp(ctx context.Context, o *v1alpha1.WebApp) (reconcile.Result, error) {
+	if o.GetDeletionTimestamp() != nil {
+		if !o.HasFinalizer(v1alpha1.WebAppFinalizer) {
+			return reconcile.Result{}, nil
 		}
-		runtimeObjs, err := h.GetRuntimeObjs(exts)
+		err := h.Delete(o)
 		if err != nil {
-			logrus.Errorf("Error parsing the runtime objects from the template: %v", err)
-			h.SetStatus(err.Error(), o)
-			return err
+			logrus.Errorf("Error deleting all operator related resources: %v", err)
+			h.recorder.Event(o, corev1.EventTypeWarning, eventDeleteFailed, err.Error())
+			if statusErr := h.SetStatus(v1alpha1.PhaseDeleting, err.Error(), o); statusErr != nil {
+				logrus.Errorf("Error setting the status: %v", statusErr)
+			}
+			return reconcile.Result{}, err
 		}
+		return reconcile.Result{}, nil
+	}
 
-		// append route onto runtime objs list
-		route := h.CreateRoute(o)
-		runtimeObjs = append(runtimeObjs, route)
+	if isDryRun(o) {
+		return h.plan(ctx, o)
+	}
+	o.Status.Plan = nil
 
-		err = h.ProvisionObjects(runtimeObjs, o)
+	if !o.HasFinalizer(v1alpha1.WebAppFinalizer) {
+		o.AddFinalizer(v1alpha1.WebAppFinalizer)
+		err := h.sdkCruder.Update(o)
 		if err != nil {
-			logrus.Errorf("Error provisioning the runtime objects: %v", err)
-			h.SetStatus(err.Error(), o)
-			return err
-		}
-		if h.IsAppReady(o) {
-			h.SetStatus("OK", o)
-		} else {
-			h.SetStatus("", o)
+			logrus.Errorf("Error adding the finalizer: %v", err)
+			return reconcile.Result{}, err
 		}
+	}
 
-		return nil
+	if o.Spec.Paused {
+		return h.reconcilePaused(o)
 	}
+	h.resume(o)
 
-	return nil
-}
+	// errors before the objects were provisioned once keep the WebApp provisioning,
+	// afterwards they mean that the web app drifted from its desired state
+	failurePhase := v1alpha1.PhaseProvisioning
+	if isProvisioned(o) {
+		failurePhase = v1alpha1.PhaseDegraded
+	}
 
-func (h *AppHandler) reconcile(cr *v1alpha1.WebApp) error {
-	//reconcile template params into deployment config
-	dc, err := h.osClient.GetDC(cr.Namespace, "tutorial-web-app")
+	params, err := h.resolveParameters(o)
 	if err != nil {
-		return err
-	}
-	dcUpdated := false
-
-	dcUpdated, dc.Spec.Template.Spec.Containers[0] = migrateImage(dc.Spec.Template.Spec.Containers[0])
-
-	for _, param := range webappParams {
-		updated := false
-		if val, ok := cr.Spec.Template.Parameters[param]; ok {
-			updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, val)
-		} else {
-			// if WALKTHROUGH_LOCATIONS is not defined then use the default value
-			if param == WTLocations {
-				updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, WTLocationsDefault)
-			} else if param == IntegreatlyVersion {
-				updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, IntegreatlyVersionDefault)
-			} else if param == ClusterType {
-				updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, ClusterTypeDefault)
-			} else if param == OpenShiftVersion {
-				updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, OpenShiftVersionDefault)
-			} else if param == OpenShiftAPIHost {
-				updated, dc.Spec.Template.Spec.Containers[0] = updateOrCreateEnvVar(dc.Spec.Template.Spec.Containers[0], param, OpenShiftAPIHostDefault)
-			} else {
-				//key does not exist in CR, ensure it is not present in the DC
-				updated, dc.Spec.Template.Spec.Containers[0] = deleteEnvVar(dc.Spec.Template.Spec.Containers[0], param)
-			}
-		}
-		if updated && !dcUpdated {
-			dcUpdated = true
+		logrus.Errorf("Error resolving the template parameters: %v", err)
+		h.fail(o, v1alpha1.TemplateProcessed, v1alpha1.ReasonParameterResolveFailed, failurePhase, err.Error(), err)
+		return reconcile.Result{}, err
+	}
+
+	exts, err := h.ProcessTemplate(o, params.values)
+	if err != nil {
+		logrus.Errorf("Error while processing the template: %v", err)
+		h.fail(o, v1alpha1.TemplateProcessed, v1alpha1.ReasonTemplateProcessFailed, failurePhase, err.Error(), err)
+		return reconcile.Result{}, err
+	}
+
+	runtimeObjs, err := h.GetRuntimeObjs(exts)
+	if err != nil {
+		logrus.Errorf("Error parsing the runtime objects from the template: %v", err)
+		h.fail(o, v1alpha1.TemplateProcessed, v1alpha1.ReasonInvalidObjects, failurePhase, err.Error(), err)
+		return reconcile.Result{}, err
+	}
+	if !o.Status.IsConditionTrue(v1alpha1.TemplateProcessed) {
+		h.recorder.Eventf(o, corev1.EventTypeNormal, eventTemplateProcessed, "processed the template into %d objects", len(runtimeObjs))
+	}
+	o.Status.SetCondition(v1alpha1.TemplateProcessed, corev1.ConditionTrue, v1alpha1.ReasonTemplateProcessed, "", o.Generation)
+
+	runtimeObjs, err = h.reconcile(o, params, runtimeObjs)
+	if err != nil {
+		logrus.Errorf("Error reconciling the web app deployment: %v", err)
+		h.fail(o, v1alpha1.ObjectsProvisioned, v1alpha1.ReasonReconcileFailed, failurePhase, "Error: "+err.Error(), err)
+		return reconcile.Result{}, err
+	}
+
+	// append route, or ingress on Kubernetes, onto runtime objs list
+	var route runtime.Object
+	if h.platform == openshift.PlatformKubernetes {
+		route, err = h.CreateIngress(o)
+	} else {
+		route, err = h.CreateRoute(o)
+	}
+	if err != nil {
+		logrus.Errorf("Error creating the route: %v", err)
+		h.fail(o, v1alpha1.ObjectsProvisioned, v1alpha1.ReasonReconcileFailed, failurePhase, "Error: "+err.Error(), err)
+		return reconcile.Result{}, err
+	}
+	runtimeObjs = append(runtimeObjs, route)
+
+	migrated, err := h.migrate(o, runtimeObjs)
+	if err != nil {
+		logrus.Errorf("Error migrating the web app: %v", err)
+		h.fail(o, v1alpha1.ObjectsProvisioned, v1alpha1.ReasonMigrationFailed, failurePhase, err.Error(), err)
+		return reconcile.Result{}, err
+	}
+
+	err = h.provisionObjects(runtimeObjs, o, migrated)
+	if err != nil {
+		logrus.Errorf("Error provisioning the runtime objects: %v", err)
+		h.fail(o, v1alpha1.ObjectsProvisioned, v1alpha1.ReasonProvisionFailed, failurePhase, err.Error(), err)
+		return reconcile.Result{}, err
+	}
+	o.Status.SetCondition(v1alpha1.ObjectsProvisioned, corev1.ConditionTrue, v1alpha1.ReasonProvisioned, "", o.Generation)
+
+	if h.platform != openshift.PlatformKubernetes {
+		err = h.reconcileOAuthClient(o, runtimeObjs)
+		if err != nil {
+			logrus.Errorf("Error reconciling the OAuthClient: %v", err)
+			h.fail(o, v1alpha1.OAuthClientReady, v1alpha1.ReasonOAuthClientFailed, failurePhase, err.Error(), err)
+			return reconcile.Result{}, err
 		}
 	}
-	//update the DC
-	if dcUpdated {
-		logrus.Info("Updating DC")
-		return h.osClient.UpdateDC(cr.Namespace, &dc)
+	h.observeStorage(o)
+	h.observeBackup(o)
+
+	ready, err := h.setReadyStatus(o)
+	if err != nil {
+		return reconcile.Result{}, err
 	}
-	return nil
+	if !ready {
+		return reconcile.Result{RequeueAfter: readinessRequeue}, nil
+	}
+	return reconcile.Result{}, nil
 }
 
-func migrateImage(container corev1.Container) (bool, corev1.Container) {
-	if container.Image == WebAppImage {
-		return false, container
+// fail sets the failed condition and the phase of a WebApp, counts the error and records it as a warning event.
+// The reconcile is retried for the error, so a failed status update is only logged.
+func (h *AppHandler) fail(cr *v1alpha1.WebApp, condition v1alpha1.WebAppConditionType, reason string, phase v1alpha1.WebAppPhase, msg string, err error) {
+	cr.Status.SetCondition(condition, corev1.ConditionFalse, reason, err.Error(), cr.Generation)
+	h.metrics.ReconcileError(string(phase), reason)
+	h.recorder.Event(cr, corev1.EventTypeWarning, reason, err.Error())
+	if err := h.SetStatus(phase, msg, cr); err != nil {
+		logrus.Errorf("Error setting the status: %v", err)
 	}
+}
 
-	logrus.Infof("Migrating image from %v to %v", container.Image, WebAppImage)
-	container.Image = WebAppImage
-	return true, container
+// isProvisioned reports whether the objects of the template have been created. WebApps
+// handled by operator versions without conditions only carry the OK status message.
+func isProvisioned(cr *v1alpha1.WebApp) bool {
+	return cr.Status.IsConditionTrue(v1alpha1.ObjectsProvisioned) || cr.Status.Message == "OK"
 }
 
-func deleteEnvVar(container corev1.Container, envName string) (bool, corev1.Container) {
-	for k, envVar := range container.Env {
-		if envVar.Name == envName {
-			container.Env = append(container.Env[:k], container.Env[k+1:]...)
-			return true, container
-		}
+// setReadyStatus surfaces the readiness of the web app as conditions and as its URL, it reports whether
+// the web app is ready
+func (h *AppHandler) setReadyStatus(cr *v1alpha1.WebApp) (bool, error) {
+	r := h.checkReadiness(cr)
+	ready := r.ready(h.platform)
+	h.metrics.SetReady(cr.Namespace, cr.Name, ready.ok())
+	cr.Status.SetCondition(v1alpha1.DeploymentAvailable, r.deployment.status, r.deployment.reason, r.deployment.message, cr.Generation)
+	cr.Status.SetCondition(v1alpha1.RouteAdmitted, r.route.status, r.route.reason, r.route.message, cr.Generation)
+	if ready.ok() && !cr.Status.IsConditionTrue(v1alpha1.Ready) {
+		h.recorder.Eventf(cr, corev1.EventTypeNormal, eventReady, "web app is ready at %s", r.url)
+	}
+	cr.Status.SetCondition(v1alpha1.Ready, ready.status, ready.reason, ready.message, cr.Generation)
+	cr.Status.URL = r.url
+
+	if ready.ok() {
+		return true, h.SetStatus(v1alpha1.PhaseReady, "OK", cr)
 	}
-	return false, container
+	return false, h.SetStatus(v1alpha1.PhaseReconciling, ready.message, cr)
 }
 
-func updateOrCreateEnvVar(container corev1.Container, envName, envVal string) (bool, corev1.Container) {
-	for envIndex, envVar := range container.Env {
-		if envVar.Name == envName {
-			if envVar.Value != envVal {
-				// update env var with correct value
-				container.Env[envIndex].Value = envVal
-				return true, container
-			}
-			return false, container
-		}
+// reconcile applies the image, the parameter references, the replicas, the strategy, the pod settings, the
+// storage settings and the backups to the web app workload rendered from the template and returns the objects
+// to apply to the cluster. Parameter values and their defaults are applied by the template itself.
+func (h *AppHandler) reconcile(cr *v1alpha1.WebApp, params *templateParameters, objects []runtime.Object) ([]runtime.Object, error) {
+	wl, err := findWorkload(objects, "tutorial-web-app")
+	if err != nil {
+		return nil, err
+	}
+	if len(wl.template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("%s tutorial-web-app has no containers", wl.kind)
 	}
+	container := &wl.template.Spec.Containers[0]
 
-	//create new env var with correct value
-	container.Env = append(container.Env, corev1.EnvVar{Name: envName, Value: envVal})
+	image, err := h.reconcileImage(cr, wl, container.Image)
+	if err != nil {
+		return nil, err
+	}
+	container.Image = image
+	version := imageVersion(image)
+	cr.Status.Version = version
+	h.metrics.SetWebAppVersion(cr.Namespace, cr.Name, image, version)
+	*container = projectSecretParameters(*container, params)
+
+	if cr.Spec.Replicas != nil {
+		wl.setReplicas(*cr.Spec.Replicas)
+	}
+	if cr.Spec.Strategy != nil {
+		wl.setStrategy(*cr.Spec.Strategy)
+	}
+	if cr.Spec.PodTemplate != nil {
+		wl.setPodTemplate(*cr.Spec.PodTemplate)
+	}
+	objects, err = h.reconcileStorage(cr, wl, objects)
+	if err != nil {
+		return nil, err
+	}
+	objects, err = h.reconcileBackup(cr, wl, objects)
+	if err != nil {
+		return nil, err
+	}
 
-	return true, container
+	if params.hash != "" {
+		if wl.template.Annotations == nil {
+			wl.template.Annotations = map[string]string{}
+		}
+		wl.template.Annotations[parametersHashAnnotation] = params.hash
+	}
+
+	return objects, nil
 }
 
+// Delete releases the resources that are not garbage collected through owner references and removes the
+// finalizer, the namespaced objects are deleted by the garbage collector together with the WebApp
 func (h *AppHandler) Delete(cr *v1alpha1.WebApp) error {
-	return h.osClient.Delete(cr.Namespace, cr.Spec.AppLabel)
+	if cr.Status.OAuthClient != "" {
+		if err := h.deleteOAuthClient(cr, cr.Status.OAuthClient); err != nil {
+			return err
+		}
+	}
+	h.recorder.Event(cr, corev1.EventTypeNormal, eventDeleting, "removing the finalizer, the web app objects are garbage collected")
+	h.metrics.Forget(cr.Namespace, cr.Name)
+	cr.RemoveFinalizer(v1alpha1.WebAppFinalizer)
+	return h.sdkCruder.Update(cr)
 }
 
-func (h *AppHandler) SetStatus(msg string, cr *v1alpha1.WebApp) {
+func (h *AppHandler) SetStatus(phase v1alpha1.WebAppPhase, msg string, cr *v1alpha1.WebApp) error {
+	cr.Status.Phase = phase
 	cr.Status.Message = msg
-	imgParts := strings.Split(WebAppImage, ":")
-	cr.Status.Version = imgParts[len(imgParts)-1]
-	h.sdkCruder.Update(cr)
+	cr.Status.ObservedGeneration = cr.Generation
+	if err := h.sdkCruder.UpdateStatus(cr); err != nil {
+		return fmt.Errorf("failed to update the status of WebApp %s/%s: %v", cr.Namespace, cr.Name, err)
+	}
+	return nil
 }
 
-func (h *AppHandler) ProcessTemplate(cr *v1alpha1.WebApp) ([]runtime.RawExtension, error) {
-	tmplPath := cr.Spec.Template.Path
-	res, err := openshift.LoadKubernetesResourceFromFile(tmplPath)
+func (h *AppHandler) ProcessTemplate(cr *v1alpha1.WebApp, params map[string]string) ([]runtime.RawExtension, error) {
+	tmpl, err := h.templates.load(cr)
+	if err != nil {
+		return nil, err
+	}
+	params, err = h.generatedParameters(cr, tmpl, params)
 	if err != nil {
 		return nil, err
 	}
 
-	params := make(map[string]string)
-	for k, v := range cr.Spec.Template.Parameters {
-		params[k] = v
+	processor := cr.Spec.Template.Processor
+	if processor == "" && h.platform == openshift.PlatformKubernetes {
+		// the processedtemplates API is only served by OpenShift
+		processor = v1alpha1.TemplateProcessorLocal
 	}
 
-	tmpl := res.(*v1.Template)
-	return h.osClient.ProcessTemplate(tmpl, params, openshift.TemplateDefaultOpts)
+	opts := openshift.TemplateDefaultOpts
+	switch processor {
+	case "", v1alpha1.TemplateProcessorServer:
+		if h.platform == openshift.PlatformKubernetes {
+			return nil, fmt.Errorf("template processor %q requires OpenShift", processor)
+		}
+	case v1alpha1.TemplateProcessorLocal:
+		opts = openshift.TemplateLocalOpts
+	default:
+		return nil, fmt.Errorf("unknown template processor %q", cr.Spec.Template.Processor)
+	}
+	opts.Namespace = cr.Namespace
+	if processor == "" {
+		processor = v1alpha1.TemplateProcessorServer
+	}
+	defer func(start time.Time) {
+		h.metrics.ObserveTemplateProcessing(string(processor), time.Since(start))
+	}(time.Now())
+	return h.osClient.ProcessTemplate(tmpl, params, opts)
 }
 
 func (h *AppHandler) GetRuntimeObjs(exts []runtime.RawExtension) ([]runtime.Object, error) {
@@ -233,74 +369,213 @@ func (h *AppHandler) GetRuntimeObjs(exts []runtime.RawExtension) ([]runtime.Obje
 	return objects, nil
 }
 
-func (h *AppHandler) CreateRoute(cr *v1alpha1.WebApp) *routev1.Route {
+// CreateRoute renders the route of the web app, an edge terminated route to the service unless spec.route
+// configures it otherwise
+func (h *AppHandler) CreateRoute(cr *v1alpha1.WebApp) (*routev1.Route, error) {
+	tls, err := h.routeTLS(cr)
+	if err != nil {
+		return nil, err
+	}
 	route := &routev1.Route{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Route",
 			APIVersion: "route.openshift.io/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      routeName,
-			Namespace: cr.Namespace,
-			Labels:    cr.GetLabels(),
+			Name:        routeNameForCR(cr),
+			Namespace:   cr.Namespace,
+			Labels:      routeLabels(cr),
+			Annotations: routeAnnotations(cr),
 		},
 		Spec: routev1.RouteSpec{
-			TLS: &routev1.TLSConfig{
-				Termination: routev1.TLSTerminationEdge,
-			},
+			Host: routeHostForCR(cr),
+			Path: routePath(cr),
+			TLS:  tls,
 			To: routev1.RouteTargetReference{
 				Kind: "Service",
 				Name: serviceName,
 			},
 		},
 	}
-	subdomain := cr.Spec.Template.Parameters["ROUTING_SUBDOMAIN"]
 
-	// Only set the host when the routing subdomain is set (RHMI 2.x). In 1.x we want to
-	// make sure to not change the existing route hosts because the cluster CORS settings
-	// depend on it
-	if subdomain != "" {
-		routeName := "solution-explorer"
-		route.Name = routeName
-		route.Spec.Host = fmt.Sprintf("%s.%s", routeName, subdomain)
-	}
-	return route
+	return route, nil
 }
 
-func (h *AppHandler) ProvisionObjects(objects []runtime.Object, cr *v1alpha1.WebApp) error {
-	for _, o := range objects {
-		gvk := o.GetObjectKind().GroupVersionKind()
-		apiVersion, kind := gvk.ToAPIVersionAndKind()
-		gvkStr := gvk.String()
+// routeTLS returns the TLS settings of the route, the certificate and the key are read from the Secret on every
+// reconcile so a renewed certificate is picked up on the next resync
+func (h *AppHandler) routeTLS(cr *v1alpha1.WebApp) (*routev1.TLSConfig, error) {
+	tls := &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}
+	if cr.Spec.Route == nil || cr.Spec.Route.TLS == nil {
+		return tls, nil
+	}
+	spec := cr.Spec.Route.TLS
+	if spec.Termination != "" {
+		tls.Termination = spec.Termination
+	}
+	tls.InsecureEdgeTerminationPolicy = spec.InsecureEdgeTerminationPolicy
+	if tls.Termination == routev1.TLSTerminationReencrypt {
+		tls.DestinationCACertificate = spec.DestinationCACertificate
+	}
+	if spec.CertificateSecret == "" || tls.Termination == routev1.TLSTerminationPassthrough {
+		return tls, nil
+	}
 
-		resourceClient, _, err := h.dynamicResourceClientFactory(apiVersion, kind, cr.Namespace)
-		if err != nil {
-			return errors.New(fmt.Sprintf("failed to get resource client: %v", err))
+	secret, err := h.osClient.GetSecret(cr.Namespace, spec.CertificateSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s for the route certificate: %v", spec.CertificateSecret, err)
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, fmt.Errorf("secret %s for the route certificate has no %s", spec.CertificateSecret, key)
 		}
+	}
+	tls.Certificate = string(secret.Data[corev1.TLSCertKey])
+	tls.Key = string(secret.Data[corev1.TLSPrivateKeyKey])
+	tls.CACertificate = string(secret.Data[routeCAKey])
+	return tls, nil
+}
 
-		unstructObj, err := k8sutil.UnstructuredFromRuntimeObject(o)
-		if err != nil {
-			return fmt.Errorf("%v failed to turn runtime object %s into unstructured object during provision", err, gvkStr)
-		}
+// CreateIngress renders the Ingress that serves the web app on Kubernetes, ingress controllers only
+// terminate TLS at the edge
+func (h *AppHandler) CreateIngress(cr *v1alpha1.WebApp) (*extv1beta1.Ingress, error) {
+	host := routeHostForCR(cr)
+	ingress := &extv1beta1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "extensions/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        routeNameForCR(cr),
+			Namespace:   cr.Namespace,
+			Labels:      routeLabels(cr),
+			Annotations: routeAnnotations(cr),
+		},
+		Spec: extv1beta1.IngressSpec{
+			Rules: []extv1beta1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: extv1beta1.IngressRuleValue{
+						HTTP: &extv1beta1.HTTPIngressRuleValue{
+							Paths: []extv1beta1.HTTPIngressPath{
+								{
+									Path: routePath(cr),
+									Backend: extv1beta1.IngressBackend{
+										ServiceName: serviceName,
+										ServicePort: intstr.FromString("http"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
 
-		unstructObj, err = resourceClient.Create(unstructObj)
-		if err != nil && !errors2.IsAlreadyExists(err) {
-			return fmt.Errorf("%v failed to create object during provision with kind ", err)
+	secretName := ""
+	if cr.Spec.Route != nil && cr.Spec.Route.TLS != nil {
+		tls := cr.Spec.Route.TLS
+		if tls.Termination != "" && tls.Termination != routev1.TLSTerminationEdge {
+			return nil, fmt.Errorf("route termination %s requires OpenShift", tls.Termination)
 		}
+		secretName = tls.CertificateSecret
+	}
+	if host != "" {
+		ingress.Spec.TLS = []extv1beta1.IngressTLS{{Hosts: []string{host}, SecretName: secretName}}
 	}
 
-	return nil
+	return ingress, nil
 }
 
-func (h *AppHandler) IsAppReady(cr *v1alpha1.WebApp) bool {
-	pod, err := h.osClient.GetPod(cr.Namespace, cr.Spec.AppLabel)
-	if err != nil {
-		return false
+// routeLabels are the labels of the WebApp with the labels of spec.route on top
+func routeLabels(cr *v1alpha1.WebApp) map[string]string {
+	if cr.Spec.Route == nil || len(cr.Spec.Route.Labels) == 0 {
+		return cr.GetLabels()
+	}
+	labels := map[string]string{}
+	for k, v := range cr.GetLabels() {
+		labels[k] = v
 	}
+	for k, v := range cr.Spec.Route.Labels {
+		labels[k] = v
+	}
+	return labels
+}
 
-	if pod.Status.Phase == corev1.PodRunning {
-		return true
+func routeAnnotations(cr *v1alpha1.WebApp) map[string]string {
+	if cr.Spec.Route == nil {
+		return nil
 	}
+	return cr.Spec.Route.Annotations
+}
+
+func routePath(cr *v1alpha1.WebApp) string {
+	if cr.Spec.Route == nil {
+		return ""
+	}
+	return cr.Spec.Route.Path
+}
+
+// RHMI 2.x sets the routing subdomain and exposes the web app as the solution explorer
+func routeNameForCR(cr *v1alpha1.WebApp) string {
+	if cr.Spec.Template.Parameters[routingSubdomain] != "" {
+		return solutionExplorerRouteName
+	}
+	return routeName
+}
+
+// Only set the host when it is configured or the routing subdomain is set (RHMI 2.x). In 1.x
+// we want to make sure to not change the existing route hosts because the cluster CORS
+// settings depend on it
+func routeHostForCR(cr *v1alpha1.WebApp) string {
+	if cr.Spec.Route != nil && cr.Spec.Route.Host != "" {
+		return cr.Spec.Route.Host
+	}
+	subdomain := cr.Spec.Template.Parameters[routingSubdomain]
+	if subdomain == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s", routeNameForCR(cr), subdomain)
+}
 
-	return false
+// ProvisionObjects creates the objects that are missing and brings drifted objects back to the
+// state rendered from the template
+func (h *AppHandler) ProvisionObjects(objects []runtime.Object, cr *v1alpha1.WebApp) error {
+	return h.provisionObjects(objects, cr, nil)
+}
+
+// provisionObjects provisions the objects, the patch of the workload removes the environment variables
+// migrated away from. The migrations are recorded once the workload was applied.
+func (h *AppHandler) provisionObjects(objects []runtime.Object, cr *v1alpha1.WebApp, migrated *migrationResult) error {
+	for _, o := range objects {
+		workload := migrated != nil && o == migrated.workload
+		var legacyEnv []string
+		if workload {
+			legacyEnv = migrated.legacyEnv
+		}
+		result, err := h.applyObject(o, cr, legacyEnv)
+		if err != nil {
+			return err
+		}
+		if workload {
+			h.recordMigrations(cr, migrated)
+		}
+		kind := o.GetObjectKind().GroupVersionKind().Kind
+		if result.changed() {
+			logrus.Infof("Applied %s to namespace %s", kind, cr.Namespace)
+		}
+		if result.created {
+			h.recorder.Eventf(cr, corev1.EventTypeNormal, eventCreated, "created %s %s", kind, result.name)
+		}
+		if len(result.env) > 0 {
+			// only the names, values may hold secrets
+			h.recorder.Eventf(cr, corev1.EventTypeNormal, eventEnvVarChanged, "changed environment variables %s of %s %s", strings.Join(result.env, ", "), kind, result.name)
+		}
+	}
+
+	return nil
+}
+
+func (h *AppHandler) IsAppReady(cr *v1alpha1.WebApp) bool {
+	return h.checkReadiness(cr).ready(h.platform).ok()
 }
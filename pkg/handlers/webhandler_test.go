@@ -3,116 +3,943 @@ package handlers
 import (
 	"context"
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/openshift"
+	_ "github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/resources"
 	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
 	v1 "github.com/openshift/api/apps/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	v1template "github.com/openshift/api/template/v1"
 	"github.com/operator-framework/operator-sdk/pkg/sdk"
+	k8sappsv1 "k8s.io/api/apps/v1"
 	v12 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
 )
 
 func MockGetResourcesClient(_, _, _ string) (dynamic.ResourceInterface, string, error) {
 	return nil, "", nil
 }
 
+const (
+	testTemplate           = "../../deploy/template/tutorial-web-app.yml"
+	testKubernetesTemplate = "../../deploy/template/tutorial-web-app-kubernetes.yml"
+	testDigestImage        = "quay.io/integreatly/tutorial-web-app@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+)
+
+func processLocally(tmpl *v1template.Template, params map[string]string, opts openshift.TemplateOpt) ([]runtime.RawExtension, error) {
+	return openshift.NewLocalTemplate("test").Process(tmpl, params, opts)
+}
+
+// podPhase returns a pod of the latest version in the given phase, running pods passed their readiness probes
+func podPhase(phase v12.PodPhase) func(string, string) ([]v12.Pod, error) {
+	return func(ns string, selector string) ([]v12.Pod, error) {
+		pod := v12.Pod{Status: v12.PodStatus{Phase: phase}}
+		if phase == v12.PodRunning {
+			pod.Status.Conditions = []v12.PodCondition{{Type: v12.PodReady, Status: v12.ConditionTrue}}
+		}
+		return []v12.Pod{pod}, nil
+	}
+}
+
+func serviceEndpoints(ns string, name string) (v12.Endpoints, error) {
+	return v12.Endpoints{Subsets: []v12.EndpointSubset{{Addresses: []v12.EndpointAddress{{IP: "172.1.0.3"}}}}}, nil
+}
+
+func workshopPod() *v1alpha1.WebAppPodTemplate {
+	return &v1alpha1.WebAppPodTemplate{
+		Resources: &v12.ResourceRequirements{
+			Requests: v12.ResourceList{v12.ResourceMemory: resource.MustParse("256Mi")},
+			Limits:   v12.ResourceList{v12.ResourceMemory: resource.MustParse("512Mi")},
+		},
+		ReadinessProbe:    &v12.Probe{Handler: v12.Handler{HTTPGet: &v12.HTTPGetAction{Path: "/", Port: intstr.FromString("http")}}},
+		NodeSelector:      map[string]string{"node-role.kubernetes.io/compute": "true"},
+		Tolerations:       []v12.Toleration{{Key: "workshop", Operator: v12.TolerationOpExists, Effect: v12.TaintEffectNoSchedule}},
+		PriorityClassName: "workshop",
+	}
+}
+
+// liveDC returns a DC running the given image, or a not found error when the image is empty
+func liveDC(image string) func(string, string) (v1.DeploymentConfig, error) {
+	return func(ns string, name string) (v1.DeploymentConfig, error) {
+		if image == "" {
+			return v1.DeploymentConfig{}, errors2.NewNotFound(v1.Resource("deploymentconfigs"), name)
+		}
+		return v1.DeploymentConfig{
+			Spec: v1.DeploymentConfigSpec{
+				Template: &v12.PodTemplateSpec{
+					Spec: v12.PodSpec{
+						Containers: []v12.Container{{Image: image}},
+					},
+				},
+			},
+		}, nil
+	}
+}
+
+func containerEnv(obj *unstructured.Unstructured, name string) (string, bool) {
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return "", false
+	}
+	env, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "env")
+	for _, e := range env {
+		if e.(map[string]interface{})["name"] == name {
+			value, _ := e.(map[string]interface{})["value"].(string)
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func containerImage(obj *unstructured.Unstructured) string {
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return ""
+	}
+	image, _ := containers[0].(map[string]interface{})["image"].(string)
+	return image
+}
+
 func TestReconcile(t *testing.T) {
 	cases := []struct {
-		Name            string
-		Event           sdk.Event
-		OSClient        func() *openshift.OSClientInterfaceMock
-		SDKCruder       func() SdkCruder
-		ExpectedMessage string
-		Verify          func(*v1alpha1.WebApp, *testing.T)
+		Name         string
+		Platform     openshift.Platform
+		DefaultImage string
+		WebApp       *v1alpha1.WebApp
+		// Prepare runs after the web app was provisioned once
+		Prepare  func(*fakeCluster, *v1alpha1.WebApp)
+		OSClient func() *openshift.OSClientInterfaceMock
+		Verify   func(*v1alpha1.WebApp, *fakeCluster, *testing.T)
 	}{
 		{
-			Name: "Update DC",
-			Event: sdk.Event{
-				Object: &v1alpha1.WebApp{
-					Spec: v1alpha1.WebAppSpec{
-						Template: v1alpha1.WebAppTemplate{
-							Parameters: map[string]string{
-								"OPENSHIFT_OAUTHCLIENT_ID": "test-value",
-							},
+			Name: "Provision all objects",
+			WebApp: &v1alpha1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "tutorial-web-app",
+					UID:  "5f3c3e1e-0c4d-4d0b-9a4b-1b7e2f6c9d10",
+				},
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+						Parameters: map[string]string{
+							"OPENSHIFT_OAUTHCLIENT_ID": "test-value",
 						},
 					},
-					Status: v1alpha1.WebAppStatus{
-						Message: "OK",
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Message != "OK" {
+					t.Fatalf("expected status OK, got %s", wa.Status.Message)
+				}
+				if wa.Status.Phase != v1alpha1.PhaseReady {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReady, wa.Status.Phase)
+				}
+				if !wa.Status.IsConditionTrue(v1alpha1.DeploymentAvailable) {
+					t.Fatalf("expected condition %s to be true, got %v", v1alpha1.DeploymentAvailable, wa.Status.Conditions)
+				}
+				for _, key := range []string{"DeploymentConfig/tutorial-web-app", "Service/tutorial-web-app", "PersistentVolumeClaim/user-walkthroughs", "Route/tutorial-web-app"} {
+					if _, ok := cluster.objects[key]; !ok {
+						t.Fatalf("expected %s to be provisioned, got %v", key, cluster.objects)
+					}
+				}
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				if val, _ := containerEnv(dc, "OPENSHIFT_OAUTHCLIENT_ID"); val != "test-value" {
+					t.Fatalf("expected OPENSHIFT_OAUTHCLIENT_ID to be test-value, got %s", val)
+				}
+				// parameters that are not set in the CR use the default of the template
+				if val, _ := containerEnv(dc, "WALKTHROUGH_LOCATIONS"); val != "https://github.com/integr8ly/tutorial-web-app-walkthroughs.git#v1.12.3" {
+					t.Fatalf("expected WALKTHROUGH_LOCATIONS to be the template default, got %s", val)
+				}
+				if val, _ := containerEnv(dc, "INTEGREATLY_VERSION"); val != "not set" {
+					t.Fatalf("expected INTEGREATLY_VERSION to be the template default, got %s", val)
+				}
+				if cluster.patches != 0 {
+					t.Fatalf("expected no patches, got %d", cluster.patches)
+				}
+				if !wa.HasFinalizer(v1alpha1.WebAppFinalizer) {
+					t.Fatalf("expected finalizer %s, got %v", v1alpha1.WebAppFinalizer, wa.GetFinalizers())
+				}
+				for key, obj := range cluster.objects {
+					if obj.GetKind() == "OAuthClient" {
+						// cluster scoped, it is deleted by the finalizer
+						continue
+					}
+					refs := obj.GetOwnerReferences()
+					if len(refs) != 1 || refs[0].Kind != "WebApp" || refs[0].Name != wa.Name || refs[0].Controller == nil || !*refs[0].Controller {
+						t.Fatalf("expected %s to be controlled by the WebApp, got %v", key, refs)
+					}
+				}
+			},
+		},
+		{
+			Name: "Objects in sync are not patched",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
 					},
 				},
 			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				// fields defaulted by the cluster are not drift
+				unstructured.SetNestedField(cluster.get("Service", "tutorial-web-app").Object, "172.30.0.1", "spec", "clusterIP")
+			},
 			OSClient: func() *openshift.OSClientInterfaceMock {
 				return &openshift.OSClientInterfaceMock{
-					GetDCFunc: func(ns string, dcName string) (v1.DeploymentConfig, error) {
-						return v1.DeploymentConfig{
-							Spec: v1.DeploymentConfigSpec{
-								Template: &v12.PodTemplateSpec{
-									Spec: v12.PodSpec{
-										Containers: []v12.Container{
-											{
-												Env: []v12.EnvVar{},
-											},
-										},
-									},
-								},
-							},
-						}, nil
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Phase != v1alpha1.PhaseReady {
+					t.Fatalf("expected phase %s, got %s: %s", v1alpha1.PhaseReady, wa.Status.Phase, wa.Status.Message)
+				}
+				if cluster.patches != 0 {
+					t.Fatalf("expected no patches, got %d", cluster.patches)
+				}
+			},
+		},
+		{
+			Name: "Recreate deleted object",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
 					},
-					UpdateDCFunc: func(ns string, dc *v1.DeploymentConfig) error {
-						return nil
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				delete(cluster.objects, "Service/tutorial-web-app")
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.get("Service", "tutorial-web-app") == nil {
+					t.Fatalf("expected service to be recreated")
+				}
+				if wa.Status.Phase != v1alpha1.PhaseReady {
+					t.Fatalf("expected phase %s, got %s: %s", v1alpha1.PhaseReady, wa.Status.Phase, wa.Status.Message)
+				}
+			},
+		},
+		{
+			Name: "Restore drifted DC",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+						Parameters: map[string]string{
+							"OPENSHIFT_OAUTHCLIENT_ID": "test-value",
+						},
+					},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				containers, _, _ := unstructured.NestedSlice(dc.Object, "spec", "template", "spec", "containers")
+				containers[0].(map[string]interface{})["image"] = "someDifferentImage"
+				containers[0].(map[string]interface{})["env"] = []interface{}{}
+				unstructured.SetNestedSlice(dc.Object, containers, "spec", "template", "spec", "containers")
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if cluster.patches != 1 {
+					t.Fatalf("expected the DC to be patched once, got %d patches", cluster.patches)
+				}
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				if image := containerImage(dc); image != WebAppImage {
+					t.Fatalf("expected image %s, got %s", WebAppImage, image)
+				}
+				if val, _ := containerEnv(dc, "OPENSHIFT_OAUTHCLIENT_ID"); val != "test-value" {
+					t.Fatalf("expected OPENSHIFT_OAUTHCLIENT_ID to be test-value, got %s", val)
+				}
+			},
+		},
+		{
+			Name: "Remove param deleted from the CR",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+						Parameters: map[string]string{
+							"INSTALLATION_TYPE": "managed",
+						},
 					},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				delete(wa.Spec.Template.Parameters, "INSTALLATION_TYPE")
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
 				}
 			},
-			SDKCruder: func() SdkCruder {
-				return &SdkCruderMock{
-					UpdateFunc: func(object sdk.Object) error {
-						return nil
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				if val, _ := containerEnv(dc, "INSTALLATION_TYPE"); val != "" {
+					t.Fatalf("expected INSTALLATION_TYPE to be reset to the template default, got %s", val)
+				}
+			},
+		},
+		{
+			Name: "Update any template parameter",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
 					},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				wa.Spec.Template.Parameters = map[string]string{
+					"DATABASE_LOCATION": "/data/walkthroughs",
+					"UPGRADE_DATA":      `{"scheduled":true}`,
 				}
 			},
-			Verify: func(wa *v1alpha1.WebApp, t *testing.T) {
-				if wa.Status.Message != "OK" {
-					t.Fatalf("expected status OK, got %s", wa.Status.Message)
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				if val, _ := containerEnv(dc, "DATABASE_LOCATION"); val != "/data/walkthroughs" {
+					t.Fatalf("expected DATABASE_LOCATION to be updated, got %s", val)
+				}
+				if val, _ := containerEnv(dc, "UPGRADE_DATA"); val != `{"scheduled":true}` {
+					t.Fatalf("expected UPGRADE_DATA to be updated, got %s", val)
+				}
+				containers, _, _ := unstructured.NestedSlice(dc.Object, "spec", "template", "spec", "containers")
+				mounts, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "volumeMounts")
+				if path := mounts[0].(map[string]interface{})["mountPath"]; path != "/data/walkthroughs" {
+					t.Fatalf("expected the volume to be mounted at the new location, got %v", path)
 				}
 			},
 		},
 		{
-			Name: "DC missing",
-			Event: sdk.Event{
-				Object: &v1alpha1.WebApp{
-					Spec: v1alpha1.WebAppSpec{
-						Template: v1alpha1.WebAppTemplate{
-							Parameters: map[string]string{
-								"OPENSHIFT_OAUTHCLIENT_ID": "test-value",
-							},
+			Name: "Scale out with a rolling strategy on ReadWriteMany storage",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+					Replicas: int32Ptr(3),
+					Strategy: &v1alpha1.WebAppStrategy{Type: v1alpha1.StrategyRolling, MaxSurge: intOrStringPtr(intstr.FromString("50%"))},
+					Storage:  &v1alpha1.WebAppStorage{AccessMode: v12.ReadWriteMany},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				if replicas, _, _ := unstructured.NestedFieldNoCopy(dc.Object, "spec", "replicas"); replicas != float64(3) {
+					t.Fatalf("expected 3 replicas, got %v", replicas)
+				}
+				strategy, _, _ := unstructured.NestedMap(dc.Object, "spec", "strategy")
+				if strategy["type"] != "Rolling" || strategy["recreateParams"] != nil {
+					t.Fatalf("expected a Rolling strategy without recreate params, got %v", strategy)
+				}
+				if surge, _, _ := unstructured.NestedString(strategy, "rollingParams", "maxSurge"); surge != "50%" {
+					t.Fatalf("expected maxSurge 50%%, got %v", strategy)
+				}
+				pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs")
+				if modes, _, _ := unstructured.NestedStringSlice(pvc.Object, "spec", "accessModes"); len(modes) != 1 || modes[0] != "ReadWriteMany" {
+					t.Fatalf("expected the claim to be ReadWriteMany, got %v", modes)
+				}
+				// one ready pod is not enough for three replicas
+				if wa.Status.Phase != v1alpha1.PhaseReconciling {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReconciling, wa.Status.Phase)
+				}
+			},
+		},
+		{
+			Name: "Access mode of an existing claim is not changed",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				wa.Spec.Storage = &v1alpha1.WebAppStorage{AccessMode: v12.ReadWriteMany}
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				c := wa.Status.GetCondition(v1alpha1.ObjectsProvisioned)
+				if c == nil || c.Reason != v1alpha1.ReasonReconcileFailed || !strings.Contains(c.Message, "has to be deleted") {
+					t.Fatalf("expected condition %s with reason %s, got %v", v1alpha1.ObjectsProvisioned, v1alpha1.ReasonReconcileFailed, c)
+				}
+				if cluster.patches != 0 {
+					t.Fatalf("expected no object to be patched, got %d patches", cluster.patches)
+				}
+			},
+		},
+		{
+			Name: "Provision the claim with the size and storage class of the CR",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+					Storage:  &v1alpha1.WebAppStorage{Size: quantityPtr("1Gi"), StorageClassName: stringPtr("gp2")},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs")
+				if size, _, _ := unstructured.NestedString(pvc.Object, "spec", "resources", "requests", "storage"); size != "1Gi" {
+					t.Fatalf("expected the claim to request 1Gi, got %s", size)
+				}
+				if class, _, _ := unstructured.NestedString(pvc.Object, "spec", "storageClassName"); class != "gp2" {
+					t.Fatalf("expected storage class gp2, got %s", class)
+				}
+				storage := wa.Status.Storage
+				if storage == nil || storage.ClaimName != "user-walkthroughs" || storage.Requested == nil || storage.Requested.String() != "1Gi" {
+					t.Fatalf("expected the storage status of claim user-walkthroughs requesting 1Gi, got %+v", storage)
+				}
+			},
+		},
+		{
+			Name: "Expand the claim when its storage class allows it",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				unstructured.SetNestedField(cluster.get("PersistentVolumeClaim", "user-walkthroughs").Object, "expandable", "spec", "storageClassName")
+				cluster.objects["StorageClass/expandable"] = &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion":           "storage.k8s.io/v1",
+					"kind":                 "StorageClass",
+					"metadata":             map[string]interface{}{"name": "expandable"},
+					"allowVolumeExpansion": true,
+				}}
+				wa.Spec.Storage = &v1alpha1.WebAppStorage{Size: quantityPtr("1Gi")}
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs")
+				if size, _, _ := unstructured.NestedString(pvc.Object, "spec", "resources", "requests", "storage"); size != "1Gi" {
+					t.Fatalf("expected the claim to be expanded to 1Gi, got %s", size)
+				}
+				if wa.Status.Storage == nil || wa.Status.Storage.Message != "" {
+					t.Fatalf("expected a storage status without message, got %+v", wa.Status.Storage)
+				}
+			},
+		},
+		{
+			Name: "Keep the size of a claim whose storage class does not allow expansion",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+					Storage:  &v1alpha1.WebAppStorage{Size: quantityPtr("200Mi"), StorageClassName: stringPtr("standard")},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				cluster.objects["StorageClass/standard"] = &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "storage.k8s.io/v1",
+					"kind":       "StorageClass",
+					"metadata":   map[string]interface{}{"name": "standard"},
+				}}
+				wa.Spec.Storage.Size = quantityPtr("1Gi")
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs")
+				if size, _, _ := unstructured.NestedString(pvc.Object, "spec", "resources", "requests", "storage"); size != "200Mi" {
+					t.Fatalf("expected the claim to keep 200Mi, got %s", size)
+				}
+				if wa.Status.Storage == nil || !strings.Contains(wa.Status.Storage.Message, "StorageClass standard does not allow volume expansion") {
+					t.Fatalf("expected the storage status to explain the size, got %+v", wa.Status.Storage)
+				}
+				if wa.Status.Phase != v1alpha1.PhaseReady {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReady, wa.Status.Phase)
+				}
+			},
+		},
+		{
+			Name: "Claims are not shrunk",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				wa.Spec.Storage = &v1alpha1.WebAppStorage{Size: quantityPtr("50Mi")}
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs")
+				if size, _, _ := unstructured.NestedString(pvc.Object, "spec", "resources", "requests", "storage"); size != "100Mi" {
+					t.Fatalf("expected the claim to keep 100Mi, got %s", size)
+				}
+				if wa.Status.Storage == nil || !strings.Contains(wa.Status.Storage.Message, "requests 100Mi and can not be shrunk to 50Mi") {
+					t.Fatalf("expected the storage status to explain the size, got %+v", wa.Status.Storage)
+				}
+			},
+		},
+		{
+			Name: "Keep the data in an emptyDir",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{Path: testTemplate},
+					Storage:  &v1alpha1.WebAppStorage{EmptyDir: true, Size: quantityPtr("200Mi")},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if pvc := cluster.get("PersistentVolumeClaim", "user-walkthroughs"); pvc != nil {
+					t.Fatalf("expected no claim to be provisioned, got %v", pvc)
+				}
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				volumes, _, _ := unstructured.NestedSlice(dc.Object, "spec", "template", "spec", "volumes")
+				if len(volumes) != 1 {
+					t.Fatalf("expected one volume, got %v", volumes)
+				}
+				volume := volumes[0].(map[string]interface{})
+				if limit, _, _ := unstructured.NestedString(volume, "emptyDir", "sizeLimit"); limit != "200Mi" || volume["persistentVolumeClaim"] != nil {
+					t.Fatalf("expected an emptyDir limited to 200Mi, got %v", volume)
+				}
+				if wa.Status.Storage == nil || !wa.Status.Storage.EmptyDir {
+					t.Fatalf("expected the storage status of an emptyDir, got %+v", wa.Status.Storage)
+				}
+			},
+		},
+		{
+			Name: "Apply the pod settings of the CR",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template:    v1alpha1.WebAppTemplate{Path: testTemplate},
+					PodTemplate: workshopPod(),
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				pod, _, _ := unstructured.NestedMap(dc.Object, "spec", "template", "spec")
+				containers := pod["containers"].([]interface{})
+				container := containers[0].(map[string]interface{})
+				if memory, _, _ := unstructured.NestedString(container, "resources", "limits", "memory"); memory != "512Mi" {
+					t.Fatalf("expected a memory limit of 512Mi, got %v", container["resources"])
+				}
+				if path, _, _ := unstructured.NestedString(container, "readinessProbe", "httpGet", "path"); path != "/" {
+					t.Fatalf("expected the readiness probe of the CR, got %v", container["readinessProbe"])
+				}
+				if selector, _, _ := unstructured.NestedStringMap(pod, "nodeSelector"); selector["node-role.kubernetes.io/compute"] != "true" {
+					t.Fatalf("expected the node selector of the CR, got %v", selector)
+				}
+				if tolerations, _, _ := unstructured.NestedSlice(pod, "tolerations"); len(tolerations) != 1 {
+					t.Fatalf("expected the tolerations of the CR, got %v", tolerations)
+				}
+				if pod["priorityClassName"] != "workshop" {
+					t.Fatalf("expected priority class workshop, got %v", pod["priorityClassName"])
+				}
+			},
+		},
+		{
+			Name: "Remove pod settings deleted from the CR",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template:    v1alpha1.WebAppTemplate{Path: testTemplate},
+					PodTemplate: workshopPod(),
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				wa.Spec.PodTemplate = nil
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+				pod, _, _ := unstructured.NestedMap(dc.Object, "spec", "template", "spec")
+				container := pod["containers"].([]interface{})[0].(map[string]interface{})
+				if _, ok := container["readinessProbe"]; ok {
+					t.Fatalf("expected the readiness probe to be removed, got %v", container["readinessProbe"])
+				}
+				if limits, _, _ := unstructured.NestedMap(container, "resources", "limits"); len(limits) > 0 {
+					t.Fatalf("expected the limits to be removed, got %v", limits)
+				}
+				for _, key := range []string{"nodeSelector", "tolerations", "priorityClassName"} {
+					if _, ok := pod[key]; ok {
+						t.Fatalf("expected %s to be removed, got %v", key, pod[key])
+					}
+				}
+			},
+		},
+		{
+			Name:     "Provision Deployment on Kubernetes",
+			Platform: openshift.PlatformKubernetes,
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testKubernetesTemplate,
+						Parameters: map[string]string{
+							"OPENSHIFT_OAUTHCLIENT_ID": "test-value",
 						},
 					},
-					Status: v1alpha1.WebAppStatus{
-						Message: "OK",
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Phase != v1alpha1.PhaseReady {
+					t.Fatalf("expected phase %s, got %s: %s", v1alpha1.PhaseReady, wa.Status.Phase, wa.Status.Message)
+				}
+				deployment := cluster.get("Deployment", "tutorial-web-app")
+				if deployment == nil {
+					t.Fatalf("expected deployment to be provisioned, got %v", cluster.objects)
+				}
+				if val, _ := containerEnv(deployment, "OPENSHIFT_OAUTHCLIENT_ID"); val != "test-value" {
+					t.Fatalf("expected OPENSHIFT_OAUTHCLIENT_ID to be test-value, got %s", val)
+				}
+				if cluster.get("Ingress", "tutorial-web-app") == nil {
+					t.Fatalf("expected ingress to be provisioned, got %v", cluster.objects)
+				}
+			},
+		},
+		{
+			Name: "Pod not running",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodPending),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Phase != v1alpha1.PhaseReconciling {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseReconciling, wa.Status.Phase)
+				}
+				c := wa.Status.GetCondition(v1alpha1.DeploymentAvailable)
+				if c == nil || c.Status != v12.ConditionFalse || c.Reason != v1alpha1.ReasonPodsNotReady {
+					t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.DeploymentAvailable, v1alpha1.ReasonPodsNotReady, c)
+				}
+			},
+		},
+		{
+			Name:         "Use operator default image",
+			DefaultImage: "registry.lab.example.com/integreatly/tutorial-web-app:2.28.1",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				expected := "registry.lab.example.com/integreatly/tutorial-web-app:2.28.1"
+				if image := containerImage(cluster.get("DeploymentConfig", "tutorial-web-app")); image != expected {
+					t.Fatalf("expected image %s, got %s", expected, image)
+				}
+			},
+		},
+		{
+			Name:         "Use image from the CR by digest",
+			DefaultImage: "registry.lab.example.com/integreatly/tutorial-web-app:2.28.1",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Image: testDigestImage,
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if image := containerImage(cluster.get("DeploymentConfig", "tutorial-web-app")); image != testDigestImage {
+					t.Fatalf("expected image %s, got %s", testDigestImage, image)
+				}
+				if wa.Status.Version != "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae" {
+					t.Fatalf("expected the digest as version, got %s", wa.Status.Version)
+				}
+			},
+		},
+		{
+			Name: "Use image from the CR for a new web app with IfNotSet",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Image:       testDigestImage,
+					ImagePolicy: v1alpha1.ImagePolicyIfNotSet,
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+					GetDCFunc:           liveDC(""),
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if image := containerImage(cluster.get("DeploymentConfig", "tutorial-web-app")); image != testDigestImage {
+					t.Fatalf("expected image %s, got %s", testDigestImage, image)
+				}
+			},
+		},
+		{
+			Name: "Keep live image with IfNotSet",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Image:       testDigestImage,
+					ImagePolicy: v1alpha1.ImagePolicyIfNotSet,
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+					GetDCFunc:           liveDC("mirror.example.com/tutorial-web-app:pinned"),
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if image := containerImage(cluster.get("DeploymentConfig", "tutorial-web-app")); image != "mirror.example.com/tutorial-web-app:pinned" {
+					t.Fatalf("expected the live image to be kept, got %s", image)
+				}
+				if wa.Status.Version != "pinned" {
+					t.Fatalf("expected version pinned, got %s", wa.Status.Version)
+				}
+			},
+		},
+		{
+			Name:     "Keep live image with Ignore",
+			Platform: openshift.PlatformKubernetes,
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					ImagePolicy: v1alpha1.ImagePolicyIgnore,
+					Template: v1alpha1.WebAppTemplate{
+						Path: testKubernetesTemplate,
 					},
 				},
 			},
 			OSClient: func() *openshift.OSClientInterfaceMock {
 				return &openshift.OSClientInterfaceMock{
-					GetDCFunc: func(ns string, dcName string) (v1.DeploymentConfig, error) {
-						return v1.DeploymentConfig{}, errors.New("no DC found")
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
+					GetDeploymentFunc: func(ns string, name string) (k8sappsv1.Deployment, error) {
+						return k8sappsv1.Deployment{
+							Spec: k8sappsv1.DeploymentSpec{
+								Template: v12.PodTemplateSpec{
+									Spec: v12.PodSpec{
+										Containers: []v12.Container{{Image: "mirror.example.com/tutorial-web-app:pinned"}},
+									},
+								},
+							},
+						}, nil
 					},
-					UpdateDCFunc: func(ns string, dc *v1.DeploymentConfig) error {
-						return nil
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if image := containerImage(cluster.get("Deployment", "tutorial-web-app")); image != "mirror.example.com/tutorial-web-app:pinned" {
+					t.Fatalf("expected the live image to be kept, got %s", image)
+				}
+			},
+		},
+		{
+			Name: "Unknown image policy",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					ImagePolicy: "Always",
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
+					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+				}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				c := wa.Status.GetCondition(v1alpha1.ObjectsProvisioned)
+				if c == nil || c.Reason != v1alpha1.ReasonReconcileFailed {
+					t.Fatalf("expected condition %s with reason %s, got %v", v1alpha1.ObjectsProvisioned, v1alpha1.ReasonReconcileFailed, c)
+				}
+				if len(cluster.objects) != 0 {
+					t.Fatalf("expected no objects to be provisioned, got %v", cluster.objects)
+				}
+			},
+		},
+		{
+			Name: "Remove finalizer on deletion",
+			WebApp: &v1alpha1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Finalizers:        []string{"other", v1alpha1.WebAppFinalizer},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.HasFinalizer(v1alpha1.WebAppFinalizer) || !wa.HasFinalizer("other") {
+					t.Fatalf("expected only finalizer %s to be removed, got %v", v1alpha1.WebAppFinalizer, wa.GetFinalizers())
+				}
+			},
+		},
+		{
+			Name: "Template missing",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: "_testdata/missing-template.yml",
 					},
+				},
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{}
+			},
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Phase != v1alpha1.PhaseProvisioning {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseProvisioning, wa.Status.Phase)
+				}
+				c := wa.Status.GetCondition(v1alpha1.TemplateProcessed)
+				if c == nil || c.Status != v12.ConditionFalse || c.Reason != v1alpha1.ReasonTemplateProcessFailed {
+					t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.TemplateProcessed, v1alpha1.ReasonTemplateProcessFailed, c)
 				}
 			},
-			SDKCruder: func() SdkCruder {
-				return &SdkCruderMock{
-					UpdateFunc: func(object sdk.Object) error {
-						return nil
+		},
+		{
+			Name: "Apply fails after provisioning",
+			WebApp: &v1alpha1.WebApp{
+				Spec: v1alpha1.WebAppSpec{
+					Template: v1alpha1.WebAppTemplate{
+						Path: testTemplate,
 					},
+				},
+			},
+			Prepare: func(cluster *fakeCluster, wa *v1alpha1.WebApp) {
+				delete(cluster.objects, "Service/tutorial-web-app")
+				cluster.createErr = errors.New("quota exceeded")
+			},
+			OSClient: func() *openshift.OSClientInterfaceMock {
+				return &openshift.OSClientInterfaceMock{
+					ProcessTemplateFunc: processLocally,
+					GetPodsFunc:         podPhase(v12.PodRunning),
+					GetEndpointsFunc:    serviceEndpoints,
 				}
 			},
-			Verify: func(wa *v1alpha1.WebApp, t *testing.T) {
-				if wa.Status.Message != "Error: no DC found" {
-					t.Fatalf("expected status 'Error: no DC found', got %s", wa.Status.Message)
+			Verify: func(wa *v1alpha1.WebApp, cluster *fakeCluster, t *testing.T) {
+				if wa.Status.Phase != v1alpha1.PhaseDegraded {
+					t.Fatalf("expected phase %s, got %s", v1alpha1.PhaseDegraded, wa.Status.Phase)
+				}
+				c := wa.Status.GetCondition(v1alpha1.ObjectsProvisioned)
+				if c == nil || c.Status != v12.ConditionFalse || c.Reason != v1alpha1.ReasonProvisionFailed {
+					t.Fatalf("expected condition %s to be false with reason %s, got %v", v1alpha1.ObjectsProvisioned, v1alpha1.ReasonProvisionFailed, c)
 				}
 			},
 		},
@@ -120,48 +947,499 @@ func TestReconcile(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			osClient := tc.OSClient()
-			wh := NewWebHandler(nil, osClient, MockGetResourcesClient, tc.SDKCruder())
-			wh.Handle(context.TODO(), tc.Event)
-			tc.Verify(tc.Event.Object.(*v1alpha1.WebApp), t)
+			platform := tc.Platform
+			if platform == "" {
+				platform = openshift.PlatformOpenShift
+			}
+			cluster := newFakeCluster()
+			cruder := &SdkCruderMock{
+				UpdateFunc: func(object sdk.Object) error {
+					return nil
+				},
+				UpdateStatusFunc: func(object sdk.Object) error {
+					return nil
+				},
+			}
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, tc.OSClient(), cluster.clientFactory, cruder, platform, tc.DefaultImage, "")
+
+			if tc.Prepare != nil {
+				provisioned := tc.WebApp.DeepCopy()
+				_, err := wh.Reconcile(context.TODO(), provisioned)
+				if err != nil {
+					t.Fatalf("unexpected error provisioning the web app: %v", err)
+				}
+				tc.WebApp.Status = provisioned.Status
+				tc.Prepare(cluster, tc.WebApp)
+				cluster.patches = 0
+			}
+
+			wh.Reconcile(context.TODO(), tc.WebApp)
+			tc.Verify(tc.WebApp, cluster, t)
 		})
 	}
 }
 
-func TestMigrateImage(t *testing.T) {
+func TestReconcile_ParametersFrom(t *testing.T) {
+	secret := v12.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sso", ResourceVersion: "1"},
+		Data:       map[string][]byte{"route": []byte("https://sso.example.com")},
+	}
+	optional := true
+	osClient := &openshift.OSClientInterfaceMock{
+		ProcessTemplateFunc: processLocally,
+		GetPodsFunc:         podPhase(v12.PodRunning),
+		GetEndpointsFunc:    serviceEndpoints,
+		GetSecretFunc: func(ns string, name string) (v12.Secret, error) {
+			if name != "sso" {
+				return v12.Secret{}, errors2.NewNotFound(v12.Resource("secrets"), name)
+			}
+			return secret, nil
+		},
+		GetConfigMapFunc: func(ns string, name string) (v12.ConfigMap, error) {
+			return v12.ConfigMap{Data: map[string]string{"host": "openshift.example.com"}}, nil
+		},
+	}
+	cr := &v1alpha1.WebApp{
+		Spec: v1alpha1.WebAppSpec{
+			Template: v1alpha1.WebAppTemplate{
+				Path: testTemplate,
+				ParametersFrom: []v1alpha1.TemplateParameterSource{
+					{
+						Name: "SSO_ROUTE",
+						ValueFrom: v1alpha1.ParameterValueSource{
+							SecretKeyRef: &v12.SecretKeySelector{LocalObjectReference: v12.LocalObjectReference{Name: "sso"}, Key: "route"},
+						},
+					},
+					{
+						Name: "OPENSHIFT_HOST",
+						ValueFrom: v1alpha1.ParameterValueSource{
+							ConfigMapKeyRef: &v12.ConfigMapKeySelector{LocalObjectReference: v12.LocalObjectReference{Name: "cluster"}, Key: "host"},
+						},
+					},
+					{
+						Name: "OPENSHIFT_OAUTH_HOST",
+						ValueFrom: v1alpha1.ParameterValueSource{
+							SecretKeyRef: &v12.SecretKeySelector{LocalObjectReference: v12.LocalObjectReference{Name: "missing"}, Key: "host", Optional: &optional},
+						},
+					},
+				},
+			},
+		},
+	}
+	cluster := newFakeCluster()
+	cruder := &SdkCruderMock{
+		UpdateFunc: func(object sdk.Object) error {
+			return nil
+		},
+		UpdateStatusFunc: func(object sdk.Object) error {
+			return nil
+		},
+	}
+	wh := NewWebHandler(nil, &record.FakeRecorder{}, osClient, cluster.clientFactory, cruder, openshift.PlatformOpenShift, "", "")
+
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dc := cluster.get("DeploymentConfig", "tutorial-web-app")
+	containers, _, _ := unstructured.NestedSlice(dc.Object, "spec", "template", "spec", "containers")
+	env, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "env")
+	for _, e := range env {
+		e := e.(map[string]interface{})
+		if e["name"] != "SSO_ROUTE" {
+			continue
+		}
+		if _, ok := e["value"]; ok {
+			t.Fatalf("expected SSO_ROUTE to not hold the secret value, got %v", e)
+		}
+		if name, _, _ := unstructured.NestedString(e, "valueFrom", "secretKeyRef", "name"); name != "sso" {
+			t.Fatalf("expected SSO_ROUTE to reference secret sso, got %v", e)
+		}
+	}
+	if val, _ := containerEnv(dc, "OPENSHIFT_HOST"); val != "openshift.example.com" {
+		t.Fatalf("expected OPENSHIFT_HOST from the config map, got %s", val)
+	}
+	if val, _ := containerEnv(dc, "OPENSHIFT_OAUTH_HOST"); val != "" {
+		t.Fatalf("expected the missing optional parameter to use the template default, got %s", val)
+	}
+	hash, _, _ := unstructured.NestedString(dc.Object, "spec", "template", "metadata", "annotations", parametersHashAnnotation)
+	if hash == "" {
+		t.Fatalf("expected the %s annotation on the pod template", parametersHashAnnotation)
+	}
+
+	// a change of the referenced secret rolls out the web app
+	secret.Data["route"] = []byte("https://sso2.example.com")
+	secret.ResourceVersion = "2"
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dc = cluster.get("DeploymentConfig", "tutorial-web-app")
+	newHash, _, _ := unstructured.NestedString(dc.Object, "spec", "template", "metadata", "annotations", parametersHashAnnotation)
+	if newHash == hash || cluster.patches != 1 {
+		t.Fatalf("expected the DC to be patched with a new hash, got %d patches and hash %s", cluster.patches, newHash)
+	}
+
+	// parameters can not be set twice
+	cr.Spec.Template.Parameters = map[string]string{"SSO_ROUTE": "https://sso.example.com"}
+	wh.Reconcile(context.TODO(), cr)
+	c := cr.Status.GetCondition(v1alpha1.TemplateProcessed)
+	if c == nil || c.Reason != v1alpha1.ReasonParameterResolveFailed {
+		t.Fatalf("expected condition %s with reason %s, got %v", v1alpha1.TemplateProcessed, v1alpha1.ReasonParameterResolveFailed, c)
+	}
+}
+
+// recorded drains the events recorded so far
+func recorded(recorder *record.FakeRecorder) []string {
+	recorded := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			recorded = append(recorded, event)
+		default:
+			return recorded
+		}
+	}
+}
+
+func expectEvents(t *testing.T, recorder *record.FakeRecorder, expected ...string) {
+	if got := recorded(recorder); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
+
+func TestReconcile_Events(t *testing.T) {
+	podRunning := false
+	osClient := &openshift.OSClientInterfaceMock{
+		ProcessTemplateFunc: processLocally,
+		GetPodsFunc: func(ns string, selector string) ([]v12.Pod, error) {
+			if !podRunning {
+				return podPhase(v12.PodPending)(ns, selector)
+			}
+			return podPhase(v12.PodRunning)(ns, selector)
+		},
+		GetEndpointsFunc: serviceEndpoints,
+	}
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec: v1alpha1.WebAppSpec{
+			Template: v1alpha1.WebAppTemplate{
+				Path:       testTemplate,
+				Parameters: map[string]string{"DATABASE_LOCATION": "/data"},
+			},
+		},
+	}
+	cluster := newFakeCluster()
+	cruder := &SdkCruderMock{
+		UpdateFunc: func(object sdk.Object) error {
+			return nil
+		},
+		UpdateStatusFunc: func(object sdk.Object) error {
+			return nil
+		},
+	}
+	recorder := record.NewFakeRecorder(20)
+	wh := NewWebHandler(nil, recorder, osClient, cluster.clientFactory, cruder, openshift.PlatformOpenShift, "quay.io/integreatly/tutorial-web-app:2.10.0", "")
+
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectEvents(t, recorder,
+		"Normal TemplateProcessed processed the template into 3 objects",
+		"Normal Created created DeploymentConfig tutorial-web-app",
+		"Normal Created created Service tutorial-web-app",
+		"Normal Created created PersistentVolumeClaim user-walkthroughs",
+		"Normal Created created Route tutorial-web-app",
+		"Normal Created created OAuthClient webapp-tutorial-web-app",
+	)
+
+	// transitions are recorded once
+	podRunning = true
+	wh.Reconcile(context.TODO(), cr)
+	wh.Reconcile(context.TODO(), cr)
+	expectEvents(t, recorder, "Normal Ready web app is ready at https://tutorial-web-app-webapp.apps.example.com")
+
+	cr.Spec.Template.Parameters["DATABASE_LOCATION"] = "/data/walkthroughs"
+	wh.Reconcile(context.TODO(), cr)
+	expectEvents(t, recorder, "Normal EnvVarChanged changed environment variables DATABASE_LOCATION of DeploymentConfig tutorial-web-app")
+
+	wh.defaultImage = "quay.io/integreatly/tutorial-web-app:2.11.0"
+	wh.Reconcile(context.TODO(), cr)
+	expectEvents(t, recorder, "Normal ImageMigrated migrated from version 2.10.0 to 2.11.0")
+
+	delete(cluster.objects, "Service/tutorial-web-app")
+	cluster.createErr = errors.New("quota exceeded")
+	wh.Reconcile(context.TODO(), cr)
+	warnings := recorded(recorder)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "Warning "+v1alpha1.ReasonProvisionFailed+" ") || !strings.Contains(warnings[0], "quota exceeded") {
+		t.Fatalf("expected a %s warning, got %q", v1alpha1.ReasonProvisionFailed, warnings)
+	}
+
+	cr.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	wh.Reconcile(context.TODO(), cr)
+	expectEvents(t, recorder, "Normal Deleting removing the finalizer, the web app objects are garbage collected")
+}
+
+func TestReconcile_StatusConflict(t *testing.T) {
+	osClient := &openshift.OSClientInterfaceMock{
+		ProcessTemplateFunc: processLocally,
+		GetPodsFunc:         podPhase(v12.PodRunning),
+		GetEndpointsFunc:    serviceEndpoints,
+	}
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	cruder := &SdkCruderMock{
+		UpdateFunc: func(object sdk.Object) error {
+			return nil
+		},
+		UpdateStatusFunc: func(object sdk.Object) error {
+			return errors.New("the object has been modified")
+		},
+	}
+	wh := NewWebHandler(nil, &record.FakeRecorder{}, osClient, newFakeCluster().clientFactory, cruder, openshift.PlatformOpenShift, "", "")
+
+	// the status would be lost without a retry, the WebApp is only reconciled again on changes
+	if _, err := wh.Reconcile(context.TODO(), cr); err == nil || !strings.Contains(err.Error(), "the object has been modified") {
+		t.Fatalf("expected the failed status update to fail the reconcile, got %v", err)
+	}
+}
+
+func TestImageVersion(t *testing.T) {
+	cases := map[string]string{
+		"quay.io/integreatly/tutorial-web-app:2.28.1": "2.28.1",
+		"localhost:5000/tutorial-web-app":             "latest",
+		"localhost:5000/tutorial-web-app:dev":         "dev",
+		testDigestImage:                               "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+	}
+
+	for image, expected := range cases {
+		if version := imageVersion(image); version != expected {
+			t.Fatalf("expected version %s for image %s, got %s", expected, image, version)
+		}
+	}
+}
+
+func TestCreateRoute(t *testing.T) {
+	osClient := &openshift.OSClientInterfaceMock{
+		GetSecretFunc: func(ns string, name string) (v12.Secret, error) {
+			switch name {
+			case "workshop-tls":
+				return v12.Secret{Data: map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key"), "ca.crt": []byte("ca")}}, nil
+			case "no-key":
+				return v12.Secret{Data: map[string][]byte{"tls.crt": []byte("cert")}}, nil
+			}
+			return v12.Secret{}, errors2.NewNotFound(v12.Resource("secrets"), name)
+		},
+	}
+
 	cases := []struct {
-		Name      string
-		Container v12.Container
-		Verify    func(bool, v12.Container)
+		Name          string
+		Route         *v1alpha1.WebAppRoute
+		ExpectedError string
+		Verify        func(*routev1.Route, *testing.T)
 	}{
 		{
-			Name: "Container Image not updated if image is the same",
-			Container: v12.Container{
-				Image: WebAppImage,
+			Name: "Default route",
+			Verify: func(route *routev1.Route, t *testing.T) {
+				if route.Spec.Host != "" || route.Spec.Path != "" {
+					t.Fatalf("expected the host to be generated by the router, got %s%s", route.Spec.Host, route.Spec.Path)
+				}
+				if route.Spec.TLS.Termination != routev1.TLSTerminationEdge || route.Spec.TLS.Certificate != "" {
+					t.Fatalf("expected edge termination with the router certificate, got %v", route.Spec.TLS)
+				}
+				if !reflect.DeepEqual(route.Labels, map[string]string{"app": "tutorial-web-app"}) {
+					t.Fatalf("expected the labels of the WebApp, got %v", route.Labels)
+				}
 			},
-			Verify: func(updated bool, container v12.Container) {
-				if updated != false {
-					t.Fatalf("Expected image to not be updated but was updated...")
+		},
+		{
+			Name: "Vanity host with its own certificate",
+			Route: &v1alpha1.WebAppRoute{
+				Host:        "workshop.example.com",
+				Path:        "/tutorials",
+				Annotations: map[string]string{"haproxy.router.openshift.io/timeout": "2m"},
+				Labels:      map[string]string{"router": "public"},
+				TLS: &v1alpha1.WebAppRouteTLS{
+					Termination:                   routev1.TLSTerminationReencrypt,
+					CertificateSecret:             "workshop-tls",
+					DestinationCACertificate:      "destination-ca",
+					InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+				},
+			},
+			Verify: func(route *routev1.Route, t *testing.T) {
+				if route.Spec.Host != "workshop.example.com" || route.Spec.Path != "/tutorials" {
+					t.Fatalf("expected host workshop.example.com and path /tutorials, got %s%s", route.Spec.Host, route.Spec.Path)
+				}
+				expectedTLS := &routev1.TLSConfig{
+					Termination:                   routev1.TLSTerminationReencrypt,
+					Certificate:                   "cert",
+					Key:                           "key",
+					CACertificate:                 "ca",
+					DestinationCACertificate:      "destination-ca",
+					InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+				}
+				if !reflect.DeepEqual(route.Spec.TLS, expectedTLS) {
+					t.Fatalf("expected TLS %v, got %v", expectedTLS, route.Spec.TLS)
+				}
+				if route.Annotations["haproxy.router.openshift.io/timeout"] != "2m" {
+					t.Fatalf("expected the annotations of the route settings, got %v", route.Annotations)
+				}
+				if !reflect.DeepEqual(route.Labels, map[string]string{"app": "tutorial-web-app", "router": "public"}) {
+					t.Fatalf("expected the shard label next to the labels of the WebApp, got %v", route.Labels)
 				}
 			},
 		},
 		{
-			Name: "Container Image is updated if image is not the same",
-			Container: v12.Container{
-				Image: "someDifferentImage",
+			Name:  "Passthrough route",
+			Route: &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{Termination: routev1.TLSTerminationPassthrough}},
+			Verify: func(route *routev1.Route, t *testing.T) {
+				if route.Spec.TLS.Termination != routev1.TLSTerminationPassthrough || route.Spec.TLS.Key != "" {
+					t.Fatalf("expected passthrough termination without a certificate, got %v", route.Spec.TLS)
+				}
 			},
-			Verify: func(updated bool, container v12.Container) {
-				if updated != true && container.Image != WebAppImage {
-					t.Fatalf("Expected image to be updated but was not...")
+		},
+		{
+			Name:          "Certificate secret without a key",
+			Route:         &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{CertificateSecret: "no-key"}},
+			ExpectedError: "secret no-key for the route certificate has no tls.key",
+		},
+		{
+			Name:          "Missing certificate secret",
+			Route:         &v1alpha1.WebAppRoute{TLS: &v1alpha1.WebAppRouteTLS{CertificateSecret: "missing"}},
+			ExpectedError: "failed to get secret missing for the route certificate",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, osClient, MockGetResourcesClient, &SdkCruderMock{}, openshift.PlatformOpenShift, "", "")
+			cr := &v1alpha1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp", Labels: map[string]string{"app": "tutorial-web-app"}},
+				Spec:       v1alpha1.WebAppSpec{Route: tc.Route},
+			}
+			route, err := wh.CreateRoute(cr)
+			if tc.ExpectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.ExpectedError, err)
 				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cr.Labels["router"] != "" {
+				t.Fatalf("expected the labels of the WebApp to be left alone, got %v", cr.Labels)
+			}
+			tc.Verify(route, t)
+		})
+	}
+}
+
+func TestReconcile_RouteChanged(t *testing.T) {
+	cluster := newFakeCluster()
+	wh := backupHandler(cluster, &record.FakeRecorder{})
+	cr := &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "tutorial-web-app", Namespace: "webapp"},
+		Spec:       v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Path: testTemplate}},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cr.Spec.Route = &v1alpha1.WebAppRoute{
+		Host:        "workshop.example.com",
+		Path:        "/tutorials",
+		Annotations: map[string]string{"haproxy.router.openshift.io/ip_whitelist": "10.0.0.0/8"},
+		TLS:         &v1alpha1.WebAppRouteTLS{InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect},
+	}
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	route := &routev1.Route{}
+	liveObject(t, cluster, "Route", "tutorial-web-app", route)
+	if route.Spec.Host != "workshop.example.com" || route.Spec.TLS.InsecureEdgeTerminationPolicy != routev1.InsecureEdgeTerminationPolicyRedirect {
+		t.Fatalf("expected the route settings to be applied, got %s %v", route.Spec.Host, route.Spec.TLS)
+	}
+	if route.Annotations["haproxy.router.openshift.io/ip_whitelist"] != "10.0.0.0/8" {
+		t.Fatalf("expected the route annotations to be applied, got %v", route.Annotations)
+	}
+	client := cluster.get("OAuthClient", "webapp-tutorial-web-app")
+	if uris, _, _ := unstructured.NestedStringSlice(client.Object, "redirectURIs"); len(uris) != 1 || uris[0] != "https://workshop.example.com/tutorials" {
+		t.Fatalf("expected the OAuthClient to redirect to the new host and path, got %v", uris)
+	}
+
+	cr.Spec.Route = nil
+	if _, err := wh.Reconcile(context.TODO(), cr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	route = &routev1.Route{}
+	liveObject(t, cluster, "Route", "tutorial-web-app", route)
+	if route.Spec.Host != "" || route.Spec.TLS.InsecureEdgeTerminationPolicy != "" {
+		t.Fatalf("expected the route settings to be removed, got %s %v", route.Spec.Host, route.Spec.TLS)
+	}
+	if _, ok := route.Annotations["haproxy.router.openshift.io/ip_whitelist"]; ok {
+		t.Fatalf("expected the route annotations to be removed, got %v", route.Annotations)
+	}
+}
+
+func TestCreateIngress(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Parameters     map[string]string
+		Route          *v1alpha1.WebAppRoute
+		ExpectedName   string
+		ExpectedHost   string
+		ExpectedPath   string
+		ExpectedSecret string
+	}{
+		{
+			Name:         "Ingress without routing subdomain",
+			ExpectedName: "tutorial-web-app",
+		},
+		{
+			Name:         "Ingress with routing subdomain",
+			Parameters:   map[string]string{"ROUTING_SUBDOMAIN": "apps.example.com"},
+			ExpectedName: "solution-explorer",
+			ExpectedHost: "solution-explorer.apps.example.com",
+		},
+		{
+			Name:       "Ingress with route settings",
+			Parameters: map[string]string{"ROUTING_SUBDOMAIN": "apps.example.com"},
+			Route: &v1alpha1.WebAppRoute{
+				Host: "workshop.example.com",
+				Path: "/tutorials",
+				TLS:  &v1alpha1.WebAppRouteTLS{CertificateSecret: "workshop-tls"},
 			},
+			ExpectedName:   "solution-explorer",
+			ExpectedHost:   "workshop.example.com",
+			ExpectedPath:   "/tutorials",
+			ExpectedSecret: "workshop-tls",
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			updated, container := migrateImage(tc.Container)
-			tc.Verify(updated, container)
+			wh := NewWebHandler(nil, &record.FakeRecorder{}, &openshift.OSClientInterfaceMock{}, MockGetResourcesClient, &SdkCruderMock{}, openshift.PlatformKubernetes, "", "")
+			ingress, err := wh.CreateIngress(&v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{Template: v1alpha1.WebAppTemplate{Parameters: tc.Parameters}, Route: tc.Route}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ingress.Name != tc.ExpectedName {
+				t.Fatalf("expected name %s, got %s", tc.ExpectedName, ingress.Name)
+			}
+			if ingress.Spec.Rules[0].Host != tc.ExpectedHost {
+				t.Fatalf("expected host %s, got %s", tc.ExpectedHost, ingress.Spec.Rules[0].Host)
+			}
+			if tc.ExpectedHost != "" && (len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].Hosts[0] != tc.ExpectedHost || ingress.Spec.TLS[0].SecretName != tc.ExpectedSecret) {
+				t.Fatalf("expected TLS for host %s with secret %q, got %v", tc.ExpectedHost, tc.ExpectedSecret, ingress.Spec.TLS)
+			}
+			if ingress.Spec.Rules[0].HTTP.Paths[0].Path != tc.ExpectedPath {
+				t.Fatalf("expected path %q, got %q", tc.ExpectedPath, ingress.Spec.Rules[0].HTTP.Paths[0].Path)
+			}
+			if ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName != serviceName {
+				t.Fatalf("expected backend service %s, got %v", serviceName, ingress.Spec.Rules[0].HTTP.Paths[0].Backend)
+			}
 		})
 	}
 }
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	appsv1 "github.com/openshift/api/apps/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// workload is the pod template of the web app, owned by a DeploymentConfig on OpenShift and by
+// a Deployment on Kubernetes
+type workload struct {
+	kind     string
+	name     string
+	template *corev1.PodTemplateSpec
+	object   runtime.Object
+}
+
+// findWorkload returns the workload with the given name out of the objects rendered from the template
+func findWorkload(objects []runtime.Object, name string) (*workload, error) {
+	for _, o := range objects {
+		switch obj := o.(type) {
+		case *appsv1.DeploymentConfig:
+			if obj.Name != name {
+				continue
+			}
+			if obj.Spec.Template == nil {
+				return nil, fmt.Errorf("deployment config %s has no pod template", name)
+			}
+			return &workload{kind: "DeploymentConfig", name: name, template: obj.Spec.Template, object: obj}, nil
+		case *k8sappsv1.Deployment:
+			if obj.Name != name {
+				continue
+			}
+			return &workload{kind: "Deployment", name: name, template: &obj.Spec.Template, object: obj}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("template does not contain a DeploymentConfig or Deployment named %s", name)
+}
+
+func (wl *workload) setReplicas(replicas int32) {
+	switch obj := wl.object.(type) {
+	case *appsv1.DeploymentConfig:
+		obj.Spec.Replicas = replicas
+	case *k8sappsv1.Deployment:
+		obj.Spec.Replicas = &replicas
+	}
+}
+
+// setStrategy replaces the strategy of the template, the parameters of the other strategy type are dropped
+// because the API rejects them
+func (wl *workload) setStrategy(strategy v1alpha1.WebAppStrategy) {
+	switch obj := wl.object.(type) {
+	case *appsv1.DeploymentConfig:
+		if strategy.Type == v1alpha1.StrategyRolling {
+			obj.Spec.Strategy.Type = appsv1.DeploymentStrategyTypeRolling
+			obj.Spec.Strategy.RecreateParams = nil
+			obj.Spec.Strategy.RollingParams = &appsv1.RollingDeploymentStrategyParams{
+				MaxSurge:       strategy.MaxSurge,
+				MaxUnavailable: strategy.MaxUnavailable,
+			}
+			return
+		}
+		obj.Spec.Strategy.Type = appsv1.DeploymentStrategyTypeRecreate
+		obj.Spec.Strategy.RollingParams = nil
+	case *k8sappsv1.Deployment:
+		if strategy.Type == v1alpha1.StrategyRolling {
+			obj.Spec.Strategy = k8sappsv1.DeploymentStrategy{
+				Type: k8sappsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &k8sappsv1.RollingUpdateDeployment{
+					MaxSurge:       strategy.MaxSurge,
+					MaxUnavailable: strategy.MaxUnavailable,
+				},
+			}
+			return
+		}
+		obj.Spec.Strategy = k8sappsv1.DeploymentStrategy{Type: k8sappsv1.RecreateDeploymentStrategyType}
+	}
+}
+
+// setPodTemplate applies the pod settings of the WebApp to the pod template and its first container, which
+// runs the web app
+func (wl *workload) setPodTemplate(overrides v1alpha1.WebAppPodTemplate) {
+	pod := &wl.template.Spec
+	container := &pod.Containers[0]
+	if overrides.Resources != nil {
+		container.Resources = *overrides.Resources
+	}
+	if overrides.LivenessProbe != nil {
+		container.LivenessProbe = overrides.LivenessProbe
+	}
+	if overrides.ReadinessProbe != nil {
+		container.ReadinessProbe = overrides.ReadinessProbe
+	}
+
+	if len(overrides.NodeSelector) > 0 {
+		if pod.NodeSelector == nil {
+			pod.NodeSelector = map[string]string{}
+		}
+		for key, value := range overrides.NodeSelector {
+			pod.NodeSelector[key] = value
+		}
+	}
+	if overrides.Tolerations != nil {
+		pod.Tolerations = overrides.Tolerations
+	}
+	if overrides.Affinity != nil {
+		pod.Affinity = overrides.Affinity
+	}
+	if overrides.PriorityClassName != "" {
+		pod.PriorityClassName = overrides.PriorityClassName
+	}
+}
+
+// claims returns the names of the volume claims mounted by the workload
+func (wl *workload) claims() []string {
+	var claims []string
+	for _, v := range wl.template.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims = append(claims, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	return claims
+}
+
+// useEmptyDir replaces the volume claims of the workload with emptyDir volumes limited to sizeLimit
+func (wl *workload) useEmptyDir(sizeLimit *resource.Quantity) {
+	for i, v := range wl.template.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			wl.template.Spec.Volumes[i].VolumeSource = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: sizeLimit}}
+		}
+	}
+}
+
+// dataVolume returns the volume the web app keeps its data on, the first volume claim or emptyDir mounted by
+// the web app container, with its mount
+func (wl *workload) dataVolume() (corev1.Volume, corev1.VolumeMount, bool) {
+	for _, v := range wl.template.Spec.Volumes {
+		if v.PersistentVolumeClaim == nil && v.EmptyDir == nil {
+			continue
+		}
+		for _, m := range wl.template.Spec.Containers[0].VolumeMounts {
+			if m.Name == v.Name {
+				return v, m, true
+			}
+		}
+	}
+	return corev1.Volume{}, corev1.VolumeMount{}, false
+}
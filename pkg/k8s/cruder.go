@@ -1,6 +1,12 @@
 package k8s
 
-import "github.com/operator-framework/operator-sdk/pkg/sdk"
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sclient"
+	"github.com/operator-framework/operator-sdk/pkg/sdk"
+	"github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
+)
 
 type Cruder struct {
 }
@@ -13,6 +19,30 @@ func (c Cruder) Update(o sdk.Object) error {
 	return sdk.Update(o)
 }
 
+// UpdateStatus writes the status of the object through the status subresource and updates the object with the
+// result from the server, the sdk only updates the main resource
+func (c Cruder) UpdateStatus(o sdk.Object) error {
+	_, namespace, err := k8sutil.GetNameAndNamespace(o)
+	if err != nil {
+		return err
+	}
+	gvk := o.GetObjectKind().GroupVersionKind()
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	resourceClient, _, err := k8sclient.GetResourceClient(apiVersion, kind, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get resource client: %v", err)
+	}
+	obj, err := k8sutil.UnstructuredFromRuntimeObject(o)
+	if err != nil {
+		return err
+	}
+	obj, err = resourceClient.UpdateStatus(obj)
+	if err != nil {
+		return err
+	}
+	return k8sutil.UnstructuredIntoRuntimeObject(obj, o)
+}
+
 func (c Cruder) Delete(object sdk.Object, opts ...sdk.DeleteOption) error {
 
 	return sdk.Delete(object, opts...)
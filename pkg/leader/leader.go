@@ -0,0 +1,208 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+// Config configures the leader election
+type Config struct {
+	// Namespace and Name of the lock ConfigMap
+	Namespace string
+	Name      string
+	// Identity of this replica, unique among the replicas
+	Identity string
+	// Recorder records the leadership changes as events on the lock ConfigMap
+	Recorder record.EventRecorder
+
+	// LeaseDuration is how long the other replicas wait before they take over the lease of a leader that
+	// stopped renewing it
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader keeps trying to renew the lease before it gives up leadership,
+	// shorter than LeaseDuration
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval in which the lease is acquired or renewed
+	RetryPeriod time.Duration
+
+	// OnStartedLeading runs when the lease was acquired, its context is cancelled when leadership is lost or
+	// the context of Run is done
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading runs when the lease could not be renewed, which includes a lease released by Run
+	OnStoppedLeading func()
+	// OnNewLeader runs when a different replica became the leader, optional
+	OnNewLeader func(identity string)
+}
+
+// Elector elects a leader among the replicas of the operator with the leader election of client-go and its
+// ConfigMap lock. It adds what the leader election of client-go 1.11 lacks: it stops with a context and
+// releases the lease when it stops, so another replica takes over without waiting for the lease to expire.
+type Elector struct {
+	config  Config
+	lock    *releasableLock
+	elector *leaderelection.LeaderElector
+	// ctx is the context of Run, the parent of the context of OnStartedLeading
+	ctx context.Context
+}
+
+func New(client kubernetes.Interface, config Config) (*Elector, error) {
+	switch {
+	case config.Namespace == "" || config.Name == "":
+		return nil, fmt.Errorf("the namespace and the name of the lock must be set")
+	case config.Identity == "":
+		return nil, fmt.Errorf("the identity must be set")
+	case config.Recorder == nil:
+		return nil, fmt.Errorf("the recorder must be set")
+	case config.OnStartedLeading == nil || config.OnStoppedLeading == nil:
+		return nil, fmt.Errorf("OnStartedLeading and OnStoppedLeading must be set")
+	}
+
+	e := &Elector{
+		config: config,
+		lock: &releasableLock{Interface: &resourcelock.ConfigMapLock{
+			ConfigMapMeta: metav1.ObjectMeta{Namespace: config.Namespace, Name: config.Name},
+			Client:        client.CoreV1(),
+			LockConfig:    resourcelock.ResourceLockConfig{Identity: config.Identity, EventRecorder: config.Recorder},
+		}},
+		ctx: context.TODO(),
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          e.lock,
+		LeaseDuration: config.LeaseDuration,
+		RenewDeadline: config.RenewDeadline,
+		RetryPeriod:   config.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: e.startedLeading,
+			OnStoppedLeading: config.OnStoppedLeading,
+			OnNewLeader:      e.newLeader,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.elector = elector
+	return e, nil
+}
+
+// Run blocks until the lease was lost or the context is done. It runs OnStartedLeading once the lease was
+// acquired and releases the lease when the context is done.
+func (e *Elector) Run(ctx context.Context) {
+	e.ctx = ctx
+	lost := make(chan struct{})
+	go func() {
+		defer close(lost)
+		e.elector.Run()
+	}()
+
+	select {
+	case <-lost:
+	case <-ctx.Done():
+		if err := e.lock.release(); err != nil {
+			logrus.Errorf("failed to release the lock %s: %v", e.lock.Describe(), err)
+		}
+	}
+}
+
+func (e *Elector) startedLeading(stop <-chan struct{}) {
+	logrus.Infof("%s became the leader, lock %s", e.config.Identity, e.lock.Describe())
+	ctx, cancel := context.WithCancel(e.ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+	}()
+	e.config.OnStartedLeading(ctx)
+}
+
+func (e *Elector) newLeader(identity string) {
+	// client-go reports every leader, this replica is handled by OnStartedLeading and a released lease has none
+	if identity == e.config.Identity || identity == "" {
+		return
+	}
+	logrus.Infof("%s is the leader, lock %s", identity, e.lock.Describe())
+	if e.config.OnNewLeader != nil {
+		e.config.OnNewLeader(identity)
+	}
+}
+
+// releasableLock is a lock whose lease can be released. A released lease has no holder, the lock reports it as
+// missing so the next replica takes it over right away: client-go 1.11 would otherwise wait for the lease
+// duration, whatever the record says. Once released, the lock refuses to take the lease again.
+type releasableLock struct {
+	resourcelock.Interface
+	// mu serializes the elector and release, the ConfigMap lock is not safe for concurrent use
+	mu sync.Mutex
+	// free holds the last record read when it has no holder
+	free     *resourcelock.LeaderElectionRecord
+	released bool
+}
+
+func (l *releasableLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	record, err := l.Interface.Get()
+	l.free = nil
+	if err == nil && record.HolderIdentity == "" {
+		l.free = record
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, l.Describe())
+	}
+	return record, err
+}
+
+// Create takes over a free lease, the ConfigMap was read by Get so a concurrent take over fails with a conflict
+func (l *releasableLock) Create(record resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return fmt.Errorf("the lease was released")
+	}
+	if l.free == nil {
+		return l.Interface.Create(record)
+	}
+	record.LeaderTransitions = l.free.LeaderTransitions + 1
+	return l.Interface.Update(record)
+}
+
+func (l *releasableLock) Update(record resourcelock.LeaderElectionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return fmt.Errorf("the lease was released")
+	}
+	return l.Interface.Update(record)
+}
+
+// release gives up the lease when it is held by this replica
+func (l *releasableLock) release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.released = true
+	record, err := l.Interface.Get()
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if record.HolderIdentity != l.Identity() {
+		return nil
+	}
+	record.HolderIdentity = ""
+	if err := l.Interface.Update(*record); err != nil {
+		return err
+	}
+	logrus.Infof("%s released the lease, lock %s", l.Identity(), l.Describe())
+	return nil
+}
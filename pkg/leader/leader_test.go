@@ -0,0 +1,161 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+func testConfig(id string) Config {
+	return Config{
+		Namespace: "webapp",
+		Name:      "lock",
+		Identity:  id,
+		Recorder:  &record.FakeRecorder{},
+		// long enough that a take over before the lease expired can only come from a release
+		LeaseDuration:    10 * time.Second,
+		RenewDeadline:    5 * time.Second,
+		RetryPeriod:      100 * time.Millisecond,
+		OnStartedLeading: func(context.Context) {},
+		OnStoppedLeading: func() {},
+	}
+}
+
+func lockRecord(t *testing.T, client kubernetes.Interface) resourcelock.LeaderElectionRecord {
+	cm, err := client.CoreV1().ConfigMaps("webapp").Get("lock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the lock: %v", err)
+	}
+	record := resourcelock.LeaderElectionRecord{}
+	if err := json.Unmarshal([]byte(cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey]), &record); err != nil {
+		t.Fatalf("failed to decode the record: %v", err)
+	}
+	return record
+}
+
+func waitForLeader(t *testing.T, started chan string, expected string, timeout time.Duration) {
+	select {
+	case id := <-started:
+		if id != expected {
+			t.Fatalf("expected %s to lead, got %s", expected, id)
+		}
+	case <-time.After(timeout):
+		t.Fatalf("expected %s to become the leader within %s", expected, timeout)
+	}
+}
+
+func TestRun(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	started := make(chan string, 2)
+	leaderContexts := make(chan context.Context, 2)
+	newElector := func(id string) *Elector {
+		config := testConfig(id)
+		config.OnStartedLeading = func(ctx context.Context) {
+			leaderContexts <- ctx
+			started <- id
+		}
+		e, err := New(client, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return e
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() {
+		defer close(doneA)
+		newElector("operator-a").Run(ctxA)
+	}()
+	waitForLeader(t, started, "operator-a", 5*time.Second)
+	leaderCtxA := <-leaderContexts
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go newElector("operator-b").Run(ctxB)
+	time.Sleep(300 * time.Millisecond)
+	if record := lockRecord(t, client); record.HolderIdentity != "operator-a" {
+		t.Fatalf("expected operator-a to keep the lease, got %+v", record)
+	}
+
+	// operator-a shuts down and releases the lease, operator-b takes over without waiting for it to expire
+	cancelA()
+	select {
+	case <-doneA:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected Run to return when its context is done")
+	}
+	if leaderCtxA.Err() == nil {
+		t.Fatalf("expected the context of the leader to be cancelled")
+	}
+	waitForLeader(t, started, "operator-b", 3*time.Second)
+	if record := lockRecord(t, client); record.HolderIdentity != "operator-b" || record.LeaderTransitions != 1 {
+		t.Fatalf("expected operator-b to hold the lease after a transition, got %+v", record)
+	}
+}
+
+func TestRelease_NotHeld(t *testing.T) {
+	record := resourcelock.LeaderElectionRecord{HolderIdentity: "operator-b", LeaseDurationSeconds: 10}
+	value, _ := json.Marshal(record)
+	client := fake.NewSimpleClientset(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "webapp",
+		Name:        "lock",
+		Annotations: map[string]string{resourcelock.LeaderElectionRecordAnnotationKey: string(value)},
+	}})
+	e, err := New(client, testConfig("operator-a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := e.lock.release(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record := lockRecord(t, client); record.HolderIdentity != "operator-b" {
+		t.Fatalf("expected the lease of operator-b to be kept, got %+v", record)
+	}
+	if err := e.lock.Update(resourcelock.LeaderElectionRecord{HolderIdentity: "operator-a"}); err == nil {
+		t.Fatalf("expected a released lock to refuse the lease")
+	}
+}
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Config func(*Config)
+	}{
+		{
+			Name:   "Missing identity",
+			Config: func(c *Config) { c.Identity = "" },
+		},
+		{
+			Name:   "Missing recorder",
+			Config: func(c *Config) { c.Recorder = nil },
+		},
+		{
+			Name:   "Renew deadline longer than the lease",
+			Config: func(c *Config) { c.RenewDeadline = 15 * time.Second },
+		},
+		{
+			Name:   "Missing callbacks",
+			Config: func(c *Config) { c.OnStoppedLeading = nil },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := testConfig("operator-a")
+			tc.Config(&config)
+			if _, err := New(fake.NewSimpleClientset(), config); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
@@ -1,17 +1,179 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "integreatly_tutorial_webapp_operator"
 
 func RegisterOperatorMetrics() (*Metrics, error) {
-	operatorErrors := prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "integreatly_tutorial_webapp_operator_reconcile_errors_total",
-		Help: "Number of errors that occurred while reconciling the integreatly tutorial webapp deployment",
-	})
+	m := newMetrics()
+	if err := m.register(prometheus.DefaultRegisterer); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		reconcileDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Time it took to reconcile a WebApp, by result",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"result"}),
+		operatorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of errors that occurred while reconciling the integreatly tutorial webapp deployment, by phase and reason",
+		}, []string{"phase", "reason"}),
+		ready: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "webapp_ready",
+			Help:      "Whether the web app of a WebApp is running, 1 when it is",
+		}, []string{"namespace", "name"}),
+		templateDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "template_processing_duration_seconds",
+			Help:      "Time it took to process the template of a WebApp, by processor",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, []string{"processor"}),
+		imageMigrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "image_migrations_total",
+			Help:      "Number of times the version of a deployed web app was changed",
+		}),
+		webAppInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "webapp_info",
+			Help:      "The image and version of the web app deployed for a WebApp, always 1",
+		}, []string{"namespace", "name", "image", "version"}),
+		leader: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "leader",
+			Help:      "Whether this replica of the operator is the leader, 1 when it is",
+		}),
+		leaderTransitions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "leader_transitions_total",
+			Help:      "Number of leaders observed by this replica of the operator, including itself",
+		}),
+		info: map[string]prometheus.Labels{},
+	}
+}
+
+func (m *Metrics) register(registerer prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.reconcileDuration, m.operatorErrors, m.ready, m.templateDuration, m.imageMigrations, m.webAppInfo, m.leader, m.leaderTransitions} {
+		if err := registerer.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	err := prometheus.Register(operatorErrors)
+// ObserveReconcile records the duration of a reconcile
+func (m *Metrics) ObserveReconcile(d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
 	if err != nil {
-		return nil, err
+		result = "error"
+	}
+	m.reconcileDuration.WithLabelValues(result).Observe(d.Seconds())
+}
+
+// ReconcileError counts an error of a WebApp in the phase, with the reason of the failed condition
+func (m *Metrics) ReconcileError(phase, reason string) {
+	if m == nil {
+		return
+	}
+	m.operatorErrors.WithLabelValues(phase, reason).Inc()
+}
+
+// SetReady records whether the web app of a WebApp is running
+func (m *Metrics) SetReady(ns, name string, ready bool) {
+	if m == nil {
+		return
+	}
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	m.ready.WithLabelValues(ns, name).Set(value)
+}
+
+// ObserveTemplateProcessing records the duration of processing a template
+func (m *Metrics) ObserveTemplateProcessing(processor string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.templateDuration.WithLabelValues(processor).Observe(d.Seconds())
+}
+
+// SetWebAppVersion records the image and version deployed for a WebApp
+func (m *Metrics) SetWebAppVersion(ns, name, image, version string) {
+	if m == nil {
+		return
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := ns + "/" + name
+	labels := prometheus.Labels{"namespace": ns, "name": name, "image": image, "version": version}
+	if old, ok := m.info[key]; ok {
+		if old["image"] == image && old["version"] == version {
+			return
+		}
+		m.webAppInfo.Delete(old)
+	}
+	m.info[key] = labels
+	m.webAppInfo.With(labels).Set(1)
+}
+
+// ImageMigrated counts a change of the version of a deployed web app
+func (m *Metrics) ImageMigrated() {
+	if m == nil {
+		return
+	}
+	m.imageMigrations.Inc()
+}
 
-	return &Metrics{operatorErrors: operatorErrors}, nil
+// Forget removes the series of a deleted WebApp
+func (m *Metrics) Forget(ns, name string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := ns + "/" + name
+	if old, ok := m.info[key]; ok {
+		m.webAppInfo.Delete(old)
+		delete(m.info, key)
+	}
+	m.ready.DeleteLabelValues(ns, name)
+}
+
+// SetLeader records whether this replica is the leader, a change of leadership is counted as a transition
+func (m *Metrics) SetLeader(leading bool) {
+	if m == nil {
+		return
+	}
+	if leading {
+		m.leader.Set(1)
+		m.leaderTransitions.Inc()
+		return
+	}
+	m.leader.Set(0)
+}
+
+// ObserveLeader counts a different replica becoming the leader
+func (m *Metrics) ObserveLeader() {
+	if m == nil {
+		return
+	}
+	m.leaderTransitions.Inc()
 }
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// gather returns the series of a metric family as label sets and values
+func gather(t *testing.T, registry *prometheus.Registry, name string) []*dto.Metric {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather the metrics: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == namespace+"_"+name {
+			return f.GetMetric()
+		}
+	}
+	return nil
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newMetrics()
+	if err := m.register(registry); err != nil {
+		t.Fatalf("failed to register the metrics: %v", err)
+	}
+
+	m.ObserveReconcile(time.Second, nil)
+	m.ObserveReconcile(time.Second, errors.New("failed"))
+	if series := gather(t, registry, "reconcile_duration_seconds"); len(series) != 2 {
+		t.Fatalf("expected a duration series per result, got %v", series)
+	}
+
+	m.ReconcileError("Degraded", "ProvisionFailed")
+	m.ReconcileError("Degraded", "ProvisionFailed")
+	series := gather(t, registry, "reconcile_errors_total")
+	if len(series) != 1 || series[0].GetCounter().GetValue() != 2 || label(series[0], "reason") != "ProvisionFailed" {
+		t.Fatalf("expected 2 ProvisionFailed errors, got %v", series)
+	}
+
+	m.SetWebAppVersion("webapp", "tutorial-web-app", "quay.io/integreatly/tutorial-web-app:2.10.0", "2.10.0")
+	m.SetWebAppVersion("webapp", "tutorial-web-app", "quay.io/integreatly/tutorial-web-app:2.11.0", "2.11.0")
+	series = gather(t, registry, "webapp_info")
+	if len(series) != 1 || label(series[0], "version") != "2.11.0" {
+		t.Fatalf("expected only the info of version 2.11.0, got %v", series)
+	}
+
+	m.SetReady("webapp", "tutorial-web-app", true)
+	if series := gather(t, registry, "webapp_ready"); len(series) != 1 || series[0].GetGauge().GetValue() != 1 {
+		t.Fatalf("expected the web app to be ready, got %v", series)
+	}
+
+	m.Forget("webapp", "tutorial-web-app")
+	if series := gather(t, registry, "webapp_info"); len(series) != 0 {
+		t.Fatalf("expected the info of the deleted WebApp to be removed, got %v", series)
+	}
+	if series := gather(t, registry, "webapp_ready"); len(series) != 0 {
+		t.Fatalf("expected the readiness of the deleted WebApp to be removed, got %v", series)
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	m.ObserveReconcile(time.Second, nil)
+	m.ReconcileError("Degraded", "ProvisionFailed")
+	m.SetReady("webapp", "tutorial-web-app", true)
+	m.ObserveTemplateProcessing("server", time.Second)
+	m.SetWebAppVersion("webapp", "tutorial-web-app", "image", "version")
+	m.ImageMigrated()
+	m.Forget("webapp", "tutorial-web-app")
+	m.SetLeader(true)
+	m.ObserveLeader()
+}
@@ -1,7 +1,22 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type Metrics struct {
-	operatorErrors prometheus.Counter
+	reconcileDuration *prometheus.HistogramVec
+	operatorErrors    *prometheus.CounterVec
+	ready             *prometheus.GaugeVec
+	templateDuration  *prometheus.HistogramVec
+	imageMigrations   prometheus.Counter
+	webAppInfo        *prometheus.GaugeVec
+	leader            prometheus.Gauge
+	leaderTransitions prometheus.Counter
+
+	mu sync.Mutex
+	// info are the labels of the webAppInfo series by WebApp, so the series of a previous version is removed
+	info map[string]prometheus.Labels
 }
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+)
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// specPatch returns the JSON patch for the fields set by the defaulter. Only the defaulted fields are
+// patched, so fields of the WebApp unknown to the operator are preserved.
+func specPatch(original, defaulted *v1alpha1.WebApp) []patchOperation {
+	ops := []patchOperation{}
+	if original.Spec.AppLabel != defaulted.Spec.AppLabel {
+		ops = append(ops, patchOperation{Op: "add", Path: "/spec/app_label", Value: defaulted.Spec.AppLabel})
+	}
+
+	if original.Spec.Template.Parameters == nil && len(defaulted.Spec.Template.Parameters) > 0 {
+		return append(ops, patchOperation{Op: "add", Path: "/spec/template/parameters", Value: defaulted.Spec.Template.Parameters})
+	}
+	keys := make([]string, 0, len(defaulted.Spec.Template.Parameters))
+	for key := range defaulted.Spec.Template.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := defaulted.Spec.Template.Parameters[key]
+		if old, ok := original.Spec.Template.Parameters[key]; ok && old == value {
+			continue
+		}
+		ops = append(ops, patchOperation{Op: "add", Path: "/spec/template/parameters/" + escapePointer(key), Value: value})
+	}
+	return ops
+}
+
+// escapePointer escapes a JSON pointer reference token, RFC 6901
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
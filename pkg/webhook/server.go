@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ValidatePath = "/validate-webapp"
+	MutatePath   = "/mutate-webapp"
+
+	certFile = "tls.crt"
+	keyFile  = "tls.key"
+)
+
+// Server serves the validating and mutating admission webhooks of the WebApp
+type Server struct {
+	validator Validator
+	defaulter Defaulter
+	server    *http.Server
+	certDir   string
+}
+
+// NewServer returns a webhook server listening on the given port, certDir must contain tls.crt and tls.key
+func NewServer(port int, certDir string, validator Validator, defaulter Defaulter) *Server {
+	s := &Server{validator: validator, defaulter: defaulter, certDir: certDir}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, s.serve(s.validate))
+	mux.HandleFunc(MutatePath, s.serve(s.mutate))
+	s.server = &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	return s
+}
+
+// Start serves the webhooks until the context is done
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		err := s.server.ListenAndServeTLS(filepath.Join(s.certDir, certFile), filepath.Join(s.certDir, keyFile))
+		if err != nil && err != http.ErrServerClosed {
+			logrus.Fatalf("failed to serve the admission webhooks: %v", err)
+		}
+	}()
+}
+
+func (s *Server) serve(admit func(*AdmissionRequest) *AdmissionResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			http.Error(w, fmt.Sprintf("unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			logrus.Errorf("failed to write the admission response: %v", err)
+		}
+	}
+}
+
+func (s *Server) validate(req *AdmissionRequest) *AdmissionResponse {
+	cr, err := decodeWebApp(req)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+	}
+	// a WebApp that is deleted or whose spec is unchanged was validated before, the operator must be able
+	// to update its metadata, such as the finalizer, even when it is no longer valid
+	if cr.DeletionTimestamp != nil {
+		return &AdmissionResponse{Allowed: true}
+	}
+	if len(req.OldObject.Raw) > 0 {
+		old := &v1alpha1.WebApp{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, fmt.Sprintf("failed to decode the old WebApp: %v", err))
+		}
+		if reflect.DeepEqual(old.Spec, cr.Spec) {
+			return &AdmissionResponse{Allowed: true}
+		}
+	}
+	if err := s.validator.Validate(cr); err != nil {
+		return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, fmt.Sprintf("WebApp %s is invalid: %v", cr.Name, err))
+	}
+	return &AdmissionResponse{Allowed: true}
+}
+
+func (s *Server) mutate(req *AdmissionRequest) *AdmissionResponse {
+	cr, err := decodeWebApp(req)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+	}
+	defaulted := cr.DeepCopy()
+	s.defaulter.Default(defaulted)
+
+	patch, err := json.Marshal(specPatch(cr, defaulted))
+	if err != nil {
+		return denied(http.StatusInternalServerError, metav1.StatusReasonInternalError, err.Error())
+	}
+	if string(patch) == "[]" {
+		return &AdmissionResponse{Allowed: true}
+	}
+	patchType := patchTypeJSONPatch
+	return &AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}
+}
+
+func decodeWebApp(req *AdmissionRequest) (*v1alpha1.WebApp, error) {
+	cr := &v1alpha1.WebApp{}
+	if err := json.Unmarshal(req.Object.Raw, cr); err != nil {
+		return nil, fmt.Errorf("failed to decode WebApp: %v", err)
+	}
+	if cr.Namespace == "" {
+		cr.Namespace = req.Namespace
+	}
+	return cr, nil
+}
+
+func denied(code int32, reason metav1.StatusReason, msg string) *AdmissionResponse {
+	return &AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Reason:  reason,
+			Message: msg,
+		},
+	}
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/tutorial-web-app-operator/pkg/apis/integreatly/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakeAdmitter struct {
+	err error
+}
+
+func (f fakeAdmitter) Validate(cr *v1alpha1.WebApp) error {
+	return f.err
+}
+
+func (f fakeAdmitter) Default(cr *v1alpha1.WebApp) {
+	if cr.Spec.AppLabel == "" {
+		cr.Spec.AppLabel = "tutorial-web-app"
+	}
+	if cr.Spec.Template.Parameters == nil {
+		cr.Spec.Template.Parameters = map[string]string{}
+	}
+	cr.Spec.Template.Parameters["WALKTHROUGH/LOCATIONS"] = "default"
+}
+
+func review(t *testing.T, s *Server, path string, cr *v1alpha1.WebApp) *AdmissionResponse {
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return send(t, s, path, &AdmissionRequest{UID: "1234", Operation: "CREATE", Object: runtime.RawExtension{Raw: raw}})
+}
+
+func reviewUpdate(t *testing.T, s *Server, old, cr *v1alpha1.WebApp) *AdmissionResponse {
+	raw, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRaw, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return send(t, s, ValidatePath, &AdmissionRequest{UID: "1234", Operation: "UPDATE", Object: runtime.RawExtension{Raw: raw}, OldObject: runtime.RawExtension{Raw: oldRaw}})
+}
+
+func send(t *testing.T, s *Server, path string, request *AdmissionRequest) *AdmissionResponse {
+	body, err := json.Marshal(AdmissionReview{Request: request})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	res := AdmissionReview{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Response == nil || res.Response.UID != "1234" {
+		t.Fatalf("expected a response for request 1234, got %+v", res.Response)
+	}
+	return res.Response
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Err     error
+		Allowed bool
+	}{
+		{Name: "Valid web app", Allowed: true},
+		{Name: "Invalid web app", Err: errors.New("spec.template: Required value")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := NewServer(0, "", fakeAdmitter{err: tc.Err}, fakeAdmitter{})
+			res := review(t, s, ValidatePath, &v1alpha1.WebApp{})
+			if res.Allowed != tc.Allowed {
+				t.Fatalf("expected allowed %v, got %v", tc.Allowed, res.Allowed)
+			}
+			if !tc.Allowed && (res.Result == nil || res.Result.Code != http.StatusUnprocessableEntity) {
+				t.Fatalf("expected an unprocessable entity status, got %+v", res.Result)
+			}
+		})
+	}
+}
+
+func TestValidate_Update(t *testing.T) {
+	old := &v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{AppLabel: "tutorial-web-app"}}
+	deleted := old.DeepCopy()
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted.Finalizers = nil
+	changed := old.DeepCopy()
+	changed.Spec.AppLabel = "other"
+
+	cases := []struct {
+		Name    string
+		WebApp  *v1alpha1.WebApp
+		Allowed bool
+	}{
+		{Name: "Unchanged spec is not validated", WebApp: old.DeepCopy(), Allowed: true},
+		{Name: "Deleted web app is not validated", WebApp: deleted, Allowed: true},
+		{Name: "Changed spec is validated", WebApp: changed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := NewServer(0, "", fakeAdmitter{err: errors.New("spec.template: Required value")}, fakeAdmitter{})
+			res := reviewUpdate(t, s, old, tc.WebApp)
+			if res.Allowed != tc.Allowed {
+				t.Fatalf("expected allowed %v, got %v", tc.Allowed, res.Allowed)
+			}
+		})
+	}
+}
+
+func TestMutate(t *testing.T) {
+	cases := []struct {
+		Name          string
+		WebApp        *v1alpha1.WebApp
+		ExpectedPatch []patchOperation
+	}{
+		{
+			Name:   "Default all fields",
+			WebApp: &v1alpha1.WebApp{},
+			ExpectedPatch: []patchOperation{
+				{Op: "add", Path: "/spec/app_label", Value: "tutorial-web-app"},
+				{Op: "add", Path: "/spec/template/parameters", Value: map[string]interface{}{"WALKTHROUGH/LOCATIONS": "default"}},
+			},
+		},
+		{
+			Name: "Add a parameter",
+			WebApp: &v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{
+				AppLabel: "tutorial-web-app",
+				Template: v1alpha1.WebAppTemplate{Parameters: map[string]string{"OPENSHIFT_VERSION": "4"}},
+			}},
+			ExpectedPatch: []patchOperation{
+				{Op: "add", Path: "/spec/template/parameters/WALKTHROUGH~1LOCATIONS", Value: "default"},
+			},
+		},
+		{
+			Name: "Nothing to default",
+			WebApp: &v1alpha1.WebApp{Spec: v1alpha1.WebAppSpec{
+				AppLabel: "tutorial-web-app",
+				Template: v1alpha1.WebAppTemplate{Parameters: map[string]string{"WALKTHROUGH/LOCATIONS": "default"}},
+			}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := NewServer(0, "", fakeAdmitter{}, fakeAdmitter{})
+			res := review(t, s, MutatePath, tc.WebApp)
+			if !res.Allowed {
+				t.Fatalf("expected the web app to be allowed, got %+v", res.Result)
+			}
+			if tc.ExpectedPatch == nil {
+				if res.Patch != nil {
+					t.Fatalf("expected no patch, got %s", res.Patch)
+				}
+				return
+			}
+			if res.PatchType == nil || *res.PatchType != patchTypeJSONPatch {
+				t.Fatalf("expected patch type %s, got %v", patchTypeJSONPatch, res.PatchType)
+			}
+			expected, _ := json.Marshal(tc.ExpectedPatch)
+			if string(res.Patch) != string(expected) {
+				t.Fatalf("expected patch %s, got %s", expected, res.Patch)
+			}
+		})
+	}
+}
+
+func TestServeRejectsBadRequests(t *testing.T) {
+	s := NewServer(0, "", fakeAdmitter{}, fakeAdmitter{})
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ValidatePath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader([]byte("{}")))
+	req.Header.Set("Content-Type", "application/json")
+	s.server.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}